@@ -10,24 +10,67 @@ import (
 
 func addCommand() *cobra.Command {
 	var (
-		resourceArg                         string
-		fileFlag, jobNameFlag, jobValueFlag string
-		valuesFlag                          []string
-		keyValuesFlag                       = mapFlag{}
+		resourceArg                                    string
+		fileFlag, jobNameFlag, jobValueFlag, groupFlag string
+		valuesFlag                                     []string
+		relabelOpts                                    = &relabelFlags{}
+		keyValuesFlag                                  = mapFlag{}
+		targetLabelsFlag                               = labelsFlag{}
+		alertmanagerOpts                               = &alertmanagerFlags{}
+		templateOpts                                   = &templateFlags{}
+		probeOpts                                      = &probeFlags{}
 	)
 
+	writeOpts := &writeOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "add <resource>",
-		Short: "Add job,targets,labels to prometheus configuration file",
-		Long: `add job,targets,labels to prometheus configuration file.
+		Short: "Add job,targets,labels,relabel,alertmanagers,probe to prometheus configuration file",
+		Long: `add job,targets,labels,relabel,alertmanagers,probe to prometheus configuration file.
 
 Examples:
     # append new value to job'targets
     mpc add targets -f prometheus.yaml -n node_exporter -v 127.0.0.1:9100
 
+    # append new value to the static_configs group whose labels contain dc=sh,
+    # a new group with labels dc=sh is created if no group matches
+    mpc add targets -f prometheus.yaml -n node_exporter -g dc=sh -v 127.0.0.1:9100
+
+    # append new value with its own labels, the target is put into the static_configs group
+    # whose labels match exactly, or moved there if it is already in another group
+    mpc add targets -f prometheus.yaml -n node_exporter -v 10.0.0.5:9100 --target-labels env=prod,team=db
+
     # append new kv to job'labels
     mpc add labels -f prometheus.yaml -n node_exporter -p foo=bar
 
+    # append new kv to labels of the second static_configs group
+    mpc add labels -f prometheus.yaml -n node_exporter -g 1 -p foo=bar
+
+    # append a metric_relabel_configs rule to drop go runtime metrics
+    mpc add relabel -f prometheus.yaml -n node_exporter --metric --source-labels __name__ --regex 'go_.*' --action drop
+
+    # insert a relabel_configs rule at the first position
+    mpc add relabel -f prometheus.yaml -n node_exporter -i 0 --source-labels __address__ --regex '([^:]+):.*' --target-label instance --replacement '${1}'
+
+    # append alertmanager targets, the alertmanager group is created if alerting has none,
+    # -g selects the alertmanager group by index if there are several
+    mpc add alertmanagers -f prometheus.yaml -v 127.0.0.1:9093 --scheme http
+
+    # add a new alertmanager group
+    mpc add alertmanagers -f prometheus.yaml -v 10.0.0.1:9093,10.0.0.2:9093 --new-group --scheme https --path-prefix /alertmanager
+
+    # probe urls by blackbox exporter, the probe job is created with the relabel rules if it does not exist
+    mpc add probe -f prometheus.yaml -n http_probe --module http_2xx --exporter blackbox:9115 -v https://example.com
+
+    # also add the module to blackbox.yml if it is missing
+    mpc add probe -f prometheus.yaml -n http_probe --module http_2xx --exporter blackbox:9115 -v https://example.com --blackbox-file blackbox.yml
+
+    # print the unified diff of the change without writing, exit code is 2 if anything would change
+    mpc add targets -f prometheus.yaml -n node_exporter -v 127.0.0.1:9100 --dry-run
+
+    # write the result to stdout, the original file is not changed
+    mpc add targets -f prometheus.yaml -n node_exporter -v 127.0.0.1:9100 -o -
+
     # add or replace job
     mpc add job -f prometheus.yaml -n node_exporter -d '
 job_name: mysql_exporter
@@ -36,6 +79,9 @@ static_configs:
   - 127.0.0.0:3306
   labels:
     foo: bar'
+
+    # add job rendered by a template, use "mpc templates" to list templates and their params
+    mpc add job -f prometheus.yaml --template mysqld --set name=mysql_prod --set targets=a:9104,b:9104 --set labels=env=prod
 `,
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -51,9 +97,21 @@ static_configs:
 			switch resourceArg {
 			// 执行job命令
 			case Job:
+				jobValue := jobValueFlag
+				if templateOpts.name != "" {
+					if jobValueFlag != "" {
+						return fmt.Errorf("flag 'job-value' and 'template' cannot be used together. ")
+					}
+					job, err := templateOpts.render(jobNameFlag)
+					if err != nil {
+						return err
+					}
+					jobValue = string(job)
+				}
 				err := runJobAddCommand(&jobAddOptions{
 					file:   fileFlag,
-					values: jobValueFlag,
+					values: jobValue,
+					write:  writeOpts,
 				})
 				if err != nil {
 					return err
@@ -67,10 +125,16 @@ static_configs:
 				if err := checkSliceValues(valuesFlag, "add"); err != nil {
 					return err
 				}
+				if groupFlag != "" && len(targetLabelsFlag) > 0 {
+					return fmt.Errorf("flag 'group' and 'target-labels' cannot be used together. ")
+				}
 				err := runTargetsAddCommand(&targetsAddOptions{
-					file:   fileFlag,
-					name:   jobNameFlag,
-					values: valuesFlag,
+					file:         fileFlag,
+					name:         jobNameFlag,
+					group:        groupFlag,
+					values:       valuesFlag,
+					targetLabels: mapFlag(targetLabelsFlag),
+					write:        writeOpts,
 				})
 				if err != nil {
 					return err
@@ -87,7 +151,71 @@ static_configs:
 				err := runLabelsAddCommand(&labelsAddOptions{
 					file:      fileFlag,
 					name:      jobNameFlag,
+					group:     groupFlag,
 					keyValues: keyValuesFlag,
+					write:     writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Relabel:
+				if err := checkJobName(jobNameFlag, "add"); err != nil {
+					return err
+				}
+				rule, err := relabelOpts.rule(cmd)
+				if err != nil {
+					return err
+				}
+				err = runRelabelAddCommand(&relabelEditOptions{
+					file:  fileFlag,
+					name:  jobNameFlag,
+					kind:  relabelOpts.kind(),
+					index: relabelOpts.index,
+					rule:  rule,
+					write: writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Alertmanagers:
+				settings := alertmanagerOpts.settings(cmd)
+				if len(valuesFlag) == 0 && len(settings) == 0 {
+					return fmt.Errorf("you must specify targets(-v) or settings(--scheme, --path-prefix) of alertmanager group to add")
+				}
+				if alertmanagerOpts.newGroup && groupFlag != "" {
+					return fmt.Errorf("flag 'group' and 'new-group' cannot be used together. ")
+				}
+				err := runAlertmanagersAddCommand(&alertmanagersEditOptions{
+					file:     fileFlag,
+					group:    groupFlag,
+					values:   valuesFlag,
+					settings: settings,
+					newGroup: alertmanagerOpts.newGroup,
+					write:    writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Probe:
+				if err := checkJobName(jobNameFlag, "add"); err != nil {
+					return err
+				}
+				if len(valuesFlag) == 0 && probeOpts.module == "" && probeOpts.exporter == "" {
+					return fmt.Errorf("you must specify targets(-v), module(--module) or exporter(--exporter) of probe job to add")
+				}
+				if probeOpts.blackboxFile != "" && writeOpts.output != "" {
+					return fmt.Errorf("flag 'blackbox-file' and 'output' cannot be used together. ")
+				}
+				err := runProbeAddCommand(&probeEditOptions{
+					file:         fileFlag,
+					name:         jobNameFlag,
+					values:       valuesFlag,
+					targetLabels: mapFlag(targetLabelsFlag),
+					probe:        probeOpts,
+					write:        writeOpts,
 				})
 				if err != nil {
 					return err
@@ -97,7 +225,7 @@ static_configs:
 				return fmt.Errorf(`unknown resource name '%s'. use "mpc resources" for a complete list of supported resources.\n`, resourceArg)
 			}
 
-			return nil
+			return writeOpts.result()
 		},
 	}
 
@@ -105,8 +233,18 @@ static_configs:
 	cmd.MarkFlagRequired("file")
 	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required, eg: node_exporter")
 	cmd.Flags().StringVarP(&jobValueFlag, "job-value", "d", "", "document value, if the resource is 'job', required, data format is yaml or json")
-	cmd.Flags().StringSliceVarP(&valuesFlag, "targets-value", "v", nil, "if the resource is 'targets', required, data format is string, eg: 127.0.0.1:9100")
+	cmd.Flags().StringSliceVarP(&valuesFlag, "targets-value", "v", nil, "if the resource is 'targets', 'alertmanagers' or 'probe', data format is string, eg: 127.0.0.1:9100")
 	cmd.Flags().VarP(&keyValuesFlag, "labels-value", "p", "key-value pairs, if the resource is 'labels', required, eg: foo=bar")
+	cmd.Flags().StringVarP(&groupFlag, "group", "g", "", "static_configs group, index or label selector, or index of alertmanager group if the resource is 'alertmanagers', eg: 1 or dc=sh")
+	cmd.Flags().Var(&targetLabelsFlag, "target-labels", "key-value pairs of target labels, if the resource is 'targets' or 'probe', eg: env=prod,team=db")
+	relabelOpts.addKindFlags(cmd, "position to insert the relabel rule, default is appending to the end, if the resource is 'relabel'")
+	relabelOpts.addRuleFlags(cmd)
+	alertmanagerOpts.addSettingFlags(cmd)
+	templateOpts.addFlags(cmd)
+	probeOpts.addFlags(cmd)
+	cmd.Flags().BoolVar(&alertmanagerOpts.newGroup, "new-group", false, "add a new alertmanager group instead of appending to the existing one, if the resource is 'alertmanagers'")
+	writeOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
 
 	return cmd
 }
@@ -116,6 +254,7 @@ static_configs:
 type jobAddOptions struct {
 	file   string
 	values string
+	write  *writeOptions
 }
 
 func runJobAddCommand(options *jobAddOptions) error {
@@ -130,49 +269,89 @@ func runJobAddCommand(options *jobAddOptions) error {
 		return err
 	}
 
-	return cy.Persistent(options.file)
+	return options.write.write(options.file, data, cy.Data, cy)
 }
 
 type targetsAddOptions struct {
-	file   string
-	name   string
-	values []string
+	file         string
+	name         string
+	group        string
+	values       []string
+	targetLabels mapFlag
+	write        *writeOptions
 }
 
 func runTargetsAddCommand(options *targetsAddOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	err = cy.AddJobTargets(options.name, options.values)
+	sdFile, err := getJobSDFile(cy, options.file, options.name)
+	if err != nil {
+		return err
+	}
+	if sdFile != "" {
+		fsd, err := readFileSD(sdFile)
+		if err != nil {
+			return err
+		}
+		sdData := fsd.Data
+		if len(options.targetLabels) > 0 {
+			err = fsd.AddTargetsWithLabels(options.values, options.targetLabels)
+		} else {
+			err = fsd.AddGroupTargets(sel, options.values)
+		}
+		if err != nil {
+			return err
+		}
+		return options.write.write(sdFile, sdData, fsd.Data, fsd)
+	}
+
+	if len(options.targetLabels) > 0 {
+		err = cy.AddJobTargetsWithLabels(options.name, options.values, options.targetLabels)
+	} else {
+		err = cy.AddJobGroupTargets(options.name, sel, options.values)
+	}
 	if err != nil {
 		return err
 	}
 
-	return cy.Persistent(options.file)
+	return options.write.write(options.file, data, cy.Data, cy)
 }
 
 type labelsAddOptions struct {
 	file      string
 	name      string
+	group     string
 	keyValues mapFlag
+	write     *writeOptions
 }
 
 func runLabelsAddCommand(options *labelsAddOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	err = cy.AddJobLabels(options.name, options.keyValues)
+	err = cy.AddJobGroupLabels(options.name, sel, options.keyValues)
 	if err != nil {
 		return err
 	}
 
-	return cy.Persistent(options.file)
+	return options.write.write(options.file, data, cy.Data, cy)
 }
 
 type mapFlag map[string]string
@@ -197,6 +376,28 @@ func (m mapFlag) Type() string {
 	return fmt.Sprintf("%T", m)
 }
 
+// labelsFlag 标签参数，一次可以设置多个用逗号分隔的标签，例如 env=prod,team=db
+type labelsFlag map[string]string
+
+func (m labelsFlag) String() string {
+	return fmt.Sprintf("%v", map[string]string(m))
+}
+
+func (m labelsFlag) Set(value string) error {
+	for _, kv := range strings.Split(value, ",") {
+		err := mapFlag(m).Set(kv)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m labelsFlag) Type() string {
+	return fmt.Sprintf("%T", m)
+}
+
 // ---------------------------------------------------------------------------------------
 
 func checkJobName(jobName string, action string) error {
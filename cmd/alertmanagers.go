@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+// alertmanagerFlags alertmanagers资源的参数，add、replace命令共用
+type alertmanagerFlags struct {
+	scheme     string
+	pathPrefix string
+	newGroup   bool
+}
+
+func (a *alertmanagerFlags) addSettingFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&a.scheme, "scheme", "", "scheme of alertmanager group, http or https, if the resource is 'alertmanagers'")
+	cmd.Flags().StringVar(&a.pathPrefix, "path-prefix", "", "path prefix of alertmanager group, empty value deletes it, if the resource is 'alertmanagers', eg: /alertmanager")
+}
+
+// 只返回命令行设置了的参数，值为空时删除该设置
+func (a *alertmanagerFlags) settings(cmd *cobra.Command) map[string]string {
+	settings := map[string]string{}
+	if cmd.Flags().Changed("scheme") {
+		settings[promConf.AlertmanagerScheme] = a.scheme
+	}
+	if cmd.Flags().Changed("path-prefix") {
+		settings[promConf.AlertmanagerPathPrefix] = a.pathPrefix
+	}
+	return settings
+}
+
+// 解析alertmanager分组索引，为空时返回-1
+func parseAlertmanagerIndex(group string) (int, error) {
+	if group == "" {
+		return -1, nil
+	}
+
+	index, err := strconv.Atoi(group)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("alertmanager group '%s' is invalid, it must be an index, eg: 0", group)
+	}
+	return index, nil
+}
+
+// ---------------------------------------------------------------------------------------
+
+func runAlertmanagersGetCommand(file string, group string) ([]*promConf.Alertmanager, error) {
+	index, err := parseAlertmanagerIndex(group)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readPrometheusConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	ams, err := cy.GetAlertmanagers()
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		return ams, nil
+	}
+	if index >= len(ams) {
+		return nil, fmt.Errorf("alertmanager group '%d' not found", index)
+	}
+
+	return ams[index : index+1], nil
+}
+
+func printAlertmanagers(ams []*promConf.Alertmanager) {
+	for _, am := range ams {
+		scheme := am.Scheme
+		if scheme == "" {
+			scheme = "http"
+		}
+		fmt.Printf("[%d] scheme: %s, path_prefix: %s, targets: %v\n", am.Index, scheme, am.PathPrefix, am.Targets)
+	}
+}
+
+func alertmanagersTable(ams []*promConf.Alertmanager) *outputTable {
+	table := newOutputTable("INDEX", "SCHEME", "PATH_PREFIX", "TARGETS").addWideColumns("ADDRESSES")
+	for _, am := range ams {
+		table.addRow(am.Index, am.Scheme, am.PathPrefix, len(am.Targets), strings.Join(am.Targets, ","))
+	}
+	return table
+}
+
+type alertmanagersEditOptions struct {
+	file     string
+	group    string
+	values   []string
+	settings map[string]string
+	newGroup bool
+	write    *writeOptions
+}
+
+func runAlertmanagersAddCommand(options *alertmanagersEditOptions) error {
+	return editAlertmanagers(options, func(cy *promConf.ConfigYaml, index int) error {
+		if options.newGroup {
+			return cy.AddAlertmanager(options.values, options.settings)
+		}
+		if len(options.values) > 0 {
+			err := cy.AddAlertmanagerTargets(index, options.values)
+			if err != nil {
+				return err
+			}
+		}
+		if len(options.settings) > 0 {
+			return cy.SetAlertmanagerSettings(index, options.settings)
+		}
+		return nil
+	})
+}
+
+func runAlertmanagersDelCommand(options *alertmanagersEditOptions) error {
+	return editAlertmanagers(options, func(cy *promConf.ConfigYaml, index int) error {
+		// 没有指定target时删除整个分组
+		if len(options.values) == 0 {
+			return cy.DelAlertmanager(index)
+		}
+		return cy.DelAlertmanagerTargets(index, options.values)
+	})
+}
+
+func runAlertmanagersReplaceCommand(options *alertmanagersEditOptions) error {
+	return editAlertmanagers(options, func(cy *promConf.ConfigYaml, index int) error {
+		if len(options.values) > 0 {
+			err := cy.ReplaceAlertmanagerTargets(index, options.values)
+			if err != nil {
+				return err
+			}
+		}
+		if len(options.settings) > 0 {
+			return cy.SetAlertmanagerSettings(index, options.settings)
+		}
+		return nil
+	})
+}
+
+func editAlertmanagers(options *alertmanagersEditOptions, fn func(cy *promConf.ConfigYaml, index int) error) error {
+	index, err := parseAlertmanagerIndex(options.group)
+	if err != nil {
+		return err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = fn(cy, index)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func applyCommand() *cobra.Command {
+	var (
+		fileFlag, desiredFlag string
+		pruneFlag             bool
+	)
+
+	writeOpts := &writeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Make jobs, targets and labels match a desired-state file",
+		Long: `make jobs, targets and labels in prometheus configuration file match a desired-state file,
+the plan of adds, deletes and replaces is printed and applied in one write. the desired-state
+file is a list of jobs, or a prometheus configuration file whose scrape_configs are used.
+
+a job that differs only in static_configs is updated in place and keeps its comments, a job
+whose other fields differ is replaced by the declared one. jobs not declared are kept, unless
+--prune is specified.
+
+Examples:
+    mpc apply -f prometheus.yaml --desired jobs.yaml
+
+    # also delete the jobs that are not declared in jobs.yaml
+    mpc apply -f prometheus.yaml --desired jobs.yaml --prune
+
+    # print the plan and the unified diff without writing, exit code is 2 if anything would change
+    mpc apply -f prometheus.yaml --desired jobs.yaml --prune --dry-run
+
+jobs.yaml:
+    - job_name: node_exporter
+      static_configs:
+        - targets: ['10.0.0.1:9100', '10.0.0.2:9100']
+          labels:
+            env: prod
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runApplyCommand(&applyOptions{
+				file:    fileFlag,
+				desired: desiredFlag,
+				prune:   pruneFlag,
+				write:   writeOpts,
+			})
+			if err != nil {
+				return err
+			}
+
+			return writeOpts.result()
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVar(&desiredFlag, "desired", "", "desired-state file of jobs, required, data format is yaml or json, eg: jobs.yaml")
+	cmd.MarkFlagRequired("desired")
+	cmd.Flags().BoolVar(&pruneFlag, "prune", false, "delete the jobs that are not declared in desired-state file")
+	writeOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+type applyOptions struct {
+	file    string
+	desired string
+	prune   bool
+	write   *writeOptions
+}
+
+func runApplyCommand(options *applyOptions) error {
+	desired, err := ioutil.ReadFile(options.desired)
+	if err != nil {
+		return err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	plan, err := cy.Apply(desired, options.prune)
+	if err != nil {
+		return err
+	}
+
+	// 结果输出到标准输出时，计划输出到标准错误
+	var w io.Writer = os.Stdout
+	if options.write.output == "-" {
+		w = os.Stderr
+	}
+	printApplyPlan(w, plan)
+	if plan.IsEmpty() {
+		return nil
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
+
+// 打印修改计划，+表示添加，~表示修改，-表示删除
+func printApplyPlan(w io.Writer, plan *promConf.ApplyPlan) {
+	if plan.IsEmpty() {
+		fmt.Fprintln(w, "No changes, jobs match the desired state.")
+		return
+	}
+
+	symbols := map[string]string{
+		promConf.ApplyAdd:     "+",
+		promConf.ApplyUpdate:  "~",
+		promConf.ApplyReplace: "~",
+		promConf.ApplyDelete:  "-",
+	}
+	for _, change := range plan.Changes {
+		fmt.Fprintf(w, "%s %s job %s\n", symbols[change.Action], change.Action, change.Job)
+		for _, detail := range change.Details {
+			fmt.Fprintf(w, "    %s\n", detail)
+		}
+	}
+
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to delete.\n",
+		plan.Count(promConf.ApplyAdd),
+		plan.Count(promConf.ApplyUpdate)+plan.Count(promConf.ApplyReplace),
+		plan.Count(promConf.ApplyDelete))
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func checkCommand() *cobra.Command {
+	var fileFlag string
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check prometheus configuration file offline",
+		Long: `check prometheus configuration file offline, find duplicate job names, scrape_timeout greater
+than scrape_interval, malformed durations, invalid target addresses and label names, bad regexes
+in relabel rules and unknown schemes. the same checks are run before every command writes the file.
+
+Examples:
+    mpc check -f prometheus.yaml
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runCheckCommand(fileFlag)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s is valid\n", fileFlag)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runCheckCommand(file string) error {
+	data, err := readPrometheusConfigFile(file)
+	if err != nil {
+		return err
+	}
+
+	return promConf.Validate(data)
+}
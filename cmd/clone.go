@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func cloneCommand() *cobra.Command {
+	var (
+		resourceArg, srcNameArg, dstNameArg string
+		fileFlag                            string
+		withoutTargetsFlag                  bool
+	)
+
+	writeOpts := &writeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "clone job <source name> <new name>",
+		Short: "Clone job in prometheus configuration file",
+		Long: `clone job in prometheus configuration file, the new job is added after the source job,
+all fields of source job are copied, including the fields which are not modeled by mpc.
+
+Examples:
+    mpc clone job node_exporter node_exporter_dev -f prometheus.yaml
+
+    # copy the job without targets, the labels of static_configs groups are kept
+    mpc clone job node_exporter node_exporter_dev -f prometheus.yaml --without-targets
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return fmt.Errorf("you must specify the resource, source name and new name, eg: mpc clone %s node_exporter node_exporter_dev\n", Job)
+			}
+			resourceArg, srcNameArg, dstNameArg = args[0], args[1], args[2]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch resourceArg {
+			case Job:
+				err := runJobCloneCommand(&jobCloneOptions{
+					file:           fileFlag,
+					srcName:        srcNameArg,
+					dstName:        dstNameArg,
+					withoutTargets: withoutTargetsFlag,
+					write:          writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			default:
+				return fmt.Errorf("unknown resource name '%s', only supports %s\n", resourceArg, Job)
+			}
+
+			return writeOpts.result()
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().BoolVar(&withoutTargetsFlag, "without-targets", false, "clear the targets of static_configs groups in the new job")
+	writeOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+type jobCloneOptions struct {
+	file           string
+	srcName        string
+	dstName        string
+	withoutTargets bool
+	write          *writeOptions
+}
+
+func runJobCloneCommand(options *jobCloneOptions) error {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.CloneJob(options.srcName, options.dstName, options.withoutTargets)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+const (
+	// JobToFileSD 把job的static_configs转移到file_sd_configs文件
+	JobToFileSD = "job-to-filesd"
+)
+
+func convertCommand() *cobra.Command {
+	var (
+		convertArg                                 string
+		fileFlag, jobNameFlag, dirFlag, formatFlag string
+	)
+
+	writeOpts := &writeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "convert <conversion>",
+		Short: "Convert job in prometheus configuration file",
+		Long: `convert job in prometheus configuration file.
+
+Examples:
+    # move the static_configs of job into file targets.d/node_exporter.json, and rewrite
+    # the job to reference it by file_sd_configs, after that the targets commands edit
+    # the file directly, and prometheus re-reads it without reload.
+    mpc convert job-to-filesd -f prometheus.yaml -n node_exporter --dir targets.d/
+
+    # print the unified diff of both files without writing
+    mpc convert job-to-filesd -f prometheus.yaml -n node_exporter --dry-run
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf(`you must specify the type of conversion, eg: %s\n`, JobToFileSD)
+			}
+			convertArg = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch convertArg {
+			case JobToFileSD:
+				if err := checkJobName(jobNameFlag, "convert"); err != nil {
+					return err
+				}
+				sdFile, err := runJobToFileSDCommand(&jobToFileSDOptions{
+					file:   fileFlag,
+					name:   jobNameFlag,
+					dir:    dirFlag,
+					format: formatFlag,
+					write:  writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+				if !writeOpts.dryRun {
+					fmt.Printf("static_configs of job '%s' have been moved to %s\n", jobNameFlag, sdFile)
+				}
+
+			default:
+				return fmt.Errorf("unknown conversion '%s', eg: %s\n", convertArg, JobToFileSD)
+			}
+
+			return writeOpts.result()
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required, eg: node_exporter")
+	cmd.Flags().StringVar(&dirFlag, "dir", "targets.d", "directory of file_sd_configs files")
+	cmd.Flags().StringVar(&formatFlag, "format", "json", "format of file_sd_configs file, json or yaml")
+	cmd.Flags().BoolVar(&writeOpts.dryRun, "dry-run", false, "print unified diff of the changes without writing, exit code is 2 if anything would change")
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+type jobToFileSDOptions struct {
+	file   string
+	name   string
+	dir    string
+	format string
+	write  *writeOptions
+}
+
+func runJobToFileSDCommand(options *jobToFileSDOptions) (string, error) {
+	if options.format != "json" && options.format != "yaml" {
+		return "", fmt.Errorf("format '%s' is invalid, only supports json and yaml", options.format)
+	}
+
+	if isRemoteFile(options.file) {
+		return "", fmt.Errorf("converting jobs of a remote file is not supported")
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return "", err
+	}
+
+	sdFile := filepath.Join(options.dir, options.name+"."+options.format)
+	cy := promConf.NewConfigYaml(data)
+	groups, err := cy.ConvertJobToFileSD(options.name, configRefPath(options.file, sdFile))
+	if err != nil {
+		return "", err
+	}
+
+	fsd := promConf.NewFileSD(sdFile, nil)
+	err = fsd.ReplaceGroups(groups)
+	if err != nil {
+		return "", err
+	}
+
+	// 文件已存在且内容相同时，是上一次转换写入的，例如重新执行命令时，不算冲突
+	sdData, err := ioutil.ReadFile(sdFile)
+	if err == nil && !bytes.Equal(sdData, fsd.Data) {
+		return "", fmt.Errorf("file %s already exists", sdFile)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	return sdFile, options.write.writeFiles(
+		&editedFile{file: sdFile, oldData: sdData, newData: fsd.Data, pf: fsd},
+		&editedFile{file: options.file, oldData: data, newData: cy.Data, pf: cy},
+	)
+}
+
+// 文件在prometheus配置中引用的路径，prometheus以配置文件所在目录为相对路径的起点
+func configRefPath(configFile string, file string) string {
+	if filepath.IsAbs(file) {
+		return filepath.ToSlash(file)
+	}
+
+	absFile, _ := filepath.Abs(file)
+	absConfigDir, _ := filepath.Abs(filepath.Dir(configFile))
+	refFile, err := filepath.Rel(absConfigDir, absFile)
+	if err != nil {
+		refFile = absFile
+	}
+
+	return filepath.ToSlash(refFile)
+}
+
+// 获取job唯一引用的file_sd_configs文件，job使用static_configs或引用了多个文件时返回空
+func getJobSDFile(cy *promConf.ConfigYaml, file string, jobName string) (string, error) {
+	groups, err := cy.GetJobGroups(jobName)
+	if err != nil {
+		return "", err
+	}
+	if len(groups) > 0 {
+		return "", nil
+	}
+
+	files, err := cy.GetJobFileSDFiles(jobName)
+	if err != nil {
+		return "", err
+	}
+	if len(files) != 1 || promConf.IsGlobPath(files[0]) {
+		return "", nil
+	}
+
+	sdFile := files[0]
+	if isRemoteFile(file) {
+		return remoteSiblingFile(file, sdFile)
+	}
+	if !filepath.IsAbs(sdFile) {
+		sdFile = filepath.Join(filepath.Dir(file), sdFile)
+	}
+
+	return sdFile, nil
+}
+
+func readFileSD(sdFile string) (*promConf.FileSD, error) {
+	data, err := readPrometheusConfigFile(sdFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return promConf.NewFileSD(sdFile, nil), nil
+		}
+		return nil, err
+	}
+
+	return promConf.NewFileSD(sdFile, data), nil
+}
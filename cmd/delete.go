@@ -10,15 +10,18 @@ import (
 
 func deleteCommand() *cobra.Command {
 	var (
-		resourceArg           string
-		fileFlag, jobNameFlag string
-		valuesFlag, keysFlag  []string
+		resourceArg                      string
+		fileFlag, jobNameFlag, groupFlag string
+		valuesFlag, keysFlag             []string
+		relabelOpts                      = &relabelFlags{}
 	)
 
+	writeOpts := &writeOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "delete <resource>",
-		Short: "Delete job,targets,labels in prometheus configuration file",
-		Long: `delete job,targets,labels in prometheus configuration file.
+		Short: "Delete job,targets,labels,groups,settings,relabel,alertmanagers,probe in prometheus configuration file",
+		Long: `delete job,targets,labels,groups,settings,relabel,alertmanagers,probe in prometheus configuration file.
 
 Examples:
     # delete job in prometheus configuration file
@@ -29,6 +32,30 @@ Examples:
 
     # delete key in  job'labels
     mpc delete labels -f prometheus.yaml -n node_exporter -k foo
+
+    # delete element in targets of the static_configs group whose labels contain dc=sh
+    mpc delete targets -f prometheus.yaml -n node_exporter -g dc=sh -v 127.0.0.1:9100
+
+    # delete static_configs group
+    mpc delete groups -f prometheus.yaml -n node_exporter -g dc=sh
+
+    # delete scrape settings of job, the global configuration or default value is used after that
+    mpc delete settings -f prometheus.yaml -n node_exporter -k scrape_interval,params.module
+
+    # delete the relabel_configs rule at index 0
+    mpc delete relabel -f prometheus.yaml -n node_exporter -i 0
+
+    # delete alertmanager targets from all alertmanager groups, the settings of groups are kept
+    mpc delete alertmanagers -f prometheus.yaml -v 127.0.0.1:9093
+
+    # delete the alertmanager group at index 1
+    mpc delete alertmanagers -f prometheus.yaml -g 1
+
+    # delete probed urls of blackbox probe job
+    mpc delete probe -f prometheus.yaml -n http_probe -v https://example.com
+
+    # print the unified diff of the change without writing
+    mpc delete job -f prometheus.yaml -n node_exporter --dry-run
 `,
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -48,8 +75,9 @@ Examples:
 					return err
 				}
 				err := runJobDelCommand(&jobDelOptions{
-					file: fileFlag,
-					name: jobNameFlag,
+					file:  fileFlag,
+					name:  jobNameFlag,
+					write: writeOpts,
 				})
 				if err != nil {
 					return err
@@ -66,7 +94,9 @@ Examples:
 				err := runTargetsDelCommand(&targetsDelOptions{
 					file:   fileFlag,
 					name:   jobNameFlag,
+					group:  groupFlag,
 					values: valuesFlag,
+					write:  writeOpts,
 				})
 				if err != nil {
 					return err
@@ -81,9 +111,94 @@ Examples:
 					return err
 				}
 				err := runLabelsDelCommand(&labelsDelOptions{
-					file: fileFlag,
-					name: jobNameFlag,
-					keys: keysFlag,
+					file:  fileFlag,
+					name:  jobNameFlag,
+					group: groupFlag,
+					keys:  keysFlag,
+					write: writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Groups:
+				if err := checkJobName(jobNameFlag, "delete"); err != nil {
+					return err
+				}
+				if groupFlag == "" {
+					return fmt.Errorf("you must specify the group of resource to delete. ")
+				}
+				err := runGroupsDelCommand(&groupsDelOptions{
+					file:  fileFlag,
+					name:  jobNameFlag,
+					group: groupFlag,
+					write: writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Settings:
+				if err := checkJobName(jobNameFlag, "delete"); err != nil {
+					return err
+				}
+				if err := checkSliceValues(keysFlag, "delete"); err != nil {
+					return err
+				}
+				err := runSettingsDelCommand(&settingsDelOptions{
+					file:  fileFlag,
+					name:  jobNameFlag,
+					keys:  keysFlag,
+					write: writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Relabel:
+				if err := checkJobName(jobNameFlag, "delete"); err != nil {
+					return err
+				}
+				if err := checkRelabelIndex(relabelOpts.index, "delete"); err != nil {
+					return err
+				}
+				err := runRelabelDelCommand(&relabelEditOptions{
+					file:  fileFlag,
+					name:  jobNameFlag,
+					kind:  relabelOpts.kind(),
+					index: relabelOpts.index,
+					write: writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Alertmanagers:
+				if len(valuesFlag) == 0 && groupFlag == "" {
+					return fmt.Errorf("you must specify targets(-v) or the alertmanager group(-g) to delete. ")
+				}
+				err := runAlertmanagersDelCommand(&alertmanagersEditOptions{
+					file:   fileFlag,
+					group:  groupFlag,
+					values: valuesFlag,
+					write:  writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Probe:
+				if err := checkJobName(jobNameFlag, "delete"); err != nil {
+					return err
+				}
+				if err := checkSliceValues(valuesFlag, "delete"); err != nil {
+					return err
+				}
+				err := runProbeDelCommand(&probeEditOptions{
+					file:   fileFlag,
+					name:   jobNameFlag,
+					values: valuesFlag,
+					write:  writeOpts,
 				})
 				if err != nil {
 					return err
@@ -93,15 +208,19 @@ Examples:
 				return fmt.Errorf("unknown resource name '%s'. Use \"mpc resources\" for a complete list of supported resources.\n", resourceArg)
 			}
 
-			return nil
+			return writeOpts.result()
 		},
 	}
 
 	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
 	cmd.MarkFlagRequired("file")
 	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required, eg: node_exporter")
-	cmd.Flags().StringSliceVarP(&valuesFlag, "values", "v", nil, "if the resource is 'targets', required, eg: 127.0.0.1:9100")
-	cmd.Flags().StringSliceVarP(&keysFlag, "keys", "k", nil, "if the resource is 'labels', required, eg: foo")
+	cmd.Flags().StringSliceVarP(&valuesFlag, "values", "v", nil, "if the resource is 'targets', 'alertmanagers' or 'probe', eg: 127.0.0.1:9100")
+	cmd.Flags().StringSliceVarP(&keysFlag, "keys", "k", nil, "if the resource is 'labels' or 'settings', required, eg: foo or scrape_interval")
+	cmd.Flags().StringVarP(&groupFlag, "group", "g", "", "static_configs group, index or label selector, required if the resource is 'groups', or index of alertmanager group if the resource is 'alertmanagers', eg: 1 or dc=sh")
+	relabelOpts.addKindFlags(cmd, "index of the relabel rule to delete, required if the resource is 'relabel'")
+	writeOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
 
 	return cmd
 }
@@ -109,8 +228,9 @@ Examples:
 // ---------------------------------------------------------------------------------------
 
 type jobDelOptions struct {
-	file string
-	name string
+	file  string
+	name  string
+	write *writeOptions
 }
 
 func runJobDelCommand(options *jobDelOptions) error {
@@ -125,47 +245,127 @@ func runJobDelCommand(options *jobDelOptions) error {
 		return err
 	}
 
-	return cy.Persistent(options.file)
+	return options.write.write(options.file, data, cy.Data, cy)
 }
 
 type targetsDelOptions struct {
 	file   string
 	name   string
+	group  string
 	values []string
+	write  *writeOptions
 }
 
 func runTargetsDelCommand(options *targetsDelOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	err = cy.DelJobTargets(options.name, options.values)
+	sdFile, err := getJobSDFile(cy, options.file, options.name)
 	if err != nil {
 		return err
 	}
+	if sdFile != "" {
+		fsd, err := readFileSD(sdFile)
+		if err != nil {
+			return err
+		}
+		sdData := fsd.Data
+		err = fsd.DelGroupTargets(sel, options.values)
+		if err != nil {
+			return err
+		}
+		return options.write.write(sdFile, sdData, fsd.Data, fsd)
+	}
 
-	return cy.Persistent(options.file)
+	err = cy.DelJobGroupTargets(options.name, sel, options.values)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
 }
 
 type labelsDelOptions struct {
-	file string
-	name string
-	keys []string
+	file  string
+	name  string
+	group string
+	keys  []string
+	write *writeOptions
 }
 
 func runLabelsDelCommand(options *labelsDelOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.DelJobGroupLabels(options.name, sel, options.keys)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
+
+type groupsDelOptions struct {
+	file  string
+	name  string
+	group string
+	write *writeOptions
+}
+
+func runGroupsDelCommand(options *groupsDelOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.DelJobGroup(options.name, sel)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
+
+type settingsDelOptions struct {
+	file  string
+	name  string
+	keys  []string
+	write *writeOptions
+}
+
+func runSettingsDelCommand(options *settingsDelOptions) error {
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	err = cy.DelJobLabels(options.name, options.keys)
+	err = cy.DelJobSettings(options.name, options.keys)
 	if err != nil {
 		return err
 	}
 
-	return cy.Persistent(options.file)
+	return options.write.write(options.file, data, cy.Data, cy)
 }
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func driftCommand() *cobra.Command {
+	var (
+		fileFlag, promURLFlag string
+		clientOpts            = &clientFlags{}
+		outputOpts            = &outputOptions{}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Show the differences between the configuration file and the running prometheus",
+		Long: `show the differences between the configuration file and the configuration loaded by the running
+prometheus, which is fetched from /api/v1/status/config. the targets, labels and scrape settings of
+each job are compared, fields omitted in the file are compared with the prometheus defaults and
+hidden secrets are ignored. the differences show what a reload would change.
+
+exit code is 0 if there is no drift and 2 if the file has changes that are not loaded, so that
+the command can be used in monitoring.
+
+Examples:
+    mpc drift -f prometheus.yaml --prom http://127.0.0.1:9090
+
+    # prometheus behind basic auth
+    mpc drift -f prometheus.yaml --prom https://prom.example.com --username admin --password 123456 -o json
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := outputOpts.parse(); err != nil {
+				return err
+			}
+
+			report, err := runDriftCommand(fileFlag, promURLFlag, clientOpts.options())
+			if err != nil {
+				return err
+			}
+			if outputOpts.isText() {
+				printDriftReport(report)
+			} else if err = outputOpts.print(report, driftTable(report)); err != nil {
+				return err
+			}
+
+			if !report.IsEmpty() {
+				return &ExitCodeError{Code: ExitCodeChanged}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVarP(&promURLFlag, "prom", "p", "http://127.0.0.1:9090", "prometheus url")
+	clientOpts.addFlags(cmd)
+	outputOpts.addFlags(cmd)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+func runDriftCommand(file string, promURL string, options *promConf.ClientOptions) (*promConf.DriftReport, error) {
+	data, err := readPrometheusConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := promConf.GetLoadedConfig(promURL, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return promConf.NewConfigYaml(data).Drift(loaded)
+}
+
+// 打印差异，+表示job还没有加载，~表示job不同，-表示job已从配置文件删除
+func printDriftReport(report *promConf.DriftReport) {
+	if report.IsEmpty() {
+		fmt.Println("No drift, the running prometheus has loaded the configuration file.")
+		return
+	}
+
+	symbols := map[string]string{
+		promConf.DriftAdded:   "+",
+		promConf.DriftChanged: "~",
+		promConf.DriftDeleted: "-",
+	}
+	for _, job := range report.Jobs {
+		fmt.Printf("%s job %s %s\n", symbols[job.Status], job.Job, job.Status)
+		for _, detail := range job.Details {
+			fmt.Printf("    %s\n", detail)
+		}
+	}
+
+	fmt.Printf("\nDrift: %d not loaded, %d changed, %d still loaded after deletion.\n",
+		report.Count(promConf.DriftAdded),
+		report.Count(promConf.DriftChanged),
+		report.Count(promConf.DriftDeleted))
+}
+
+func driftTable(report *promConf.DriftReport) *outputTable {
+	table := newOutputTable("JOB", "STATUS", "CHANGES")
+	for _, job := range report.Jobs {
+		table.addRow(job.Job, job.Status, len(job.Details))
+	}
+	return table
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func findCommand() *cobra.Command {
+	var (
+		resourceArg                      string
+		fileFlag, hostFlag, selectorFlag string
+		outputOpts                       = &outputOptions{}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "find targets",
+		Short: "Find targets of all jobs by host or labels",
+		Long: `find targets of all jobs by host or labels, the targets in file_sd_configs file are also
+searched if the job references only one file. the output shows the job, index of static_configs
+group, target and effective labels of each target.
+
+Examples:
+    # which jobs scrape 10.0.0.91, the port of target is ignored
+    mpc find targets -f prometheus.yaml --host 10.0.0.91
+
+    # find targets by labels, supports =, !=, =~, !~
+    mpc find targets -f prometheus.yaml --host 10.0.0.91 -l env=prod,team!=db
+
+    # print the jobs only
+    mpc find targets -f prometheus.yaml --host 10.0.0.91 -o jsonpath='{[*].job}'
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("you must specify the resource, eg: mpc find %s\n", Targets)
+			}
+			resourceArg = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch resourceArg {
+			case Targets:
+				if err := outputOpts.parse(); err != nil {
+					return err
+				}
+				if hostFlag == "" && selectorFlag == "" {
+					return fmt.Errorf("you must specify the host(--host) or label selector(-l) of targets to find")
+				}
+				targets, err := runTargetsQueryCommand(&targetsQueryOptions{
+					file:     fileFlag,
+					host:     hostFlag,
+					selector: selectorFlag,
+				})
+				if err != nil {
+					return err
+				}
+				if outputOpts.isText() {
+					return targetsTable(targets).print(true)
+				}
+				return outputOpts.print(targets, targetsTable(targets))
+
+			default:
+				return fmt.Errorf("unknown resource name '%s', only supports %s\n", resourceArg, Targets)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVar(&hostFlag, "host", "", "host name or ip of target, the port is ignored, eg: 10.0.0.91")
+	cmd.Flags().StringVarP(&selectorFlag, "selector", "l", "", "label selector of effective labels, supports =, !=, =~, !~, eg: env=prod,team!=db")
+	outputOpts.addFlags(cmd)
+
+	return cmd
+}
@@ -4,29 +4,66 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/zhufuyi/mpc/promConf"
+	"gopkg.in/yaml.v3"
 )
 
 func getCommand() *cobra.Command {
 	var (
-		resourceArg           string
-		fileFlag, jobNameFlag string
+		resourceArg                      string
+		fileFlag, jobNameFlag, groupFlag string
+		selectorFlag                     string
+		showLabelsFlag                   bool
+		relabelOpts                      = &relabelFlags{}
+		outputOpts                       = &outputOptions{}
 	)
 
 	cmd := &cobra.Command{
 		Use:   "get <resource>",
-		Short: "Show job,targets,labels from prometheus configuration file",
-		Long: `show job,targets,labels from prometheus configuration file.
+		Short: "Show jobs,job,targets,labels,groups,settings,relabel,alertmanagers,probe from prometheus configuration file",
+		Long: `show jobs,job,targets,labels,groups,settings,relabel,alertmanagers,probe from prometheus configuration file.
 
 Examples:
+    # list all jobs with the number of static_configs groups and targets
+    mpc get jobs -f prometheus.yaml
+
     mpc get job -f prometheus.yaml -n node_exporter
 
     mpc get targets -f prometheus.yaml -n node_exporter
 
-    mpc get labels -f prometheus.yaml -n node_exporter
+    # show targets of the static_configs group whose labels contain dc=sh
+    mpc get targets -f prometheus.yaml -n node_exporter -g dc=sh
+
+    # show each target with its effective labels
+    mpc get targets -f prometheus.yaml -n node_exporter --show-labels
+
+    # show targets of all jobs whose effective labels match the selector, -n limits to one job
+    mpc get targets -f prometheus.yaml -l env=prod,team!=db
+
+    mpc get labels -f prometheus.yaml -n node_exporter -g 1
+
+    # list all static_configs groups of job
+    mpc get groups -f prometheus.yaml -n node_exporter
+
+    # show scrape settings of job, e.g. scrape_interval, metrics_path, params
+    mpc get settings -f prometheus.yaml -n node_exporter
+
+    # list relabel_configs rules of job with their index, --metric for metric_relabel_configs
+    mpc get relabel -f prometheus.yaml -n node_exporter --metric
+
+    # list alertmanager groups of alerting with their index, scheme, path_prefix and targets
+    mpc get alertmanagers -f prometheus.yaml
+
+    # list probed urls of blackbox probe job, all probe jobs are listed if -n is not specified
+    mpc get probe -f prometheus.yaml -n http_probe
+
+    # output as json, yaml, table, wide table, or extract fields by jsonpath
+    mpc get targets -f prometheus.yaml -n node_exporter -o json
+    mpc get jobs -f prometheus.yaml -o jsonpath='{[*].name}'
 `,
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -38,8 +75,27 @@ Examples:
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := outputOpts.parse(); err != nil {
+				return err
+			}
+			isAllJobs := resourceArg == Jobs || resourceArg == Alertmanagers || resourceArg == Probe || (resourceArg == Targets && selectorFlag != "")
+			if !isAllJobs {
+				if err := checkJobName(jobNameFlag, "get"); err != nil {
+					return err
+				}
+			}
 
 			switch resourceArg {
+			case Jobs:
+				jobs, err := runJobsGetCommand(fileFlag)
+				if err != nil {
+					return err
+				}
+				if outputOpts.isText() {
+					return jobsTable(jobs).print(true)
+				}
+				return outputOpts.print(jobs, jobsTable(jobs))
+
 			case Job:
 				job, err := runJobGetCommand(&jobGetOptions{
 					file: fileFlag,
@@ -48,12 +104,54 @@ Examples:
 				if err != nil {
 					return err
 				}
-				fmt.Println(string(job))
+				if outputOpts.isText() || outputOpts.format == OutputYAML {
+					fmt.Println(string(job))
+					return nil
+				}
+				var v interface{}
+				if err = yaml.Unmarshal(job, &v); err != nil {
+					return err
+				}
+				return outputOpts.print(v, nil)
 
 			case Targets:
+				if selectorFlag != "" {
+					targets, err := runTargetsQueryCommand(&targetsQueryOptions{
+						file:     fileFlag,
+						name:     jobNameFlag,
+						selector: selectorFlag,
+					})
+					if err != nil {
+						return err
+					}
+					if outputOpts.isText() {
+						return targetsTable(targets).print(true)
+					}
+					return outputOpts.print(targets, targetsTable(targets))
+				}
+
+				if showLabelsFlag || !outputOpts.isText() {
+					targets, err := runTargetsWithLabelsGetCommand(&targetsGetOptions{
+						file:  fileFlag,
+						name:  jobNameFlag,
+						group: groupFlag,
+					})
+					if err != nil {
+						return err
+					}
+					if outputOpts.isText() {
+						for _, target := range targets {
+							fmt.Printf("%s %v\n", target.Address, target.Labels)
+						}
+						return nil
+					}
+					return outputOpts.print(targets, targetsTable(targets))
+				}
+
 				targets, err := runTargetsGetCommand(&targetsGetOptions{
-					file: fileFlag,
-					name: jobNameFlag,
+					file:  fileFlag,
+					name:  jobNameFlag,
+					group: groupFlag,
 				})
 				if err != nil {
 					return err
@@ -62,17 +160,122 @@ Examples:
 
 			case Labels:
 				labels, err := runLabelsGetCommand(&labelsGetOptions{
+					file:  fileFlag,
+					name:  jobNameFlag,
+					group: groupFlag,
+				})
+				if err != nil {
+					return err
+				}
+				if outputOpts.isText() {
+					fmt.Println(labels)
+					return nil
+				}
+				table := newOutputTable("NAME", "VALUE")
+				for _, name := range promConf.SortedKeys(labels) {
+					table.addRow(name, labels[name])
+				}
+				return outputOpts.print(labels, table)
+
+			case Groups:
+				groups, err := runGroupsGetCommand(&groupsGetOptions{
 					file: fileFlag,
 					name: jobNameFlag,
 				})
 				if err != nil {
-					if strings.Contains(err.Error(), "no value found") {
-						fmt.Println(labels)
-						return nil
+					return err
+				}
+				if outputOpts.isText() {
+					for i, group := range groups {
+						fmt.Printf("[%d] labels: %v, targets: %v\n", i, group.Labels, group.Targets)
+					}
+					return nil
+				}
+				outs := []*groupOutput{}
+				table := newOutputTable("INDEX", "TARGETS", "LABELS").addWideColumns("ADDRESSES")
+				for i, group := range groups {
+					out := &groupOutput{Index: i, Targets: group.Targets, Labels: group.Labels}
+					if out.Labels == nil {
+						out.Labels = map[string]string{}
+					}
+					outs = append(outs, out)
+					table.addRow(i, len(group.Targets), formatLabels(group.Labels), strings.Join(group.Targets, ","))
+				}
+				return outputOpts.print(outs, table)
+
+			case Settings:
+				settings, err := runSettingsGetCommand(&settingsGetOptions{
+					file: fileFlag,
+					name: jobNameFlag,
+				})
+				if err != nil {
+					return err
+				}
+				if outputOpts.isText() {
+					for _, name := range sortedSettingNames(settings) {
+						fmt.Printf("%s: %s\n", name, settings[name])
 					}
+					return nil
+				}
+				table := newOutputTable("NAME", "VALUE")
+				for _, name := range sortedSettingNames(settings) {
+					table.addRow(name, settings[name])
+				}
+				return outputOpts.print(settings, table)
+
+			case Relabel:
+				rules, err := runRelabelGetCommand(&relabelGetOptions{
+					file: fileFlag,
+					name: jobNameFlag,
+					kind: relabelOpts.kind(),
+				})
+				if err != nil {
+					return err
+				}
+				if outputOpts.isText() {
+					for i, rule := range rules {
+						fmt.Printf("[%d] %s\n", i, rule)
+					}
+					return nil
+				}
+				outs := []*relabelOutput{}
+				table := newOutputTable("INDEX", "ACTION", "RULE")
+				for i, rule := range rules {
+					outs = append(outs, &relabelOutput{Index: i, RelabelConfig: rule})
+					action := rule.Action
+					if action == "" {
+						action = "replace"
+					}
+					table.addRow(i, action, rule)
+				}
+				return outputOpts.print(outs, table)
+
+			case Alertmanagers:
+				ams, err := runAlertmanagersGetCommand(fileFlag, groupFlag)
+				if err != nil {
 					return err
 				}
-				fmt.Println(labels)
+				if outputOpts.isText() {
+					printAlertmanagers(ams)
+					return nil
+				}
+				return outputOpts.print(ams, alertmanagersTable(ams))
+
+			case Probe:
+				probes, err := runProbeGetCommand(fileFlag, jobNameFlag)
+				if err != nil {
+					return err
+				}
+				if outputOpts.isText() && jobNameFlag != "" {
+					for _, target := range probes[0].Targets {
+						fmt.Println(target)
+					}
+					return nil
+				}
+				if outputOpts.isText() {
+					return probesTable(probes).print(false)
+				}
+				return outputOpts.print(probes, probesTable(probes))
 
 			default:
 				return fmt.Errorf("unknown resource name '%s'. Use \"mpc resources\" for a complete list of supported resources.\n", resourceArg)
@@ -84,8 +287,12 @@ Examples:
 
 	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
 	cmd.MarkFlagRequired("file")
-	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required, eg: node_exporter")
-	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required except the resource is 'jobs', 'alertmanagers' or 'probe', or targets are selected by labels, eg: node_exporter")
+	cmd.Flags().StringVarP(&groupFlag, "group", "g", "", "static_configs group, index or label selector, or index of alertmanager group if the resource is 'alertmanagers', eg: 1 or dc=sh")
+	cmd.Flags().StringVarP(&selectorFlag, "selector", "l", "", "label selector of effective labels, select targets of all jobs if the resource is 'targets', supports =, !=, =~, !~, eg: env=prod,team!=db")
+	cmd.Flags().BoolVar(&showLabelsFlag, "show-labels", false, "show each target with its effective labels, if the resource is 'targets'")
+	relabelOpts.addKindFlags(cmd, "")
+	outputOpts.addFlags(cmd)
 
 	return cmd
 }
@@ -108,38 +315,150 @@ func runJobGetCommand(options *jobGetOptions) ([]byte, error) {
 }
 
 type targetsGetOptions struct {
-	file string
-	name string
+	file  string
+	name  string
+	group string
 }
 
 func runTargetsGetCommand(options *targetsGetOptions) ([]string, error) {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	sdFile, err := getJobSDFile(cy, options.file, options.name)
+	if err != nil {
+		return nil, err
+	}
+	if sdFile != "" {
+		fsd, err := readFileSD(sdFile)
+		if err != nil {
+			return nil, err
+		}
+		return fsd.GetGroupTargets(sel)
+	}
+
+	return cy.GetJobGroupTargets(options.name, sel)
+}
+
+func runTargetsWithLabelsGetCommand(options *targetsGetOptions) ([]*promConf.Target, error) {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return nil, err
+	}
+
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return nil, err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	return cy.GetJobTargets(options.name)
+	sdFile, err := getJobSDFile(cy, options.file, options.name)
+	if err != nil {
+		return nil, err
+	}
+	if sdFile != "" {
+		fsd, err := readFileSD(sdFile)
+		if err != nil {
+			return nil, err
+		}
+		return fsd.GetGroupTargetsWithLabels(options.name, sel)
+	}
+
+	return cy.GetJobGroupTargetsWithLabels(options.name, sel)
 }
 
 type labelsGetOptions struct {
+	file  string
+	name  string
+	group string
+}
+
+func runLabelsGetCommand(options *labelsGetOptions) (map[string]string, error) {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	return cy.GetJobGroupLabels(options.name, sel)
+}
+
+type groupsGetOptions struct {
 	file string
 	name string
 }
 
-func runLabelsGetCommand(options *labelsGetOptions) (map[string]string, error) {
+func runGroupsGetCommand(options *groupsGetOptions) ([]promConf.StaticConfigs, error) {
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return nil, err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	return cy.GetJobLabels(options.name)
+	return cy.GetJobGroups(options.name)
+}
+
+// groupOutput static_configs分组的输出格式
+type groupOutput struct {
+	Index   int               `json:"index"`
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type settingsGetOptions struct {
+	file string
+	name string
+}
+
+func runSettingsGetCommand(options *settingsGetOptions) (map[string]string, error) {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	return cy.GetJobSettings(options.name)
+}
+
+// 按promConf.SettingNames的顺序排列，params的参数排在最后
+func sortedSettingNames(settings map[string]string) []string {
+	names := []string{}
+	for _, name := range promConf.SettingNames {
+		if _, ok := settings[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	paramNames := []string{}
+	for name := range settings {
+		if strings.HasPrefix(name, promConf.SettingParams+".") {
+			paramNames = append(paramNames, name)
+		}
+	}
+	sort.Strings(paramNames)
+
+	return append(names, paramNames...)
 }
 
 // ---------------------------------------------------------------------------------------
 
 func readPrometheusConfigFile(file string) ([]byte, error) {
+	if isRemoteFile(file) {
+		return readRemoteFile(file)
+	}
+
 	_, err := os.Stat(file)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func historyCommand() *cobra.Command {
+	var (
+		fileFlag   string
+		pruneFlag  bool
+		outputOpts = &outputOptions{}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List backups of prometheus configuration file",
+		Long: `list backups of prometheus configuration file, every write saves a copy of the previous
+content into the bak directory next to the file, the changes column summarizes what the
+next write changed.
+
+Examples:
+    mpc history -f prometheus.yaml
+
+    # list backups as json, including the path of each backup file
+    mpc history -f prometheus.yaml -o json
+
+    # delete backups beyond the retention policy
+    mpc history -f prometheus.yaml --prune --backup-keep 20 --backup-max-age 30d
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pruneFlag {
+				return runHistoryPruneCommand(fileFlag)
+			}
+			if err := outputOpts.parse(); err != nil {
+				return err
+			}
+			return runHistoryCommand(fileFlag, outputOpts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().BoolVar(&pruneFlag, "prune", false, "delete backups beyond the retention policy set by --backup-keep and --backup-max-age")
+	outputOpts.addFlags(cmd)
+
+	return cmd
+}
+
+func rollbackCommand() *cobra.Command {
+	var (
+		fileFlag, toFlag string
+		reloadFlag       bool
+		reloadOpts       = &reloadFlags{}
+	)
+
+	writeOpts := &writeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Restore prometheus configuration file from a backup",
+		Long: `restore prometheus configuration file from a backup, the current content is backed up
+before it is overwritten, so a rollback can be rolled back too.
+
+Examples:
+    # restore the latest backup
+    mpc rollback -f prometheus.yaml --to 1
+
+    # restore the backup at the time listed by "mpc history", and make it effective
+    mpc rollback -f prometheus.yaml --to 20220102150405.000 --reload -p http://127.0.0.1:9090/-/reload
+
+    # print the unified diff of the rollback without writing
+    mpc rollback -f prometheus.yaml --to 2 --dry-run
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if toFlag == "" {
+				return fmt.Errorf("you must specify the backup to restore, eg: --to 1")
+			}
+			backup, err := runRollbackCommand(&rollbackOptions{
+				file:  fileFlag,
+				to:    toFlag,
+				write: writeOpts,
+			})
+			if err != nil {
+				return err
+			}
+			if writeOpts.dryRun || writeOpts.output != "" {
+				return writeOpts.result()
+			}
+			fmt.Printf("%s has been restored from %s\n", fileFlag, backup.File)
+
+			if reloadFlag {
+				return runReloadCommand(reloadOpts)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVar(&toFlag, "to", "", "backup time or number listed by 'mpc history', 1 is the latest backup, required")
+	cmd.Flags().BoolVar(&reloadFlag, "reload", false, "make the prometheus configuration effective after rollback")
+	cmd.Flags().StringVarP(&reloadOpts.promURL, "promURL", "p", "http://127.0.0.1:9090/-/reload", "prometheus url, if reload is set")
+	reloadOpts.addFlags(cmd)
+	writeOpts.addFlags(cmd)
+
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+// historyOutput 备份的输出格式
+type historyOutput struct {
+	N       int    `json:"n"`
+	Time    string `json:"time"`
+	File    string `json:"file"`
+	Changes string `json:"changes"`
+}
+
+func runHistoryCommand(file string, outputOpts *outputOptions) error {
+	if isRemoteFile(file) {
+		return fmt.Errorf("backups of a remote file are kept on the remote server, listing them is not supported")
+	}
+
+	backups, err := promConf.ListBackups(file)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 && outputOpts.isText() {
+		fmt.Printf("no backup found for %s\n", file)
+		return nil
+	}
+
+	// 备份是修改前的内容，与下一个版本比较得到修改内容，最新备份的下一个版本是当前文件
+	nextData, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	outs := []*historyOutput{}
+	table := newOutputTable("N", "TIME", "CHANGES").addWideColumns("FILE")
+	for i, backup := range backups {
+		data, err := ioutil.ReadFile(backup.File)
+		if err != nil {
+			return err
+		}
+		out := &historyOutput{N: i + 1, Time: backup.ID(), File: backup.File, Changes: promConf.SummarizeChange(data, nextData)}
+		outs = append(outs, out)
+		table.addRow(out.N, out.Time, out.Changes, out.File)
+		nextData = data
+	}
+
+	if outputOpts.isText() {
+		return table.print(false)
+	}
+	return outputOpts.print(outs, table)
+}
+
+func runHistoryPruneCommand(file string) error {
+	if backupPolicy.IsUnlimited() {
+		return fmt.Errorf("you must specify the retention policy by --backup-keep or --backup-max-age")
+	}
+
+	removed, err := promConf.PruneBackups(file, backupPolicy)
+	if err != nil {
+		return err
+	}
+	for _, backup := range removed {
+		fmt.Printf("deleted %s\n", backup.File)
+	}
+	fmt.Printf("%d backups deleted\n", len(removed))
+
+	return nil
+}
+
+type rollbackOptions struct {
+	file  string
+	to    string
+	write *writeOptions
+}
+
+func runRollbackCommand(options *rollbackOptions) (*promConf.Backup, error) {
+	if isRemoteFile(options.file) {
+		return nil, fmt.Errorf("backups of a remote file are kept on the remote server, rollback is not supported")
+	}
+
+	backup, err := promConf.FindBackup(options.file, options.to)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return nil, err
+	}
+	bkData, err := ioutil.ReadFile(backup.File)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(bkData)
+	return backup, options.write.write(options.file, data, cy.Data, cy)
+}
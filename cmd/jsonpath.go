@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 执行jsonpath模板，{}中为路径表达式或双引号字符串，其他为原样输出的文本，支持\n和\t，
+// 路径支持 $、.name、['name']、[n]、[-n]、[*]、.*，多个结果用空格分隔，例如 {[*].address}，
+// 与kubectl一致，key或数组下标不存在时返回错误，以区分值为空的情况
+func executeJSONPath(template string, data interface{}) (string, error) {
+	buf := &strings.Builder{}
+	for {
+		start := strings.Index(template, "{")
+		if start < 0 {
+			buf.WriteString(unescapeText(template))
+			break
+		}
+		end := strings.Index(template[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("jsonpath template '%s' is invalid, unclosed '{'", template)
+		}
+		end += start
+
+		buf.WriteString(unescapeText(template[:start]))
+		expr := strings.TrimSpace(template[start+1 : end])
+		if len(expr) >= 2 && expr[0] == '"' && expr[len(expr)-1] == '"' {
+			// 字符串常量，例如{"\n"}
+			literal, err := strconv.Unquote(expr)
+			if err != nil {
+				return "", fmt.Errorf("jsonpath string '%s' is invalid, %v", expr, err)
+			}
+			buf.WriteString(literal)
+			template = template[end+1:]
+			continue
+		}
+		values, err := evalJSONPath(expr, data)
+		if err != nil {
+			return "", err
+		}
+		items := []string{}
+		for _, v := range values {
+			item, err := formatJSONValue(v)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		buf.WriteString(strings.Join(items, " "))
+
+		template = template[end+1:]
+	}
+
+	return buf.String(), nil
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(s)
+}
+
+// 字符串和数字原样输出，对象和数组输出为json
+func formatJSONValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	}
+
+	out, err := json.Marshal(v)
+	return string(out), err
+}
+
+// 计算路径表达式的所有结果
+func evalJSONPath(path string, data interface{}) ([]interface{}, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	values := []interface{}{data}
+	for path != "" {
+		var (
+			next []interface{}
+			err  error
+		)
+
+		switch {
+		case strings.HasPrefix(path, ".."):
+			return nil, fmt.Errorf("jsonpath '%s' is invalid, recursive descent is not supported", path)
+
+		case strings.HasPrefix(path, "."):
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			name := path[:end]
+			path = path[end:]
+			if name == "" {
+				// {.}表示当前对象
+				continue
+			}
+			if name == "*" {
+				next = jsonPathWildcard(values)
+			} else {
+				next, err = jsonPathField(values, name)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+		case strings.HasPrefix(path, "["):
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath '%s' is invalid, unclosed '['", path)
+			}
+			next, err = jsonPathSubscript(values, strings.TrimSpace(path[1:end]))
+			if err != nil {
+				return nil, err
+			}
+			path = path[end+1:]
+
+		default:
+			return nil, fmt.Errorf("jsonpath '%s' is invalid, eg: {[*].address}", path)
+		}
+
+		values = next
+	}
+
+	return values, nil
+}
+
+// 对象的所有值按key排序，数组的所有元素
+func jsonPathWildcard(values []interface{}) []interface{} {
+	next := []interface{}{}
+	for _, v := range values {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			keys := []string{}
+			for key := range val {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				next = append(next, val[key])
+			}
+		case []interface{}:
+			next = append(next, val...)
+		}
+	}
+	return next
+}
+
+// 所有对象的name字段，所有对象都没有name字段时返回错误，没有输入时(例如空数组的[*])没有输出
+func jsonPathField(values []interface{}, name string) ([]interface{}, error) {
+	next := []interface{}{}
+	for _, v := range values {
+		if m, ok := v.(map[string]interface{}); ok {
+			if field, ok := m[name]; ok {
+				next = append(next, field)
+			}
+		}
+	}
+	if len(values) > 0 && len(next) == 0 {
+		return nil, fmt.Errorf("%s is not found", name)
+	}
+	return next, nil
+}
+
+func jsonPathSubscript(values []interface{}, subscript string) ([]interface{}, error) {
+	if subscript == "*" {
+		return jsonPathWildcard(values), nil
+	}
+
+	if len(subscript) >= 2 && (subscript[0] == '\'' || subscript[0] == '"') && subscript[len(subscript)-1] == subscript[0] {
+		return jsonPathField(values, subscript[1:len(subscript)-1])
+	}
+
+	index, err := strconv.Atoi(subscript)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath subscript '[%s]' is invalid, supports [n], [*] and ['name']", subscript)
+	}
+	next := []interface{}{}
+	for _, v := range values {
+		if arr, ok := v.([]interface{}); ok {
+			i := index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i >= 0 && i < len(arr) {
+				next = append(next, arr[i])
+			}
+		}
+	}
+	if len(values) > 0 && len(next) == 0 {
+		return nil, fmt.Errorf("array index %d is out of range", index)
+	}
+	return next, nil
+}
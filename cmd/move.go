@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func moveCommand() *cobra.Command {
+	var (
+		resourceArg                string
+		fileFlag, fromFlag, toFlag string
+		groupFlag                  string
+		valuesFlag                 []string
+	)
+
+	writeOpts := &writeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "move targets",
+		Short: "Move targets from one job to another job",
+		Long: `move targets from one job to another job in one edit, the targets must be in static_configs
+of source job. by default, the labels of the static_configs group where the targets come from are kept,
+the targets are added to the group of destination job with the same labels, or to a new group if there
+is none, use -g to add the targets to the specified group of destination job instead.
+
+Examples:
+    mpc move targets -f prometheus.yaml -v 10.0.0.1:9100,10.0.0.2:9100 --from node_exporter --to node_exporter_dev
+
+    # add to the group whose labels contain dc=sh in destination job
+    mpc move targets -f prometheus.yaml -v 10.0.0.1:9100 --from node_exporter --to node_exporter_dev -g dc=sh
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("you must specify the resource, eg: mpc move %s\n", Targets)
+			}
+			resourceArg = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch resourceArg {
+			case Targets:
+				if fromFlag == "" || toFlag == "" {
+					return fmt.Errorf("source and destination job are required, Use \"mpc move targets --from <job> --to <job>\" to specify them")
+				}
+				if err := checkSliceValues(valuesFlag, "move"); err != nil {
+					return err
+				}
+				err := runTargetsMoveCommand(&targetsMoveOptions{
+					file:   fileFlag,
+					from:   fromFlag,
+					to:     toFlag,
+					group:  groupFlag,
+					values: valuesFlag,
+					write:  writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			default:
+				return fmt.Errorf("unknown resource name '%s', only supports %s\n", resourceArg, Targets)
+			}
+
+			return writeOpts.result()
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringSliceVarP(&valuesFlag, "targets-value", "v", nil, "targets to move, required, eg: 127.0.0.1:9100")
+	cmd.Flags().StringVar(&fromFlag, "from", "", "source job name, required, eg: node_exporter")
+	cmd.Flags().StringVar(&toFlag, "to", "", "destination job name, required, eg: node_exporter_dev")
+	cmd.Flags().StringVarP(&groupFlag, "group", "g", "", "static_configs group of destination job, index or label selector, eg: 1 or dc=sh")
+	writeOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+type targetsMoveOptions struct {
+	file   string
+	from   string
+	to     string
+	group  string
+	values []string
+	write  *writeOptions
+}
+
+func runTargetsMoveCommand(options *targetsMoveOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.MoveJobTargets(options.from, options.to, sel, options.values)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
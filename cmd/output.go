@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// 查询命令的输出格式
+const (
+	OutputText     = ""         // 默认格式，与之前的输出保持一致
+	OutputJSON     = "json"     // json，字段与yaml格式相同
+	OutputYAML     = "yaml"     // yaml
+	OutputTable    = "table"    // 表格
+	OutputWide     = "wide"     // 表格，显示更多的列
+	OutputJSONPath = "jsonpath" // 按jsonpath模板输出，例如 jsonpath={[*].address}
+)
+
+// outputOptions 查询结果的输出格式，所有查询命令共用
+type outputOptions struct {
+	format   string
+	template string // jsonpath模板
+}
+
+func (o *outputOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.format, "output", "o", "", "output format, json, yaml, table, wide or jsonpath=<template>, eg: jsonpath={[*].address}")
+}
+
+// 校验输出格式，拆分jsonpath模板
+func (o *outputOptions) parse() error {
+	if strings.HasPrefix(o.format, OutputJSONPath+"=") {
+		o.template = strings.TrimPrefix(o.format, OutputJSONPath+"=")
+		o.format = OutputJSONPath
+	}
+
+	switch o.format {
+	case OutputText, OutputJSON, OutputYAML, OutputTable, OutputWide:
+		return nil
+	case OutputJSONPath:
+		if o.template == "" {
+			return fmt.Errorf("jsonpath template is empty, eg: -o jsonpath={[*].address}")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown output format '%s', supports json, yaml, table, wide and jsonpath=<template>", o.format)
+}
+
+// isText 是否为默认格式
+func (o *outputOptions) isText() bool {
+	return o.format == OutputText
+}
+
+// print 按输出格式打印查询结果，v为json、yaml、jsonpath格式输出的对象，
+// table为表格格式输出的内容，为nil时不支持表格格式
+func (o *outputOptions) print(v interface{}, table *outputTable) error {
+	switch o.format {
+	case OutputJSON:
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+
+	case OutputYAML:
+		out, err := marshalYAML(v)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		return nil
+
+	case OutputJSONPath:
+		data, err := toJSONValue(v)
+		if err != nil {
+			return err
+		}
+		out, err := executeJSONPath(o.template, data)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+	}
+
+	if table == nil {
+		return fmt.Errorf("output format '%s' is not supported, use json, yaml or jsonpath", o.format)
+	}
+	return table.print(o.format == OutputWide)
+}
+
+// 转为json解析后的对象，jsonpath在此基础上查询，保证与json格式的字段一致
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	return value, err
+}
+
+// 经过json转为yaml，字段名称和顺序与json格式相同
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &yaml.Node{}
+	err = yaml.Unmarshal(data, node)
+	if err != nil {
+		return nil, err
+	}
+	clearJSONStyle(node)
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(node)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), encoder.Close()
+}
+
+// json解析后的节点是flow风格和双引号，转为yaml块风格，非空的对象和数组才使用块风格
+func clearJSONStyle(node *yaml.Node) {
+	node.Style = 0
+	if (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && len(node.Content) == 0 {
+		node.Style = yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		clearJSONStyle(child)
+	}
+}
+
+// ---------------------------------------------------------------------------------------
+
+// outputColumn 表格的列，wide为true时只在wide格式显示
+type outputColumn struct {
+	name string
+	wide bool
+}
+
+// outputTable 表格格式输出的内容
+type outputTable struct {
+	columns []outputColumn
+	rows    [][]string
+}
+
+func newOutputTable(names ...string) *outputTable {
+	t := &outputTable{}
+	for _, name := range names {
+		t.columns = append(t.columns, outputColumn{name: name})
+	}
+	return t
+}
+
+// addWideColumns 添加只在wide格式显示的列
+func (t *outputTable) addWideColumns(names ...string) *outputTable {
+	for _, name := range names {
+		t.columns = append(t.columns, outputColumn{name: name, wide: true})
+	}
+	return t
+}
+
+func (t *outputTable) addRow(values ...interface{}) {
+	row := []string{}
+	for _, v := range values {
+		row = append(row, fmt.Sprint(v))
+	}
+	t.rows = append(t.rows, row)
+}
+
+func (t *outputTable) print(wide bool) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	names := []string{}
+	for _, c := range t.columns {
+		if !c.wide || wide {
+			names = append(names, c.name)
+		}
+	}
+	fmt.Fprintln(w, strings.Join(names, "\t"))
+
+	for _, row := range t.rows {
+		values := []string{}
+		for i, c := range t.columns {
+			if i < len(row) && (!c.wide || wide) {
+				values = append(values, row[i])
+			}
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+// probeFlags probe资源的参数，add命令使用
+type probeFlags struct {
+	module       string
+	exporter     string
+	blackboxFile string // blackbox exporter的配置文件
+	prober       string
+}
+
+func (p *probeFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.module, "module", "", "blackbox module of probe job, required to create probe job, if the resource is 'probe', eg: http_2xx")
+	cmd.Flags().StringVar(&p.exporter, "exporter", "", "address of blackbox exporter, required to create probe job, if the resource is 'probe', eg: 127.0.0.1:9115")
+	cmd.Flags().StringVar(&p.blackboxFile, "blackbox-file", "", "blackbox exporter configuration file, the module is added to it if missing, if the resource is 'probe', eg: blackbox.yml")
+	cmd.Flags().StringVar(&p.prober, "prober", "", "prober of the module added to blackbox-file, inferred from the module name by default, eg: http, tcp, icmp, dns, grpc")
+}
+
+// ---------------------------------------------------------------------------------------
+
+// 获取探测job，jobName为空时获取所有探测job
+func runProbeGetCommand(file string, jobName string) ([]*promConf.ProbeJob, error) {
+	data, err := readPrometheusConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	if jobName == "" {
+		return cy.GetProbeJobs()
+	}
+
+	probe, err := cy.GetProbeJob(jobName)
+	if err != nil {
+		return nil, err
+	}
+	return []*promConf.ProbeJob{probe}, nil
+}
+
+func probesTable(probes []*promConf.ProbeJob) *outputTable {
+	table := newOutputTable("JOB", "MODULE", "EXPORTER", "TARGETS").addWideColumns("URLS")
+	for _, probe := range probes {
+		table.addRow(probe.Job, probe.Module, probe.Exporter, len(probe.Targets), strings.Join(probe.Targets, ","))
+	}
+	return table
+}
+
+type probeEditOptions struct {
+	file         string
+	name         string
+	values       []string
+	targetLabels map[string]string
+	probe        *probeFlags
+	write        *writeOptions
+}
+
+func runProbeAddCommand(options *probeEditOptions) error {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.AddProbeTargets(options.name, options.probe.module, options.probe.exporter, options.values, options.targetLabels)
+	if err != nil {
+		return err
+	}
+
+	files := []*editedFile{}
+	// 在blackbox exporter配置文件中添加探测模块，与prometheus配置文件一起写入
+	if options.probe.blackboxFile != "" {
+		probe, err := cy.GetProbeJob(options.name)
+		if err != nil {
+			return err
+		}
+		bf, err := addBlackboxModule(options.probe.blackboxFile, probe.Module, options.probe.prober)
+		if err != nil {
+			return err
+		}
+		files = append(files, bf)
+	}
+	files = append(files, &editedFile{file: options.file, oldData: data, newData: cy.Data, pf: cy})
+
+	return options.write.writeFiles(files...)
+}
+
+// 添加探测模块到blackbox exporter配置文件，文件不存在时创建
+func addBlackboxModule(file string, module string, prober string) (*editedFile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	bc := promConf.NewBlackboxConfig(data)
+	err = bc.AddModule(module, prober, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	return &editedFile{file: file, oldData: data, newData: bc.Data, pf: bc}, nil
+}
+
+func runProbeDelCommand(options *probeEditOptions) error {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.DelProbeTargets(options.name, options.values)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+// 获取所有job的target及其生效的标签，job唯一引用的file_sd_configs文件中的target也包括在内
+func loadAllTargets(file string) ([]*promConf.Target, error) {
+	data, err := readPrometheusConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	jobs, err := cy.GetJobs()
+	if err != nil {
+		return nil, err
+	}
+	staticTargets, err := cy.GetAllTargetsWithLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []*promConf.Target{}
+	for _, job := range jobs {
+		for _, target := range staticTargets {
+			if target.Job == job.Name {
+				targets = append(targets, target)
+			}
+		}
+
+		fsd, err := getJobFileSD(cy, file, job.Name)
+		if err != nil {
+			return nil, err
+		}
+		if fsd != nil {
+			sdTargets, err := fsd.GetTargetsWithLabels(job.Name)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, sdTargets...)
+		}
+	}
+
+	return targets, nil
+}
+
+// 获取job唯一引用的file_sd_configs文件，没有时返回nil
+func getJobFileSD(cy *promConf.ConfigYaml, file string, jobName string) (*promConf.FileSD, error) {
+	sdFile, err := getJobSDFile(cy, file, jobName)
+	if err != nil || sdFile == "" {
+		return nil, err
+	}
+
+	return readFileSD(sdFile)
+}
+
+type targetsQueryOptions struct {
+	file     string
+	name     string
+	host     string
+	selector string
+}
+
+func runTargetsQueryCommand(options *targetsQueryOptions) ([]*promConf.Target, error) {
+	selector, err := promConf.ParseLabelSelector(options.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := loadAllTargets(options.file)
+	if err != nil {
+		return nil, err
+	}
+
+	return promConf.FilterTargets(targets, &promConf.TargetQuery{
+		Job:      options.name,
+		Host:     options.host,
+		Selector: selector,
+	}), nil
+}
+
+func targetsTable(targets []*promConf.Target) *outputTable {
+	table := newOutputTable("JOB", "GROUP", "TARGET").addWideColumns("LABELS")
+	for _, target := range targets {
+		table.addRow(target.Job, target.Group, target.Address, formatLabels(target.Labels))
+	}
+	return table
+}
+
+// 标签按名称排序，格式为k1=v1,k2=v2
+func formatLabels(labels map[string]string) string {
+	kvs := []string{}
+	for _, name := range promConf.SortedKeys(labels) {
+		kvs = append(kvs, name+"="+labels[name])
+	}
+	return strings.Join(kvs, ",")
+}
+
+// 获取所有job的概要，job唯一引用的file_sd_configs文件中的分组和target也计算在内
+func runJobsGetCommand(file string) ([]*promConf.JobSummary, error) {
+	data, err := readPrometheusConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	jobs, err := cy.GetJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		fsd, err := getJobFileSD(cy, file, job.Name)
+		if err != nil {
+			return nil, err
+		}
+		if fsd == nil {
+			continue
+		}
+		groups, err := fsd.GetGroups()
+		if err != nil {
+			return nil, err
+		}
+		job.Groups = len(groups)
+		for _, group := range groups {
+			job.Targets += len(group.Targets)
+		}
+	}
+
+	return jobs, nil
+}
+
+func jobsTable(jobs []*promConf.JobSummary) *outputTable {
+	table := newOutputTable("JOB", "GROUPS", "TARGETS").addWideColumns("SD_CONFIGS")
+	for _, job := range jobs {
+		table.addRow(job.Name, job.Groups, job.Targets, strings.Join(job.SDConfigs, ","))
+	}
+	return table
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+// relabelFlags relabel资源的参数，get、add、delete、replace命令共用
+type relabelFlags struct {
+	metric bool // 是否为metric_relabel_configs
+	index  int  // 规则位置
+
+	sourceLabels []string
+	separator    string
+	regex        string
+	modulus      uint64
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+func (r *relabelFlags) addKindFlags(cmd *cobra.Command, indexUsage string) {
+	cmd.Flags().BoolVar(&r.metric, "metric", false, "use metric_relabel_configs instead of relabel_configs, if the resource is 'relabel'")
+	if indexUsage != "" {
+		cmd.Flags().IntVarP(&r.index, "index", "i", -1, indexUsage)
+	}
+}
+
+func (r *relabelFlags) addRuleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&r.sourceLabels, "source-labels", nil, "source labels of relabel rule, eg: __name__")
+	cmd.Flags().StringVar(&r.separator, "separator", "", "separator of source label values, default is ';'")
+	cmd.Flags().StringVar(&r.regex, "regex", "", "regular expression of relabel rule, eg: go_.*")
+	cmd.Flags().Uint64Var(&r.modulus, "modulus", 0, "modulus of relabel rule, required if action is hashmod")
+	cmd.Flags().StringVar(&r.targetLabel, "target-label", "", "target label of relabel rule, eg: instance")
+	cmd.Flags().StringVar(&r.replacement, "replacement", "", "replacement of relabel rule, eg: ${1}")
+	cmd.Flags().StringVar(&r.action, "action", "", "action of relabel rule, eg: replace, keep, drop, labeldrop, labelkeep, labelmap, hashmod")
+}
+
+func (r *relabelFlags) kind() string {
+	if r.metric {
+		return promConf.MetricRelabelConfigs
+	}
+	return promConf.RelabelConfigs
+}
+
+func (r *relabelFlags) rule(cmd *cobra.Command) (*promConf.RelabelConfig, error) {
+	rule := &promConf.RelabelConfig{
+		SourceLabels: r.sourceLabels,
+		Separator:    r.separator,
+		Regex:        r.regex,
+		Modulus:      r.modulus,
+		TargetLabel:  r.targetLabel,
+		Action:       r.action,
+	}
+	// replacement可以设置为空
+	if cmd.Flags().Changed("replacement") {
+		replacement := r.replacement
+		rule.Replacement = &replacement
+	}
+
+	if rule.Action == "" && rule.TargetLabel == "" && rule.Regex == "" && len(rule.SourceLabels) == 0 {
+		return nil, fmt.Errorf("you must specify the relabel rule, eg: --source-labels __name__ --regex 'go_.*' --action drop")
+	}
+
+	return rule, nil
+}
+
+func checkRelabelIndex(index int, action string) error {
+	if index < 0 {
+		return fmt.Errorf("you must specify the index of relabel rule to %s, use \"mpc get relabel\" to list rules. ", action)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------------------
+
+type relabelGetOptions struct {
+	file string
+	name string
+	kind string
+}
+
+func runRelabelGetCommand(options *relabelGetOptions) ([]*promConf.RelabelConfig, error) {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return nil, err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	return cy.GetJobRelabelConfigs(options.name, options.kind)
+}
+
+// relabelOutput relabel规则的输出格式，增加规则的索引
+type relabelOutput struct {
+	Index int `json:"index"`
+	*promConf.RelabelConfig
+}
+
+type relabelEditOptions struct {
+	file  string
+	name  string
+	kind  string
+	index int
+	rule  *promConf.RelabelConfig
+	write *writeOptions
+}
+
+func runRelabelAddCommand(options *relabelEditOptions) error {
+	return editRelabel(options, func(cy *promConf.ConfigYaml) error {
+		return cy.AddJobRelabelConfig(options.name, options.kind, options.index, options.rule)
+	})
+}
+
+func runRelabelDelCommand(options *relabelEditOptions) error {
+	return editRelabel(options, func(cy *promConf.ConfigYaml) error {
+		return cy.DelJobRelabelConfig(options.name, options.kind, options.index)
+	})
+}
+
+func runRelabelReplaceCommand(options *relabelEditOptions) error {
+	return editRelabel(options, func(cy *promConf.ConfigYaml) error {
+		return cy.ReplaceJobRelabelConfig(options.name, options.kind, options.index, options.rule)
+	})
+}
+
+func editRelabel(options *relabelEditOptions, fn func(cy *promConf.ConfigYaml) error) error {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = fn(cy)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
@@ -1,34 +1,236 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/gssh"
 	"github.com/zhufuyi/mpc/promConf"
 )
 
+// 使prometheus重新加载配置的方式
+const (
+	ReloadMethodHTTP      = "http"       // 调用reload接口，需要prometheus开启--web.enable-lifecycle
+	ReloadMethodSSHSignal = "ssh-signal" // 通过ssh向prometheus进程发送SIGHUP信号
+	ReloadMethodSystemd   = "systemd"    // 通过ssh执行systemctl reload
+)
+
 func reloadCommand() *cobra.Command {
-	var promURLFlag string
+	reloadOpts := &reloadFlags{}
 
 	cmd := &cobra.Command{
 		Use:   "reload",
 		Short: "Make the prometheus configuration effective",
-		Long: `make the prometheus configuration effective
+		Long: `make the prometheus configuration effective, the status code of the reload api is checked,
+then prometheus_config_last_reload_successful and the reload timestamp are read from /metrics
+to confirm that the configuration is loaded. exit code is 8 if the reload failed.
+
+prometheus without --web.enable-lifecycle can be reloaded over ssh, by sending SIGHUP to the
+prometheus process (--method ssh-signal) or running systemctl reload (--method systemd), the
+reload is confirmed from /metrics in the same way. the ssh host is the host of promURL by default.
 
 Examples:
     mpc reload -p http://127.0.0.1:9090/-/reload
+
+    # prometheus behind basic auth and https with a private CA
+    mpc reload -p https://prom.example.com/-/reload --username admin --password 123456 --ca-file ca.pem
+
+    # bearer token and client certificate
+    mpc reload -p https://prom.example.com/-/reload --bearer-token-file token --cert-file client.pem --key-file client-key.pem
+
+    # send SIGHUP to the prometheus process over ssh, with the key ~/.ssh/id_rsa
+    mpc reload -p http://192.168.1.10:9090 --method ssh-signal --ssh-user root
+
+    # run "sudo systemctl reload prometheus" over ssh
+    mpc reload -p http://192.168.1.10:9090 --method systemd --ssh-user ops --ssh-password 123456 --sudo
 `,
 		SilenceErrors: true,
 		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := promConf.ConfReload(promURLFlag)
-			if err != nil {
-				fmt.Println(err)
-			}
-			return nil
+			return runReloadCommand(reloadOpts)
 		},
 	}
-	cmd.Flags().StringVarP(&promURLFlag, "promURL", "p", "http://127.0.0.1:9090/-/reload", "prometheus url")
+	cmd.Flags().StringVarP(&reloadOpts.promURL, "promURL", "p", "http://127.0.0.1:9090/-/reload", "prometheus url")
+	reloadOpts.addFlags(cmd)
 
 	return cmd
 }
+
+func runReloadCommand(options *reloadFlags) error {
+	var (
+		status *promConf.ReloadStatus
+		err    error
+	)
+
+	switch options.method {
+	case ReloadMethodHTTP:
+		status, err = promConf.ConfReloadWithOptions(options.promURL, options.client.options())
+	case ReloadMethodSSHSignal, ReloadMethodSystemd:
+		var shell string
+		shell, err = options.ssh.reloadShell(options.method)
+		if err != nil {
+			return err
+		}
+		status, err = promConf.ConfReloadWithTrigger(options.promURL, options.client.options(), func() error {
+			return options.ssh.run(options.promURL, shell)
+		})
+	default:
+		return fmt.Errorf("unknown reload method '%s', supports %s, %s and %s",
+			options.method, ReloadMethodHTTP, ReloadMethodSSHSignal, ReloadMethodSystemd)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("prometheus configuration is reloaded at %s\n", status.LastReloadTime.Format(time.RFC3339))
+	return nil
+}
+
+// ---------------------------------------------------------------------------------------
+
+// reloadFlags reload的参数，reload、rollback等命令共用，promURL参数由命令添加
+type reloadFlags struct {
+	promURL string
+	method  string
+	client  clientFlags
+	ssh     sshFlags
+}
+
+func (r *reloadFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&r.method, "method", ReloadMethodHTTP, "reload method, http, ssh-signal or systemd")
+	r.client.addFlags(cmd)
+	r.ssh.addFlags(cmd)
+}
+
+// clientFlags 访问prometheus http接口的认证、TLS和超时参数，reload、rollback等命令共用
+type clientFlags struct {
+	promConf.ClientOptions
+}
+
+func (c *clientFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&c.Username, "username", "", "username of prometheus basic auth")
+	cmd.Flags().StringVar(&c.Password, "password", "", "password of prometheus basic auth")
+	cmd.Flags().StringVar(&c.BearerToken, "bearer-token", "", "bearer token of prometheus")
+	cmd.Flags().StringVar(&c.BearerTokenFile, "bearer-token-file", "", "file of prometheus bearer token")
+	cmd.Flags().StringVar(&c.CAFile, "ca-file", "", "CA certificate to verify prometheus server certificate")
+	cmd.Flags().StringVar(&c.CertFile, "cert-file", "", "client certificate for prometheus TLS authentication")
+	cmd.Flags().StringVar(&c.KeyFile, "key-file", "", "client key for prometheus TLS authentication")
+	cmd.Flags().BoolVar(&c.InsecureSkipVerify, "insecure-skip-verify", false, "skip verifying prometheus server certificate")
+	cmd.Flags().DurationVar(&c.Timeout, "timeout", promConf.DefaultTimeout, "timeout of each request to prometheus")
+}
+
+func (c *clientFlags) options() *promConf.ClientOptions {
+	return &c.ClientOptions
+}
+
+// ---------------------------------------------------------------------------------------
+
+// ssh执行reload命令的超时时间
+const sshReloadTimeout = 30 * time.Second
+
+// 进程名称和服务名称，拼接到shell命令中，不允许特殊字符
+var shellNameRe = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+
+// sshFlags 通过ssh reload的参数
+type sshFlags struct {
+	host     string // 为空时使用prometheus地址中的主机
+	port     int
+	user     string
+	password string // 为空时使用私钥登录
+	keyFile  string
+	sudo     bool
+	process  string // prometheus进程名称，ssh-signal使用
+	service  string // systemd服务名称，systemd使用
+}
+
+func (s *sshFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&s.host, "ssh-host", "", "ssh host of prometheus server, if method is ssh-signal or systemd, default is the host of promURL")
+	cmd.Flags().IntVar(&s.port, "ssh-port", 22, "ssh port of prometheus server")
+	cmd.Flags().StringVar(&s.user, "ssh-user", "root", "ssh user of prometheus server")
+	cmd.Flags().StringVar(&s.password, "ssh-password", "", "ssh password of prometheus server, the private key is used if empty")
+	cmd.Flags().StringVar(&s.keyFile, "ssh-key", "", "ssh private key file, default is ~/.ssh/id_rsa")
+	cmd.Flags().BoolVar(&s.sudo, "sudo", false, "run the reload command with sudo over ssh")
+	cmd.Flags().StringVar(&s.process, "process", "prometheus", "process name of prometheus, if method is ssh-signal")
+	cmd.Flags().StringVar(&s.service, "service", "prometheus", "systemd service name of prometheus, if method is systemd")
+}
+
+// 生成reload的shell命令
+func (s *sshFlags) reloadShell(method string) (string, error) {
+	var shell string
+	switch method {
+	case ReloadMethodSSHSignal:
+		if !shellNameRe.MatchString(s.process) {
+			return "", fmt.Errorf("process name '%s' is invalid", s.process)
+		}
+		shell = "pkill -HUP -x " + s.process
+	case ReloadMethodSystemd:
+		if !shellNameRe.MatchString(s.service) {
+			return "", fmt.Errorf("service name '%s' is invalid", s.service)
+		}
+		shell = "systemctl reload " + s.service
+	}
+
+	if s.sudo {
+		shell = "sudo -n " + shell
+	}
+	return shell, nil
+}
+
+// 连接ssh服务器，没有指定主机时使用promURL中的主机
+func (s *sshFlags) connect(promURL string) (*gssh.SSHClient, error) {
+	host := s.host
+	if host == "" {
+		u, err := url.Parse(promURL)
+		if err != nil || u.Hostname() == "" {
+			return nil, fmt.Errorf("can not get the ssh host from '%s', please specify --ssh-host", promURL)
+		}
+		host = u.Hostname()
+	}
+
+	if s.password != "" {
+		return gssh.NewPwdSSHClient(host, s.port, s.user, s.password)
+	}
+
+	keyFile := s.keyFile
+	if keyFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		keyFile = filepath.Join(home, ".ssh", "id_rsa")
+	}
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read ssh key error, %v, please specify --ssh-password or --ssh-key", err)
+	}
+	return gssh.NewKeySSHClient(host, s.port, s.user, key)
+}
+
+// 通过ssh执行命令，命令返回非0或输出错误信息时返回错误
+func (s *sshFlags) run(promURL string, shell string) error {
+	client, err := s.connect(promURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sshReloadTimeout)
+	defer cancel()
+
+	result := &gssh.Result{}
+	client.Exec(ctx, shell, result)
+	for range result.StdOut {
+	}
+	if result.Err != nil {
+		return fmt.Errorf("run '%s' over ssh error, %s", shell, strings.TrimSpace(result.Err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/gssh"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+// 远程文件的前缀，例如 ssh://root@192.168.1.10:22/etc/prometheus/prometheus.yml
+const remoteFilePrefix = "ssh://"
+
+// 远程文件的参数，所有命令共用
+var remoteOpts = &remoteFlags{clients: map[string]*gssh.SSHClient{}}
+
+// remoteFlags 通过ssh读写远程服务器上的配置文件的参数
+type remoteFlags struct {
+	host     string // 设置后-f指定的文件为远程服务器上的文件
+	port     int
+	user     string
+	password string // 为空时使用私钥登录
+	keyFile  string
+
+	reloadMethod string // 写入远程文件后reload的方式，为空时不reload
+	promURL      string // reload时prometheus的地址，默认为远程服务器的9090端口
+
+	clients map[string]*gssh.SSHClient // 已连接的服务器，key为user@host:port
+	written []*remoteFile              // 已写入的远程文件
+}
+
+func (r *remoteFlags) addFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&r.host, "remote-host", "", "edit the file(-f) on a remote server over ssh, the file path must be absolute")
+	cmd.PersistentFlags().IntVar(&r.port, "remote-port", 22, "ssh port of remote server")
+	cmd.PersistentFlags().StringVar(&r.user, "remote-user", "root", "ssh user of remote server")
+	cmd.PersistentFlags().StringVar(&r.password, "remote-password", "", "ssh password of remote server, the private key is used if empty")
+	cmd.PersistentFlags().StringVar(&r.keyFile, "remote-key", "", "ssh private key file of remote server, default is ~/.ssh/id_rsa")
+	cmd.PersistentFlags().StringVar(&r.reloadMethod, "remote-reload", "", "reload prometheus after the remote file is written, http, ssh-signal or systemd")
+	cmd.PersistentFlags().StringVar(&r.promURL, "remote-prom-url", "", "prometheus url used by remote-reload, default is http://<remote host>:9090")
+}
+
+// 设置了remote-host时，把-f指定的文件转为远程文件
+func (r *remoteFlags) apply(cmd *cobra.Command) error {
+	if r.reloadMethod != "" && r.reloadMethod != ReloadMethodHTTP &&
+		r.reloadMethod != ReloadMethodSSHSignal && r.reloadMethod != ReloadMethodSystemd {
+		return fmt.Errorf("unknown reload method '%s', supports %s, %s and %s",
+			r.reloadMethod, ReloadMethodHTTP, ReloadMethodSSHSignal, ReloadMethodSystemd)
+	}
+	if r.host == "" {
+		return nil
+	}
+
+	flag := cmd.Flags().Lookup("file")
+	if flag == nil || flag.Value.String() == "" || isRemoteFile(flag.Value.String()) {
+		return nil
+	}
+	file := flag.Value.String()
+	if !path.IsAbs(file) {
+		return fmt.Errorf("the file '%s' on remote server must be an absolute path", file)
+	}
+
+	u := &url.URL{
+		Scheme: "ssh",
+		User:   url.User(r.user),
+		Host:   fmt.Sprintf("%s:%d", r.host, r.port),
+		Path:   file,
+	}
+	return flag.Value.Set(u.String())
+}
+
+// ---------------------------------------------------------------------------------------
+
+// remoteFile 远程服务器上的文件
+type remoteFile struct {
+	user string
+	host string
+	port int
+	path string
+}
+
+func (f *remoteFile) String() string {
+	return fmt.Sprintf("%s%s@%s:%d%s", remoteFilePrefix, f.user, f.host, f.port, f.path)
+}
+
+// 文件是否为远程文件
+func isRemoteFile(file string) bool {
+	return strings.HasPrefix(file, remoteFilePrefix)
+}
+
+// 解析远程文件，格式为 ssh://user@host:port/path，没有用户和端口时使用remote-user和remote-port
+func parseRemoteFile(file string) (*remoteFile, error) {
+	u, err := url.Parse(file)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" || !path.IsAbs(u.Path) {
+		return nil, fmt.Errorf("remote file '%s' is invalid, eg: ssh://root@192.168.1.10:22/etc/prometheus/prometheus.yml", file)
+	}
+
+	rf := &remoteFile{user: remoteOpts.user, host: u.Hostname(), port: remoteOpts.port, path: u.Path}
+	if u.User != nil && u.User.Username() != "" {
+		rf.user = u.User.Username()
+	}
+	if u.Port() != "" {
+		rf.port, err = strconv.Atoi(u.Port())
+		if err != nil {
+			return nil, fmt.Errorf("remote file '%s' has an invalid port", file)
+		}
+	}
+
+	return rf, nil
+}
+
+// 远程文件引用的其他文件，例如file_sd_configs文件，相对路径以远程文件所在目录为起点
+func remoteSiblingFile(file string, ref string) (string, error) {
+	rf, err := parseRemoteFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	sibling := *rf
+	sibling.path = ref
+	if !path.IsAbs(ref) {
+		sibling.path = path.Join(path.Dir(rf.path), ref)
+	}
+	return sibling.String(), nil
+}
+
+// 连接远程服务器，同一个服务器只连接一次
+func (r *remoteFlags) client(rf *remoteFile) (*gssh.SSHClient, error) {
+	key := fmt.Sprintf("%s@%s:%d", rf.user, rf.host, rf.port)
+	if client, ok := r.clients[key]; ok {
+		return client, nil
+	}
+
+	ssh := &sshFlags{host: rf.host, port: rf.port, user: rf.user, password: r.password, keyFile: r.keyFile}
+	client, err := ssh.connect("")
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s error, %v", key, err)
+	}
+	r.clients[key] = client
+	return client, nil
+}
+
+// 关闭所有连接
+func (r *remoteFlags) close() {
+	for key, client := range r.clients {
+		client.Close()
+		delete(r.clients, key)
+	}
+}
+
+// 读取远程文件
+func readRemoteFile(file string) ([]byte, error) {
+	rf, err := parseRemoteFile(file)
+	if err != nil {
+		return nil, err
+	}
+	client, err := remoteOpts.client(rf)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.ReadFile(rf.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &os.PathError{Op: "open", Path: file, Err: os.ErrNotExist}
+		}
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return data, nil
+}
+
+// 写入远程文件，文件已存在时先备份到远程服务器文件同级目录的bak下，再原子写入，
+// 备份和写入的文件保留原文件的权限，备份按--backup-keep和--backup-max-age清理
+func writeRemoteFile(file string, data []byte) error {
+	rf, err := parseRemoteFile(file)
+	if err != nil {
+		return err
+	}
+	client, err := remoteOpts.client(rf)
+	if err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0644)
+	info, err := client.Stat(rf.path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+		err = backupRemoteFile(client, rf.path, perm)
+		if err != nil {
+			return fmt.Errorf("backup %s error, %v", file, err)
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	err = client.WriteFileAtomic(rf.path, data, perm)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	remoteOpts.written = append(remoteOpts.written, rf)
+	return nil
+}
+
+// 备份远程文件到同级目录的bak下，再删除超出保留策略的备份
+func backupRemoteFile(client *gssh.SSHClient, file string, perm os.FileMode) error {
+	data, err := client.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	bkDir := path.Join(path.Dir(file), "bak")
+	bkFile := fmt.Sprintf("%s/%s.%s", bkDir, path.Base(file), time.Now().Format(promConf.BackupTimeFormat))
+	if err = client.MkdirAll(bkDir); err != nil {
+		return err
+	}
+	if err = client.WriteFileAtomic(bkFile, data, perm); err != nil {
+		return err
+	}
+
+	if backupPolicy.IsUnlimited() {
+		return nil
+	}
+	infos, err := client.ReadDir(bkDir)
+	if err != nil {
+		return err
+	}
+	backups := []*promConf.Backup{}
+	for _, info := range infos {
+		if t, ok := promConf.ParseBackupName(path.Base(file), info.Name()); ok && !info.IsDir() {
+			backups = append(backups, &promConf.Backup{File: path.Join(bkDir, info.Name()), Time: t})
+		}
+	}
+	promConf.SortBackups(backups)
+	_, err = promConf.RemoveExpiredBackups(backups, backupPolicy, client.Remove)
+	return err
+}
+
+// 写入远程文件后reload，使用第一个写入的远程文件所在的服务器
+func (r *remoteFlags) reload() error {
+	if r.reloadMethod == "" || len(r.written) == 0 {
+		return nil
+	}
+
+	rf := r.written[0]
+	promURL := r.promURL
+	if promURL == "" {
+		promURL = fmt.Sprintf("http://%s:9090", rf.host)
+	}
+
+	return runReloadCommand(&reloadFlags{
+		promURL: promURL,
+		method:  r.reloadMethod,
+		ssh: sshFlags{
+			host:     rf.host,
+			port:     rf.port,
+			user:     rf.user,
+			password: r.password,
+			keyFile:  r.keyFile,
+			sudo:     rf.user != "root",
+			process:  "prometheus",
+			service:  "prometheus",
+		},
+	})
+}
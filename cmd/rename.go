@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func renameCommand() *cobra.Command {
+	var (
+		resourceArg, oldNameArg, newNameArg string
+		fileFlag                            string
+	)
+
+	writeOpts := &writeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rename job <old name> <new name>",
+		Short: "Rename job in prometheus configuration file",
+		Long: `rename job in prometheus configuration file, all fields and comments of job are kept.
+
+Examples:
+    mpc rename job node_exporter node -f prometheus.yaml
+
+    # print the unified diff without writing
+    mpc rename job node_exporter node -f prometheus.yaml --dry-run
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return fmt.Errorf("you must specify the resource, old name and new name, eg: mpc rename %s node_exporter node\n", Job)
+			}
+			resourceArg, oldNameArg, newNameArg = args[0], args[1], args[2]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch resourceArg {
+			case Job:
+				err := runJobRenameCommand(&jobRenameOptions{
+					file:    fileFlag,
+					oldName: oldNameArg,
+					newName: newNameArg,
+					write:   writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			default:
+				return fmt.Errorf("unknown resource name '%s', only supports %s\n", resourceArg, Job)
+			}
+
+			return writeOpts.result()
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	writeOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+type jobRenameOptions struct {
+	file    string
+	oldName string
+	newName string
+	write   *writeOptions
+}
+
+func runJobRenameCommand(options *jobRenameOptions) error {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.RenameJob(options.oldName, options.newName)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
@@ -9,21 +9,41 @@ import (
 
 func replaceCommand() *cobra.Command {
 	var (
-		resourceArg           string
-		fileFlag, jobNameFlag string
-		valuesFlag            []string
-		keyValuesFlag         = mapFlag{}
+		resourceArg                      string
+		fileFlag, jobNameFlag, groupFlag string
+		valuesFlag                       []string
+		relabelOpts                      = &relabelFlags{}
+		keyValuesFlag                    = mapFlag{}
+		alertmanagerOpts                 = &alertmanagerFlags{}
 	)
 
+	writeOpts := &writeOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "replace <resource>",
-		Short: "Replace job,targets,labels to prometheus configuration file",
-		Long: `replace job,targets,labels to prometheus configuration file.
+		Short: "Replace targets,labels,settings,relabel,alertmanagers to prometheus configuration file",
+		Long: `replace targets,labels,settings,relabel,alertmanagers to prometheus configuration file.
 
 Examples:
     mpc replace targets -f prometheus.yaml -n node_exporter -v 127.0.0.1:9100
 
     mpc replace labels -f prometheus.yaml -n node_exporter -p foo=bar
+
+    # replace targets of the static_configs group whose labels contain dc=sh
+    mpc replace targets -f prometheus.yaml -n node_exporter -g dc=sh -v 127.0.0.1:9100
+
+    # replace scrape settings of job, supports scrape_interval, scrape_timeout, metrics_path,
+    # scheme, honor_labels and params.<name>, multiple values of params are separated by comma
+    mpc replace settings -f prometheus.yaml -n mysql -p scrape_interval=30s -p metrics_path=/probe -p params.module=http_2xx
+
+    # replace the metric_relabel_configs rule at index 1
+    mpc replace relabel -f prometheus.yaml -n node_exporter --metric -i 1 --source-labels __name__ --regex 'node_.*' --action keep
+
+    # replace targets and scheme of the alertmanager group at index 0
+    mpc replace alertmanagers -f prometheus.yaml -g 0 -v 127.0.0.1:9093 --scheme https
+
+    # write the result to another file, the original file is not changed
+    mpc replace targets -f prometheus.yaml -n node_exporter -v 127.0.0.1:9100 -o prometheus-new.yaml
 `,
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -48,7 +68,9 @@ Examples:
 				err := runTargetsReplaceCommand(&targetsReplaceOptions{
 					file:   fileFlag,
 					name:   jobNameFlag,
+					group:  groupFlag,
 					values: valuesFlag,
+					write:  writeOpts,
 				})
 				if err != nil {
 					return err
@@ -65,7 +87,65 @@ Examples:
 				err := runLabelsReplaceCommand(&labelsReplaceOptions{
 					file:      fileFlag,
 					name:      jobNameFlag,
+					group:     groupFlag,
 					keyValues: keyValuesFlag,
+					write:     writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Settings:
+				if err := checkJobName(jobNameFlag, "replace"); err != nil {
+					return err
+				}
+				if err := checkMapValues(keyValuesFlag, "replace"); err != nil {
+					return err
+				}
+				err := runSettingsReplaceCommand(&settingsReplaceOptions{
+					file:      fileFlag,
+					name:      jobNameFlag,
+					keyValues: keyValuesFlag,
+					write:     writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Relabel:
+				if err := checkJobName(jobNameFlag, "replace"); err != nil {
+					return err
+				}
+				if err := checkRelabelIndex(relabelOpts.index, "replace"); err != nil {
+					return err
+				}
+				rule, err := relabelOpts.rule(cmd)
+				if err != nil {
+					return err
+				}
+				err = runRelabelReplaceCommand(&relabelEditOptions{
+					file:  fileFlag,
+					name:  jobNameFlag,
+					kind:  relabelOpts.kind(),
+					index: relabelOpts.index,
+					rule:  rule,
+					write: writeOpts,
+				})
+				if err != nil {
+					return err
+				}
+
+			case Alertmanagers:
+				settings := alertmanagerOpts.settings(cmd)
+				if len(valuesFlag) == 0 && len(settings) == 0 {
+					return fmt.Errorf("you must specify targets(-v) or settings(--scheme, --path-prefix) of alertmanager group to replace")
+				}
+				err := runAlertmanagersReplaceCommand(&alertmanagersEditOptions{
+					file:     fileFlag,
+					group:    groupFlag,
+					values:   valuesFlag,
+					settings: settings,
+					write:    writeOpts,
 				})
 				if err != nil {
 					return err
@@ -75,16 +155,21 @@ Examples:
 				return fmt.Errorf("unknown resource name '%s'. Use \"mpc resources\" for a complete list of supported resources.\n", resourceArg)
 			}
 
-			return nil
+			return writeOpts.result()
 		},
 	}
 
 	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
 	cmd.MarkFlagRequired("file")
-	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required, eg: node_exporter")
-	cmd.MarkFlagRequired("name")
-	cmd.Flags().StringSliceVarP(&valuesFlag, "targets-value", "v", nil, "if the resource is 'targets', required, data format is string, eg: 127.0.0.1:9100")
-	cmd.Flags().VarP(&keyValuesFlag, "labels-value", "p", "key-value pairs, if the resource is 'labels', required, eg: foo=bar")
+	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required except the resource is 'alertmanagers', eg: node_exporter")
+	cmd.Flags().StringSliceVarP(&valuesFlag, "targets-value", "v", nil, "if the resource is 'targets' or 'alertmanagers', data format is string, eg: 127.0.0.1:9100")
+	cmd.Flags().VarP(&keyValuesFlag, "labels-value", "p", "key-value pairs, if the resource is 'labels' or 'settings', required, eg: foo=bar or scrape_interval=30s")
+	cmd.Flags().StringVarP(&groupFlag, "group", "g", "", "static_configs group, index or label selector, or index of alertmanager group if the resource is 'alertmanagers', eg: 1 or dc=sh")
+	relabelOpts.addKindFlags(cmd, "index of the relabel rule to replace, required if the resource is 'relabel'")
+	relabelOpts.addRuleFlags(cmd)
+	alertmanagerOpts.addSettingFlags(cmd)
+	writeOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
 
 	return cmd
 }
@@ -94,41 +179,94 @@ Examples:
 type targetsReplaceOptions struct {
 	file   string
 	name   string
+	group  string
 	values []string
+	write  *writeOptions
 }
 
 func runTargetsReplaceCommand(options *targetsReplaceOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	err = cy.ReplaceJobTargets(options.name, options.values)
+	sdFile, err := getJobSDFile(cy, options.file, options.name)
+	if err != nil {
+		return err
+	}
+	if sdFile != "" {
+		fsd, err := readFileSD(sdFile)
+		if err != nil {
+			return err
+		}
+		sdData := fsd.Data
+		err = fsd.ReplaceGroupTargets(sel, options.values)
+		if err != nil {
+			return err
+		}
+		return options.write.write(sdFile, sdData, fsd.Data, fsd)
+	}
+
+	err = cy.ReplaceJobGroupTargets(options.name, sel, options.values)
 	if err != nil {
 		return err
 	}
 
-	return cy.Persistent(options.file)
+	return options.write.write(options.file, data, cy.Data, cy)
 }
 
 type labelsReplaceOptions struct {
 	file      string
 	name      string
+	group     string
 	keyValues mapFlag
+	write     *writeOptions
 }
 
 func runLabelsReplaceCommand(options *labelsReplaceOptions) error {
+	sel, err := promConf.ParseGroupSelector(options.group)
+	if err != nil {
+		return err
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	err = cy.ReplaceJobGroupLabels(options.name, sel, options.keyValues)
+	if err != nil {
+		return err
+	}
+
+	return options.write.write(options.file, data, cy.Data, cy)
+}
+
+type settingsReplaceOptions struct {
+	file      string
+	name      string
+	keyValues mapFlag
+	write     *writeOptions
+}
+
+func runSettingsReplaceCommand(options *settingsReplaceOptions) error {
 	data, err := readPrometheusConfigFile(options.file)
 	if err != nil {
 		return err
 	}
 
 	cy := promConf.NewConfigYaml(data)
-	err = cy.ReplaceJobLabels(options.name, options.keyValues)
+	err = cy.ReplaceJobSettings(options.name, options.keyValues)
 	if err != nil {
 		return err
 	}
 
-	return cy.Persistent(options.file)
+	return options.write.write(options.file, data, cy.Data, cy)
 }
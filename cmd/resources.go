@@ -30,19 +30,37 @@ Examples:
 // --------------------------------------------------------------------------------------
 
 const (
+	// Jobs 所有job的概要资源
+	Jobs = "jobs"
 	// Job job资源
 	Job = "job"
 	// Targets job下targets资源
 	Targets = "targets"
 	// Labels job下labels资源
 	Labels = "labels"
+	// Groups job下static_configs分组资源
+	Groups = "groups"
+	// Settings job下抓取设置资源，例如scrape_interval、metrics_path
+	Settings = "settings"
+	// Relabel job下relabel_configs和metric_relabel_configs规则资源
+	Relabel = "relabel"
+	// Alertmanagers alerting下alertmanagers分组资源
+	Alertmanagers = "alertmanagers"
+	// Probe blackbox exporter探测job资源
+	Probe = "probe"
 )
 
 // 支持的资源名称列表
 var resourceNames = []string{
+	Jobs,
 	Job,
 	Targets,
 	Labels,
+	Groups,
+	Settings,
+	Relabel,
+	Alertmanagers,
+	Probe,
 }
 
 // ListResourceNames 资源名称列表
@@ -2,11 +2,19 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
 )
 
 // Version 命令版本号
 const Version = "0.0.1"
 
+// 备份保留策略
+var (
+	backupKeepFlag   int
+	backupMaxAgeFlag string
+	backupPolicy     promConf.BackupPolicy // 由flag解析，写入本地和远程文件时使用
+)
+
 // NewRootCMD 命令入口
 func NewRootCMD() *cobra.Command {
 	cmd := &cobra.Command{
@@ -15,8 +23,34 @@ func NewRootCMD() *cobra.Command {
 		SilenceErrors: true,
 		SilenceUsage:  true,
 		Version:       Version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			backupPolicy = promConf.BackupPolicy{Keep: backupKeepFlag}
+			if backupMaxAgeFlag != "" {
+				var err error
+				backupPolicy.MaxAge, err = promConf.ParseDuration(backupMaxAgeFlag)
+				if err != nil {
+					return err
+				}
+			}
+
+			// 命令执行失败时不会执行PersistentPostRunE，在命令执行结束时关闭远程连接
+			if runE := cmd.RunE; runE != nil {
+				cmd.RunE = func(cmd *cobra.Command, args []string) error {
+					defer remoteOpts.close()
+					return runE(cmd, args)
+				}
+			}
+			return remoteOpts.apply(cmd)
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return remoteOpts.reload()
+		},
 	}
 
+	cmd.PersistentFlags().IntVar(&backupKeepFlag, "backup-keep", 0, "maximum number of backups to keep for each file, 0 means unlimited")
+	cmd.PersistentFlags().StringVar(&backupMaxAgeFlag, "backup-max-age", "", "maximum age of backups to keep, eg: 30d, empty means unlimited")
+	remoteOpts.addFlags(cmd)
+
 	cmd.AddCommand(
 		getCommand(),
 		addCommand(),
@@ -24,6 +58,19 @@ func NewRootCMD() *cobra.Command {
 		replaceCommand(),
 		resourcesCommand(),
 		reloadCommand(),
+		convertCommand(),
+		checkCommand(),
+		historyCommand(),
+		rollbackCommand(),
+		ruleCommand(),
+		renameCommand(),
+		cloneCommand(),
+		moveCommand(),
+		applyCommand(),
+		verifyCommand(),
+		driftCommand(),
+		templatesCommand(),
+		findCommand(),
 		execCommand(),
 		execsCommand(),
 	)
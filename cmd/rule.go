@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+	"github.com/zhufuyi/mpc/rules"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RuleGet 获取规则
+	RuleGet = "get"
+	// RuleAdd 添加规则
+	RuleAdd = "add"
+	// RuleDelete 删除规则或分组
+	RuleDelete = "delete"
+	// RuleReplace 替换规则
+	RuleReplace = "replace"
+)
+
+func ruleCommand() *cobra.Command {
+	var (
+		actionArg                                   string
+		fileFlag, ruleFileFlag, groupFlag, nameFlag string
+		alertFlag, recordFlag, exprFlag, forFlag    string
+		intervalFlag                                string
+		ruleLabelsFlag                              = mapFlag{}
+		annotationsFlag                             = mapFlag{}
+	)
+
+	writeOpts := &writeOptions{}
+	outputOpts := &outputOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rule <get|add|delete|replace>",
+		Short: "Get, add, delete, replace alerting and recording rules of rule file",
+		Long: `get, add, delete, replace alerting and recording rules of rule file, the rules are checked
+offline(including PromQL syntax) before writing, the rule file is backed up in the same way as
+prometheus configuration file. after adding rules, the rule file is added to rule_files of
+prometheus configuration file if it is not matched by any path or glob, after the last group of
+rule file is deleted, the rule file is removed from rule_files.
+
+Examples:
+    # get all groups or rules of group
+    mpc rule get -f prometheus.yaml -r rules/node.yml
+    mpc rule get -f prometheus.yaml -r rules/node.yml -g node -n NodeDown
+
+    # list rules as a table, wide shows for and expr too
+    mpc rule get -f prometheus.yaml -r rules/node.yml -o wide
+
+    # add alerting rule, the group is created if it does not exist
+    mpc rule add -f prometheus.yaml -r rules/node.yml -g node --alert NodeDown -e 'up{job="node_exporter"} == 0' \
+        --for 5m --labels severity=critical --annotations summary='instance {{ $labels.instance }} is down'
+
+    # add recording rule and set the evaluation interval of group
+    mpc rule add -f prometheus.yaml -r rules/node.yml -g node --interval 30s \
+        --record instance:node_cpu:rate5m -e 'sum by (instance) (rate(node_cpu_seconds_total{mode!="idle"}[5m]))'
+
+    # write the changed rule file to another file, the rule file and prometheus.yaml are not changed,
+    # an error is returned if rule_files of prometheus.yaml would also be changed
+    mpc rule add -f prometheus.yaml -r rules/node.yml -g node --alert NodeDown -e 'up == 0' --output-file new.yml
+
+    # replace rule, the fields not set (name, expr, for, labels, annotations) keep the values of the old rule
+    mpc rule replace -f prometheus.yaml -r rules/node.yml -g node -n NodeDown -e 'up == 0' --for 1m
+
+    # delete rule, or delete the whole group if the rule name is not set
+    mpc rule delete -f prometheus.yaml -r rules/node.yml -g node -n NodeDown
+    mpc rule delete -f prometheus.yaml -r rules/node.yml -g node
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("you must specify the action, eg: %s, %s, %s, %s\n", RuleGet, RuleAdd, RuleDelete, RuleReplace)
+			}
+			actionArg = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule := &rules.Rule{
+				Alert:       alertFlag,
+				Record:      recordFlag,
+				Expr:        exprFlag,
+				For:         forFlag,
+				Labels:      ruleLabelsFlag,
+				Annotations: annotationsFlag,
+			}
+			options := &ruleEditOptions{
+				file:     fileFlag,
+				ruleFile: ruleFileFlag,
+				group:    groupFlag,
+				name:     nameFlag,
+				interval: intervalFlag,
+				rule:     rule,
+				write:    writeOpts,
+			}
+
+			switch actionArg {
+			case RuleGet:
+				if err := outputOpts.parse(); err != nil {
+					return err
+				}
+				v, table, err := runRuleGetCommand(options)
+				if err != nil {
+					return err
+				}
+				if outputOpts.isText() {
+					out, err := yaml.Marshal(v)
+					if err != nil {
+						return err
+					}
+					fmt.Print(string(out))
+					return nil
+				}
+				return outputOpts.print(v, table)
+
+			case RuleAdd:
+				if err := checkRuleGroup(groupFlag, RuleAdd); err != nil {
+					return err
+				}
+				if alertFlag == "" && recordFlag == "" {
+					return fmt.Errorf("--alert or --record must be specified")
+				}
+				if err := runRuleAddCommand(options); err != nil {
+					return err
+				}
+
+			case RuleDelete:
+				if err := checkRuleGroup(groupFlag, RuleDelete); err != nil {
+					return err
+				}
+				if err := runRuleDelCommand(options); err != nil {
+					return err
+				}
+
+			case RuleReplace:
+				if err := checkRuleGroup(groupFlag, RuleReplace); err != nil {
+					return err
+				}
+				if nameFlag == "" {
+					return fmt.Errorf("rule name is empty, Use \"mpc rule replace -n <name>\" to specify the rule to replace")
+				}
+				if err := runRuleReplaceCommand(options); err != nil {
+					return err
+				}
+
+			default:
+				return fmt.Errorf("unknown action '%s', eg: %s, %s, %s, %s\n", actionArg, RuleGet, RuleAdd, RuleDelete, RuleReplace)
+			}
+
+			return writeOpts.result()
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVarP(&ruleFileFlag, "rule-file", "r", "", "rule file, required, eg: rules/node.yml")
+	cmd.MarkFlagRequired("rule-file")
+	cmd.Flags().StringVarP(&groupFlag, "group", "g", "", "rule group name, eg: node")
+	cmd.Flags().StringVarP(&nameFlag, "name", "n", "", "rule name, alert or record name, eg: NodeDown")
+	cmd.Flags().StringVar(&alertFlag, "alert", "", "name of alerting rule, eg: NodeDown")
+	cmd.Flags().StringVar(&recordFlag, "record", "", "name of recording rule, eg: instance:node_cpu:rate5m")
+	cmd.Flags().StringVarP(&exprFlag, "expr", "e", "", "PromQL expression of rule, eg: up == 0")
+	cmd.Flags().StringVar(&forFlag, "for", "", "alerts are considered firing once they have been returned for this long, eg: 5m")
+	cmd.Flags().Var(&ruleLabelsFlag, "labels", "labels of rule, eg: severity=critical")
+	cmd.Flags().Var(&annotationsFlag, "annotations", "annotations of alerting rule, eg: summary=instance down")
+	cmd.Flags().StringVar(&intervalFlag, "interval", "", "evaluation interval of rule group, eg: 30s")
+	// -o/--output是get的输出格式，写入结果使用--output-file
+	writeOpts.addFlagsWithOutputName(cmd, "output-file", "")
+	outputOpts.addFlags(cmd)
+	cmd.RunE = withFileLock(&fileFlag, writeOpts, cmd.RunE)
+
+	return cmd
+}
+
+func checkRuleGroup(group string, action string) error {
+	if group == "" {
+		return fmt.Errorf("rule group is empty, Use \"mpc rule %s -g <group>\" to specify the rule group", action)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------------------
+
+type ruleEditOptions struct {
+	file     string
+	ruleFile string
+	group    string
+	name     string
+	interval string
+	rule     *rules.Rule
+	write    *writeOptions
+}
+
+// 获取规则，没有指定分组时返回所有分组，没有指定名称时返回分组，否则返回分组中同名的规则，
+// 同时返回表格格式输出的内容
+func runRuleGetCommand(options *ruleEditOptions) (interface{}, *outputTable, error) {
+	data, err := readPrometheusConfigFile(options.ruleFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rf := rules.NewRuleFile(data)
+	if options.group == "" {
+		groups, err := rf.GetGroups()
+		if err != nil {
+			return nil, nil, err
+		}
+		return &rules.RuleGroups{Groups: groups}, rulesTable(groups...), nil
+	}
+
+	if options.name == "" {
+		group, err := rf.GetGroup(options.group)
+		if err != nil {
+			return nil, nil, err
+		}
+		return group, rulesTable(group), nil
+	}
+
+	rs, err := rf.GetRules(options.group, options.name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rs, rulesTable(&rules.RuleGroup{Name: options.group, Rules: rs}), nil
+}
+
+func rulesTable(groups ...*rules.RuleGroup) *outputTable {
+	table := newOutputTable("GROUP", "NAME", "TYPE").addWideColumns("FOR", "EXPR")
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			ruleType := "alert"
+			if rule.Record != "" {
+				ruleType = "record"
+			}
+			table.addRow(group.Name, rule.Name(), ruleType, rule.For, rule.Expr)
+		}
+	}
+	return table
+}
+
+func runRuleAddCommand(options *ruleEditOptions) error {
+	return editRuleFile(options, func(rf *rules.RuleFile) error {
+		err := rf.AddRule(options.group, options.rule)
+		if err != nil {
+			return err
+		}
+		if options.interval != "" {
+			return rf.SetGroupInterval(options.group, options.interval)
+		}
+		return nil
+	})
+}
+
+func runRuleDelCommand(options *ruleEditOptions) error {
+	return editRuleFile(options, func(rf *rules.RuleFile) error {
+		if options.name == "" {
+			return rf.DelGroup(options.group)
+		}
+		return rf.DelRule(options.group, options.name)
+	})
+}
+
+func runRuleReplaceCommand(options *ruleEditOptions) error {
+	return editRuleFile(options, func(rf *rules.RuleFile) error {
+		err := rf.ReplaceRule(options.group, options.name, options.rule)
+		if err != nil {
+			return err
+		}
+		if options.interval != "" {
+			return rf.SetGroupInterval(options.group, options.interval)
+		}
+		return nil
+	})
+}
+
+// 修改规则文件，再同步prometheus配置文件中的rule_files，规则文件有分组时确保被rule_files匹配，
+// 没有分组时从rule_files删除，两个文件都校验通过后才写入
+func editRuleFile(options *ruleEditOptions, fn func(rf *rules.RuleFile) error) error {
+	if isRemoteFile(options.file) || isRemoteFile(options.ruleFile) {
+		return fmt.Errorf("rule files of a remote file are not supported")
+	}
+
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	ruleData, err := readPrometheusConfigFile(options.ruleFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	rf := rules.NewRuleFile(ruleData)
+	err = fn(rf)
+	if err != nil {
+		return err
+	}
+
+	cy := promConf.NewConfigYaml(data)
+	groups, err := rf.GetGroups()
+	if err != nil {
+		return err
+	}
+	refFile := configRefPath(options.file, options.ruleFile)
+	if len(groups) > 0 {
+		ruleFiles, err := cy.GetRuleFiles()
+		if err != nil {
+			return err
+		}
+		if !promConf.MatchRuleFiles(ruleFiles, filepath.Dir(options.file), options.ruleFile) {
+			err = cy.AddRuleFiles([]string{refFile})
+		}
+	} else {
+		err = cy.DelRuleFiles([]string{refFile})
+	}
+	if err != nil {
+		return err
+	}
+
+	files := []*editedFile{{file: options.ruleFile, oldData: ruleData, newData: rf.Data, pf: rf}}
+	if string(data) != string(cy.Data) {
+		// output-file只能输出一个文件，rule_files的修改不能丢弃
+		if options.write.output != "" {
+			return fmt.Errorf("rule_files of %s would also be changed, flag 'output-file' cannot write both files, use --dry-run to preview the changes", options.file)
+		}
+		files = append(files, &editedFile{file: options.file, oldData: data, newData: cy.Data, pf: cy})
+	}
+	return options.write.writeFiles(files...)
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/templates"
+)
+
+// templateFlags 使用模板添加job的参数
+type templateFlags struct {
+	name   string   // 模板名称
+	values []string // 参数值，格式为name=value
+	dir    string   // 自定义模板目录
+}
+
+func (t *templateFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&t.name, "template", "", "render the job from a template instead of job-value, if the resource is 'job', eg: mysqld")
+	cmd.Flags().StringArrayVar(&t.values, "set", nil, "param of template, can be repeated, eg: --set targets=a:9104,b:9104 --set labels=env=prod")
+	addTemplateDirFlag(cmd, &t.dir)
+}
+
+func addTemplateDirFlag(cmd *cobra.Command, dir *string) {
+	cmd.Flags().StringVar(dir, "template-dir", templates.DefaultDir(), "directory of custom templates, a custom template overrides the builtin one with the same name")
+}
+
+// 渲染模板，没有设置name参数时使用job名称
+func (t *templateFlags) render(jobName string) ([]byte, error) {
+	catalog, err := templates.Load(t.dir)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := catalog.Get(t.name)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, kv := range t.values {
+		split := strings.SplitN(kv, "=", 2)
+		if len(split) != 2 || strings.TrimSpace(split[0]) == "" {
+			return nil, fmt.Errorf("param '%s' format is invalid, eg: --set targets=a:9104,b:9104", kv)
+		}
+		values[strings.TrimSpace(split[0])] = split[1]
+	}
+	if _, ok := values["name"]; !ok && jobName != "" {
+		for _, name := range tmpl.ParamNames() {
+			if name == "name" {
+				values["name"] = jobName
+			}
+		}
+	}
+
+	return tmpl.Render(values)
+}
+
+// ---------------------------------------------------------------------------------------
+
+func templatesCommand() *cobra.Command {
+	var (
+		dirFlag    string
+		outputOpts = &outputOptions{}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "templates [name]",
+		Short: "List job templates or show params of a template",
+		Long: `list job templates or show params of a template, the builtin templates are node, mysqld,
+redis, blackbox-http and blackbox-icmp. custom templates are *.yaml files in --template-dir,
+see the output of "mpc templates <name> -o yaml" for the format.
+
+Examples:
+    mpc templates
+
+    # show params of template
+    mpc templates mysqld
+
+    # print the template definition, save it into ~/.mpc/templates to customize
+    mpc templates mysqld -o yaml
+
+    # add job rendered by template
+    mpc add job -f prometheus.yaml --template mysqld --set targets=a:9104,b:9104
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args:          cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := outputOpts.parse(); err != nil {
+				return err
+			}
+			catalog, err := templates.Load(dirFlag)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				list := []*templates.Template{}
+				for _, name := range catalog.Names() {
+					list = append(list, catalog[name])
+				}
+				if outputOpts.isText() {
+					return templatesTable(list).print(false)
+				}
+				return outputOpts.print(list, templatesTable(list))
+			}
+
+			tmpl, err := catalog.Get(args[0])
+			if err != nil {
+				return err
+			}
+			if outputOpts.isText() {
+				return templateParamsTable(tmpl).print(true)
+			}
+			return outputOpts.print(tmpl, templateParamsTable(tmpl))
+		},
+	}
+
+	addTemplateDirFlag(cmd, &dirFlag)
+	outputOpts.addFlags(cmd)
+
+	return cmd
+}
+
+func templatesTable(list []*templates.Template) *outputTable {
+	table := newOutputTable("NAME", "DESCRIPTION").addWideColumns("PARAMS", "FILE")
+	for _, t := range list {
+		file := t.File
+		if file == "" {
+			file = "<builtin>"
+		}
+		table.addRow(t.Name, t.Description, strings.Join(t.ParamNames(), ","), file)
+	}
+	return table
+}
+
+func templateParamsTable(t *templates.Template) *outputTable {
+	table := newOutputTable("PARAM", "TYPE", "REQUIRED", "DEFAULT").addWideColumns("DESCRIPTION")
+	for _, p := range t.Params {
+		table.addRow(p.Name, p.Type, p.Required, p.Default, p.Description)
+	}
+	return table
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func verifyCommand() *cobra.Command {
+	var (
+		fileFlag, jobNameFlag, promURLFlag string
+		waitFlag, intervalFlag             time.Duration
+		clientOpts                         = &clientFlags{}
+		outputOpts                         = &outputOptions{}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that the targets of job are up in prometheus",
+		Long: `verify that the targets of job are up in prometheus, the targets in static_configs or
+the file_sd_configs file of job are matched to the active targets from /api/v1/targets, the health, last error and scrape duration
+of each target are printed. exit code is 9 if a target is missing or not up.
+
+Examples:
+    mpc verify -f prometheus.yaml -n node_exporter -p http://127.0.0.1:9090
+
+    # after add targets and reload, wait up to 2 minutes for the new targets to be up
+    mpc add targets -f prometheus.yaml -n node_exporter -v 10.0.0.1:9100
+    mpc reload -p http://127.0.0.1:9090/-/reload
+    mpc verify -f prometheus.yaml -n node_exporter --wait 2m
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := outputOpts.parse(); err != nil {
+				return err
+			}
+			if intervalFlag <= 0 {
+				return fmt.Errorf("interval must be greater than 0")
+			}
+
+			healths, err := runVerifyCommand(&verifyOptions{
+				file:     fileFlag,
+				name:     jobNameFlag,
+				promURL:  promURLFlag,
+				client:   clientOpts.options(),
+				wait:     waitFlag,
+				interval: intervalFlag,
+			})
+			if healths != nil {
+				table := targetHealthTable(healths)
+				var printErr error
+				if outputOpts.isText() {
+					printErr = table.print(false)
+				} else {
+					printErr = outputOpts.print(healths, table)
+				}
+				if err == nil {
+					err = printErr
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "prometheus configuration file, required, eg: prometheus.yaml")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVarP(&jobNameFlag, "name", "n", "", "job name, required")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&promURLFlag, "promURL", "p", "http://127.0.0.1:9090", "prometheus url")
+	cmd.Flags().DurationVar(&waitFlag, "wait", 0, "wait until all targets are up or the time passes, eg: 2m, 0 means check once")
+	cmd.Flags().DurationVar(&intervalFlag, "interval", 5*time.Second, "interval of checking the targets, if wait is set")
+	clientOpts.addFlags(cmd)
+	outputOpts.addFlags(cmd)
+
+	return cmd
+}
+
+// ---------------------------------------------------------------------------------------
+
+type verifyOptions struct {
+	file     string
+	name     string
+	promURL  string
+	client   *promConf.ClientOptions
+	wait     time.Duration
+	interval time.Duration
+}
+
+// 检查job的target在prometheus中的健康状态，wait大于0时轮询到所有target正常或超时，
+// 有target不正常时同时返回健康状态和ErrTargetsUnhealthy类型的错误
+func runVerifyCommand(options *verifyOptions) ([]*promConf.TargetHealth, error) {
+	data, err := readPrometheusConfigFile(options.file)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := getJobTargets(promConf.NewConfigYaml(data), options.file, options.name)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: job '%s' has no targets in static_configs or file_sd_configs file", promConf.ErrTargetsNotFound, options.name)
+	}
+
+	deadline := time.Now().Add(options.wait)
+	for {
+		var healths []*promConf.TargetHealth
+		active, err := promConf.GetActiveTargets(options.promURL, options.client)
+		if err == nil {
+			healths = promConf.MatchJobTargets(options.name, targets, active)
+			err = checkTargetHealths(options.name, healths)
+			if err == nil {
+				return healths, nil
+			}
+		}
+
+		// 等待期间prometheus可能正在重启，获取失败时也继续重试
+		if !time.Now().Add(options.interval).Before(deadline) {
+			return healths, err
+		}
+		fmt.Fprintf(os.Stderr, "%v, retry in %s\n", err, options.interval)
+		time.Sleep(options.interval)
+	}
+}
+
+// 获取job的所有target，job唯一引用的file_sd_configs文件时从文件中获取，与targets命令一致
+func getJobTargets(cy *promConf.ConfigYaml, file string, jobName string) ([]string, error) {
+	sdFile, err := getJobSDFile(cy, file, jobName)
+	if err != nil {
+		return nil, err
+	}
+	if sdFile != "" {
+		fsd, err := readFileSD(sdFile)
+		if err != nil {
+			return nil, err
+		}
+		return fsd.GetTargets()
+	}
+
+	return cy.GetJobTargets(jobName)
+}
+
+// 所有target的状态都是up时返回nil
+func checkTargetHealths(jobName string, healths []*promConf.TargetHealth) error {
+	count := 0
+	for _, h := range healths {
+		if !h.IsUp() {
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d of %d targets of job '%s' are not up", promConf.ErrTargetsUnhealthy, count, len(healths), jobName)
+}
+
+func targetHealthTable(healths []*promConf.TargetHealth) *outputTable {
+	table := newOutputTable("TARGET", "HEALTH", "LAST SCRAPE", "DURATION", "ERROR").addWideColumns("SCRAPE URL")
+	for _, h := range healths {
+		lastScrape := "-"
+		if !h.LastScrape.IsZero() {
+			lastScrape = time.Since(h.LastScrape).Round(time.Second).String() + " ago"
+		}
+		duration := "-"
+		if h.ScrapeDuration > 0 {
+			duration = h.ScrapeDuration.Round(time.Millisecond).String()
+		}
+		table.addRow(h.Target, h.Health, lastScrape, duration, h.LastError, h.ScrapeURL)
+	}
+	return table
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+// 退出码
+const (
+	ExitCodeOK      = 0 // 执行成功，dry-run时表示没有修改
+	ExitCodeFailed  = 1 // 执行失败
+	ExitCodeChanged = 2 // dry-run或output时表示有修改
+
+	ExitCodeInvalidConfig    = 3 // 配置文件解析或校验失败
+	ExitCodeJobNotFound      = 4 // job不存在
+	ExitCodeTargetsNotFound  = 5 // target或分组不存在
+	ExitCodeLabelsNotFound   = 6 // 标签所在的分组不存在
+	ExitCodeDuplicateJob     = 7 // job名称已存在
+	ExitCodeReloadFailed     = 8 // prometheus reload失败
+	ExitCodeTargetsUnhealthy = 9 // target在prometheus中不存在或状态不是up
+)
+
+// ErrorExitCode 根据错误类型获取退出码，脚本可以根据退出码区分失败原因
+func ErrorExitCode(err error) int {
+	exitErr := &ExitCodeError{}
+	switch {
+	case err == nil:
+		return ExitCodeOK
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.Is(err, promConf.ErrInvalidConfig):
+		return ExitCodeInvalidConfig
+	case errors.Is(err, promConf.ErrJobNotFound):
+		return ExitCodeJobNotFound
+	case errors.Is(err, promConf.ErrTargetsNotFound):
+		return ExitCodeTargetsNotFound
+	case errors.Is(err, promConf.ErrLabelsNotFound):
+		return ExitCodeLabelsNotFound
+	case errors.Is(err, promConf.ErrDuplicateJob):
+		return ExitCodeDuplicateJob
+	case errors.Is(err, promConf.ErrReloadFailed):
+		return ExitCodeReloadFailed
+	case errors.Is(err, promConf.ErrTargetsUnhealthy):
+		return ExitCodeTargetsUnhealthy
+	}
+	return ExitCodeFailed
+}
+
+// ExitCodeError 指定进程退出码的错误，Err为空时只退出不打印
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+// 文件锁的等待时间和文件被修改时的重试次数
+const (
+	lockTimeout   = 30 * time.Second
+	maxEditRetry  = 3
+	retryInterval = 100 * time.Millisecond
+)
+
+// errFileChanged 文件在读取后被其他程序修改
+var errFileChanged = errors.New("file has been changed by another program since it was read")
+
+// withFileLock 持有文件锁执行命令，保证读取、修改、写入过程不被其他mpc进程打断，
+// 文件在读取后被其他程序修改时，重新执行命令，dry-run和output不写入原文件，不需要加锁
+func withFileLock(file *string, write *writeOptions, runE func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if write.dryRun || write.output != "" {
+			return runE(cmd, args)
+		}
+
+		// 远程文件不能加锁，写入前检查文件是否被修改
+		if !isRemoteFile(*file) {
+			lock, err := promConf.LockFile(*file, lockTimeout)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+		}
+
+		for i := 0; ; i++ {
+			err := runE(cmd, args)
+			if !errors.Is(err, errFileChanged) || i >= maxEditRetry {
+				return err
+			}
+			time.Sleep(retryInterval)
+		}
+	}
+}
+
+// 可以校验和持久化的文件，例如ConfigYaml、FileSD
+type persistentFile interface {
+	Validate() error
+	Persistent(file string, policy promConf.BackupPolicy) error
+}
+
+// writeOptions 修改后的写入方式，所有修改命令共用
+type writeOptions struct {
+	dryRun bool   // 只打印diff，不写入
+	output string // 写入到其他文件，-表示标准输出，原文件不变
+
+	changed bool // 是否有修改
+}
+
+func (w *writeOptions) addFlags(cmd *cobra.Command) {
+	w.addFlagsWithOutputName(cmd, "output", "o")
+}
+
+// addFlagsWithOutputName 命令的-o/--output已用于其他用途时，写入结果的flag使用其他名称
+func (w *writeOptions) addFlagsWithOutputName(cmd *cobra.Command, name string, shorthand string) {
+	cmd.Flags().BoolVar(&w.dryRun, "dry-run", false, "print unified diff of the changes without writing, exit code is 2 if anything would change")
+	cmd.Flags().StringVarP(&w.output, name, shorthand, "", "write the result to another file or stdout(-) instead of the original file, exit code is 2 if anything changed")
+}
+
+// write 写入修改后的内容，oldData为修改前的内容，newData为修改后的内容，
+// 写入原文件前检查文件是否在读取后被修改，被修改时返回errFileChanged
+func (w *writeOptions) write(file string, oldData []byte, newData []byte, pf persistentFile) error {
+	if !w.dryRun && w.output == "" {
+		currentData, err := readFileForWrite(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if !bytes.Equal(currentData, oldData) {
+			return fmt.Errorf("%s: %w", file, errFileChanged)
+		}
+	}
+
+	if !bytes.Equal(oldData, newData) {
+		w.changed = true
+	}
+
+	switch {
+	case w.dryRun:
+		err := pf.Validate()
+		if err != nil {
+			return err
+		}
+		oldName := file
+		if oldData == nil {
+			oldName = os.DevNull
+		}
+		fmt.Print(promConf.UnifiedDiff(oldName, file, oldData, newData))
+		return nil
+
+	case w.output != "":
+		err := pf.Validate()
+		if err != nil {
+			return err
+		}
+		if w.output == "-" {
+			_, err = os.Stdout.Write(newData)
+			return err
+		}
+		return ioutil.WriteFile(w.output, newData, 0666)
+	}
+
+	if isRemoteFile(file) {
+		err := pf.Validate()
+		if err != nil {
+			return err
+		}
+		return writeRemoteFile(file, newData)
+	}
+
+	return pf.Persistent(file, backupPolicy)
+}
+
+// editedFile 修改的文件，oldData为修改前的内容，newData为修改后的内容
+type editedFile struct {
+	file    string
+	oldData []byte
+	newData []byte
+	pf      persistentFile
+}
+
+// writeFiles 写入多个相关联的文件，例如规则文件和prometheus配置文件，写入前先检查所有文件是否被修改和
+// 校验所有文件，写入某个文件失败时恢复前面已写入的文件，多个文件要么都修改，要么都不修改
+func (w *writeOptions) writeFiles(files ...*editedFile) error {
+	if !w.dryRun && w.output == "" {
+		for _, f := range files {
+			currentData, err := readFileForWrite(f.file)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if !bytes.Equal(currentData, f.oldData) {
+				return fmt.Errorf("%s: %w", f.file, errFileChanged)
+			}
+			err = f.pf.Validate()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, f := range files {
+		err := w.write(f.file, f.oldData, f.newData, f.pf)
+		if err != nil {
+			if w.dryRun || w.output != "" {
+				return err
+			}
+			return restoreFiles(files[:i], err)
+		}
+	}
+
+	return nil
+}
+
+// 恢复已写入的文件为修改前的内容，修改前不存在的文件被删除，恢复失败时返回的错误不会触发重新执行命令
+func restoreFiles(files []*editedFile, err error) error {
+	for _, f := range files {
+		if bytes.Equal(f.oldData, f.newData) {
+			continue
+		}
+
+		var restoreErr error
+		switch {
+		case isRemoteFile(f.file):
+			restoreErr = writeRemoteFile(f.file, f.oldData)
+		case f.oldData == nil:
+			restoreErr = os.Remove(f.file)
+		default:
+			restoreErr = promConf.WriteFileWithBackup(f.file, f.oldData, backupPolicy)
+		}
+		if restoreErr != nil {
+			return fmt.Errorf("%v, and restore %s error, %v", err, f.file, restoreErr)
+		}
+	}
+
+	return err
+}
+
+// 读取写入前的文件内容，支持远程文件
+func readFileForWrite(file string) ([]byte, error) {
+	if isRemoteFile(file) {
+		return readRemoteFile(file)
+	}
+	return ioutil.ReadFile(file)
+}
+
+// result 命令执行结果，dry-run或output时根据是否有修改返回退出码
+func (w *writeOptions) result() error {
+	if (w.dryRun || w.output != "") && w.changed {
+		return &ExitCodeError{Code: ExitCodeChanged}
+	}
+	return nil
+}
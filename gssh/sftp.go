@@ -0,0 +1,143 @@
+package gssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// ReadFile 读取远程文件的内容
+func (s *SSHClient) ReadFile(remoteFile string) ([]byte, error) {
+	if s.sftpCli == nil {
+		if err := s.CreateSftp(); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := s.sftpCli.Open(remoteFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// Stat 获取远程文件信息，文件不存在时可以使用os.IsNotExist判断
+func (s *SSHClient) Stat(remoteFile string) (os.FileInfo, error) {
+	if s.sftpCli == nil {
+		if err := s.CreateSftp(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sftpCli.Stat(remoteFile)
+}
+
+// MkdirAll 在远程服务器创建目录，mkdir -p
+func (s *SSHClient) MkdirAll(remoteDir string) error {
+	if s.sftpCli == nil {
+		if err := s.CreateSftp(); err != nil {
+			return err
+		}
+	}
+
+	return s.sftpCli.MkdirAll(remoteDir)
+}
+
+// ReadDir 获取远程目录下的文件信息
+func (s *SSHClient) ReadDir(remoteDir string) ([]os.FileInfo, error) {
+	if s.sftpCli == nil {
+		if err := s.CreateSftp(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sftpCli.ReadDir(remoteDir)
+}
+
+// Remove 删除远程文件
+func (s *SSHClient) Remove(remoteFile string) error {
+	if s.sftpCli == nil {
+		if err := s.CreateSftp(); err != nil {
+			return err
+		}
+	}
+
+	return s.sftpCli.Remove(remoteFile)
+}
+
+// WriteFileAtomic 写入远程文件，先写入同目录下的临时文件再重命名，其他程序不会读取到写了一半的文件，
+// 文件已存在时保留原文件的权限和所有者，文件是软链接时替换链接指向的文件
+func (s *SSHClient) WriteFileAtomic(remoteFile string, content []byte, perm os.FileMode) error {
+	if s.sftpCli == nil {
+		if err := s.CreateSftp(); err != nil {
+			return err
+		}
+	}
+
+	remoteFile, err := s.realPath(remoteFile)
+	if err != nil {
+		return err
+	}
+	var owner *sftp.FileStat
+	if info, err := s.sftpCli.Stat(remoteFile); err == nil {
+		perm = info.Mode().Perm()
+		owner, _ = info.Sys().(*sftp.FileStat)
+	}
+
+	tmpFile := path.Join(path.Dir(remoteFile), "."+path.Base(remoteFile)+".tmp"+strconv.FormatInt(time.Now().UnixNano(), 10))
+	f, err := s.sftpCli.Create(tmpFile)
+	if err != nil {
+		return fmt.Errorf("create remote file %s error, %v", tmpFile, err)
+	}
+	defer s.sftpCli.Remove(tmpFile) // 重命名成功后临时文件已不存在
+
+	_, err = io.Copy(f, bytes.NewReader(content))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.sftpCli.Chmod(tmpFile, perm)
+	if err != nil {
+		return err
+	}
+	if owner != nil {
+		// 登录用户不是root时没有权限修改所有者，临时文件的所有者为登录用户
+		s.sftpCli.Chown(tmpFile, int(owner.UID), int(owner.GID))
+	}
+
+	// posix-rename覆盖已存在的文件，sftp协议的rename在目标文件存在时失败
+	return s.sftpCli.PosixRename(tmpFile, remoteFile)
+}
+
+// 软链接指向的文件路径，不是软链接时返回原路径
+func (s *SSHClient) realPath(remoteFile string) (string, error) {
+	for i := 0; i < 10; i++ {
+		info, err := s.sftpCli.Lstat(remoteFile)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return remoteFile, nil
+		}
+
+		target, err := s.sftpCli.ReadLink(remoteFile)
+		if err != nil {
+			return "", err
+		}
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(remoteFile), target)
+		}
+		remoteFile = target
+	}
+
+	return "", fmt.Errorf("too many levels of symbolic links: %s", remoteFile)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/zhufuyi/mpc/cmd"
@@ -9,7 +10,14 @@ import (
 func main() {
 	rootCMD := cmd.NewRootCMD()
 	if err := rootCMD.Execute(); err != nil {
-		rootCMD.PrintErrln("Error:", err)
-		os.Exit(1)
+		code := cmd.ErrorExitCode(err)
+		exitErr := &cmd.ExitCodeError{}
+		if errors.As(err, &exitErr) {
+			err = exitErr.Err
+		}
+		if err != nil {
+			rootCMD.PrintErrln("Error:", err)
+		}
+		os.Exit(code)
 	}
 }
@@ -0,0 +1,307 @@
+package promConf
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// alertmanager分组支持修改的设置
+const (
+	// AlertmanagerScheme 请求alertmanager的协议，http或https
+	AlertmanagerScheme = "scheme"
+	// AlertmanagerPathPrefix alertmanager的url路径前缀
+	AlertmanagerPathPrefix = "path_prefix"
+	// AlertmanagerTimeout 推送告警的超时时间
+	AlertmanagerTimeout = "timeout"
+	// AlertmanagerAPIVersion alertmanager的api版本
+	AlertmanagerAPIVersion = "api_version"
+)
+
+// AlertmanagerSettingNames 支持修改的alertmanager设置名称
+var AlertmanagerSettingNames = []string{AlertmanagerScheme, AlertmanagerPathPrefix, AlertmanagerTimeout, AlertmanagerAPIVersion}
+
+// Alertmanager alerting.alertmanagers中的一个分组
+type Alertmanager struct {
+	Index      int      `json:"index"`
+	Scheme     string   `json:"scheme"`
+	PathPrefix string   `json:"path_prefix"`
+	Targets    []string `json:"targets"`
+}
+
+// GetAlertmanagers 获取所有alertmanager分组及其static_configs中的target
+func (c *ConfigYaml) GetAlertmanagers() ([]*Alertmanager, error) {
+	cfg, err := c.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ams := []*Alertmanager{}
+	if cfg.Alerting == nil {
+		return ams, nil
+	}
+	for i, amc := range cfg.Alerting.AlertmanagerConfigs {
+		am := &Alertmanager{Index: i, Targets: []string{}}
+		if amc != nil {
+			am.Scheme, am.PathPrefix = amc.Scheme, amc.PathPrefix
+			targets, err := getGroupTargets(amc.StaticConfigs, nil)
+			if err != nil {
+				return nil, err
+			}
+			am.Targets = targets
+		}
+		ams = append(ams, am)
+	}
+
+	return ams, nil
+}
+
+// AddAlertmanagerTargets 添加target到第index个alertmanager分组，并去重，
+// index小于0时选择唯一的分组，没有alertmanager分组时自动创建
+func (c *ConfigYaml) AddAlertmanagerTargets(index int, addTargets []string) error {
+	return c.updateAlertmanagerGroups(index, true, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return addGroupTargets(groups, nil, addTargets)
+	})
+}
+
+// DelAlertmanagerTargets 从第index个alertmanager分组删除target，index小于0时从所有分组删除，
+// 删除后为空的static_configs分组被删除，alertmanager分组的设置保留，删除整个分组使用DelAlertmanager
+func (c *ConfigYaml) DelAlertmanagerTargets(index int, delTargets []string) error {
+	if index < 0 {
+		return c.edit(func(root *yaml.Node) error {
+			seq := alertmanagersNode(root, false)
+			if seq == nil {
+				return nil
+			}
+			for _, am := range seq.Content {
+				err := updateAlertmanagerGroups(am, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+					return delNonEmptyGroupTargets(groups, delTargets)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return c.updateAlertmanagerGroups(index, false, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return delNonEmptyGroupTargets(groups, delTargets)
+	})
+}
+
+// 从所有分组删除target，删除后变为空的分组也被删除，原来就是空的分组保留
+func delNonEmptyGroupTargets(groups []StaticConfigs, delTargets []string) ([]StaticConfigs, error) {
+	hasTargets := make([]bool, len(groups))
+	for i, group := range groups {
+		hasTargets[i] = len(group.Targets) > 0
+	}
+
+	groups, err := delGroupTargets(groups, nil, delTargets)
+	if err != nil {
+		return nil, err
+	}
+
+	newGroups := []StaticConfigs{}
+	for i, group := range groups {
+		if hasTargets[i] && len(group.Targets) == 0 {
+			continue
+		}
+		newGroups = append(newGroups, group)
+	}
+	return newGroups, nil
+}
+
+// ReplaceAlertmanagerTargets 替换第index个alertmanager分组的target，index小于0时选择唯一的分组
+func (c *ConfigYaml) ReplaceAlertmanagerTargets(index int, newTargets []string) error {
+	return c.updateAlertmanagerGroups(index, true, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return replaceGroupTargets(groups, nil, newTargets)
+	})
+}
+
+// SetAlertmanagerSettings 设置第index个alertmanager分组的scheme、path_prefix等，值为空时删除该设置，
+// index小于0时选择唯一的分组
+func (c *ConfigYaml) SetAlertmanagerSettings(index int, settings map[string]string) error {
+	for _, name := range SortedKeys(settings) {
+		if !isAlertmanagerSettingName(name) {
+			return fmt.Errorf("unknown alertmanager setting '%s', supports %v", name, AlertmanagerSettingNames)
+		}
+	}
+
+	return c.edit(func(root *yaml.Node) error {
+		am, err := selectAlertmanagerNode(root, index, true)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range AlertmanagerSettingNames {
+			value, ok := settings[name]
+			if !ok {
+				continue
+			}
+			if value == "" {
+				DeleteMappingKey(am, name)
+				continue
+			}
+			if MappingKeyIndex(am, name) >= 0 {
+				SetMappingValue(am, name, newScalarNode(value))
+				continue
+			}
+			// 设置添加到static_configs前面
+			InsertMappingValue(am, MappingKeyIndex(am, "static_configs"), name, newScalarNode(value))
+		}
+
+		return nil
+	})
+}
+
+// AddAlertmanager 添加新的alertmanager分组，settings为scheme、path_prefix等设置
+func (c *ConfigYaml) AddAlertmanager(targets []string, settings map[string]string) error {
+	err := c.edit(func(root *yaml.Node) error {
+		seq := alertmanagersNode(root, true)
+		am := newMappingNode()
+		group := newMappingNode()
+		targetsNode := newSequenceNode()
+		updateStringSequence(targetsNode, removeDuplicate(targets))
+		SetMappingValue(group, "targets", targetsNode)
+		staticConfigs := newSequenceNode()
+		staticConfigs.Content = append(staticConfigs.Content, group)
+		SetMappingValue(am, "static_configs", staticConfigs)
+		seq.Content = append(seq.Content, am)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+
+	ams, err := c.GetAlertmanagers()
+	if err != nil {
+		return err
+	}
+	return c.SetAlertmanagerSettings(len(ams)-1, settings)
+}
+
+// DelAlertmanager 删除第index个alertmanager分组
+func (c *ConfigYaml) DelAlertmanager(index int) error {
+	return c.edit(func(root *yaml.Node) error {
+		seq := alertmanagersNode(root, false)
+		if seq == nil || index < 0 || index >= len(seq.Content) {
+			return fmt.Errorf("alertmanager group '%d' not found", index)
+		}
+
+		seq.Content = append(seq.Content[:index], seq.Content[index+1:]...)
+		return nil
+	})
+}
+
+func (c *ConfigYaml) updateAlertmanagerGroups(index int, isCreate bool, fn func([]StaticConfigs) ([]StaticConfigs, error)) error {
+	return c.edit(func(root *yaml.Node) error {
+		am, err := selectAlertmanagerNode(root, index, isCreate)
+		if err != nil {
+			return err
+		}
+		return updateAlertmanagerGroups(am, fn)
+	})
+}
+
+// 修改alertmanager分组的static_configs
+func updateAlertmanagerGroups(am *yaml.Node, fn func([]StaticConfigs) ([]StaticConfigs, error)) error {
+	groups, err := decodeGroups(MappingValue(am, "static_configs"))
+	if err != nil {
+		return err
+	}
+
+	groups, err = fn(groups)
+	if err != nil {
+		return err
+	}
+
+	seq := editableValue(am, "static_configs")
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		seq = newSequenceNode()
+		SetMappingValue(am, "static_configs", seq)
+	}
+	seq.Content = encodeGroups(groups)
+
+	return nil
+}
+
+// 获取alerting.alertmanagers节点，isCreate为true时不存在则创建，alerting添加到rule_files和scrape_configs前面
+func alertmanagersNode(root *yaml.Node, isCreate bool) *yaml.Node {
+	alerting := editableValue(root, "alerting")
+	if alerting == nil || alerting.Kind != yaml.MappingNode {
+		if !isCreate {
+			return nil
+		}
+		alerting = newMappingNode()
+		if MappingKeyIndex(root, "alerting") >= 0 {
+			SetMappingValue(root, "alerting", alerting)
+		} else {
+			index := MappingKeyIndex(root, RuleFiles)
+			if index < 0 {
+				index = MappingKeyIndex(root, "scrape_configs")
+			}
+			InsertMappingValue(root, index, "alerting", alerting)
+		}
+	}
+
+	seq := editableValue(alerting, "alertmanagers")
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		if !isCreate {
+			return nil
+		}
+		seq = newSequenceNode()
+		SetMappingValue(alerting, "alertmanagers", seq)
+	}
+
+	return seq
+}
+
+// 选择alertmanager分组，index小于0时选择唯一的分组，isCreate为true且没有分组时自动创建
+func selectAlertmanagerNode(root *yaml.Node, index int, isCreate bool) (*yaml.Node, error) {
+	seq := alertmanagersNode(root, isCreate)
+	if seq == nil {
+		return nil, fmt.Errorf("alerting.alertmanagers is empty")
+	}
+
+	if index < 0 {
+		switch len(seq.Content) {
+		case 0:
+			if !isCreate {
+				return nil, fmt.Errorf("alerting.alertmanagers is empty")
+			}
+			seq.Content = append(seq.Content, newMappingNode())
+		case 1:
+		default:
+			return nil, fmt.Errorf("there are %d alertmanager groups, please specify the group index", len(seq.Content))
+		}
+		index = 0
+	}
+
+	if index >= len(seq.Content) {
+		return nil, fmt.Errorf("alertmanager group '%d' not found", index)
+	}
+
+	am := seq.Content[index]
+	if am.Kind == yaml.AliasNode {
+		am = copyNode(am)
+		seq.Content[index] = am
+	}
+	if am.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("alertmanager group '%d' is not a mapping", index)
+	}
+
+	return am, nil
+}
+
+func isAlertmanagerSettingName(name string) bool {
+	for _, n := range AlertmanagerSettingNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package promConf
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+var alertmanagersData = []byte(`alerting:
+  alertmanagers:
+    - scheme: http
+      static_configs:
+        - targets: null
+global:
+  evaluation_interval: 20s
+scrape_configs: []
+`)
+
+func TestConfigYaml_AlertmanagerTargets(t *testing.T) {
+	c := NewConfigYaml(alertmanagersData)
+	err := c.AddAlertmanagerTargets(-1, []string{"10.0.0.1:9093", "10.0.0.2:9093", "10.0.0.1:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetAlertmanagerSettings(0, map[string]string{AlertmanagerScheme: "https", AlertmanagerPathPrefix: "/am"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `alerting:
+  alertmanagers:
+    - scheme: https
+      path_prefix: /am
+      static_configs:
+        - targets:
+            - 10.0.0.1:9093
+            - 10.0.0.2:9093
+global:
+  evaluation_interval: 20s
+scrape_configs: []
+`
+	if string(c.Data) != expected {
+		t.Fatalf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+
+	// 多个alertmanager分组
+	err = c.AddAlertmanager([]string{"10.0.1.1:9093"}, map[string]string{AlertmanagerScheme: "https"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.AddAlertmanagerTargets(-1, []string{"10.0.1.2:9093"}); err == nil {
+		t.Error("expected error when there are several alertmanager groups")
+	}
+	err = c.ReplaceAlertmanagerTargets(1, []string{"10.0.1.2:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.DelAlertmanagerTargets(-1, []string{"10.0.0.2:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ams, err := c.GetAlertmanagers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ams) != 2 {
+		t.Fatalf("expected 2 alertmanager groups, got %d", len(ams))
+	}
+	if ams[0].Scheme != "https" || ams[0].PathPrefix != "/am" || len(ams[0].Targets) != 1 || ams[0].Targets[0] != "10.0.0.1:9093" {
+		t.Errorf("unexpected alertmanager %+v", ams[0])
+	}
+	if ams[1].Scheme != "https" || len(ams[1].Targets) != 1 || ams[1].Targets[0] != "10.0.1.2:9093" {
+		t.Errorf("unexpected alertmanager %+v", ams[1])
+	}
+
+	err = c.DelAlertmanager(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetAlertmanagerSettings(-1, map[string]string{AlertmanagerPathPrefix: ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.SetAlertmanagerSettings(-1, map[string]string{"foo": "bar"}); err == nil {
+		t.Error("expected unknown setting error")
+	}
+	if err = c.DelAlertmanager(3); err == nil {
+		t.Error("expected not found error")
+	}
+
+	expected = `alerting:
+  alertmanagers:
+    - scheme: https
+      static_configs:
+        - targets:
+            - 10.0.0.1:9093
+global:
+  evaluation_interval: 20s
+scrape_configs: []
+`
+	if string(c.Data) != expected {
+		t.Fatalf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+}
+
+func TestConfigYaml_AlertmanagerTargetsDemo(t *testing.T) {
+	data, err := ioutil.ReadFile("../demo/prometheus.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewConfigYaml(data)
+	err = c.AddAlertmanagerTargets(-1, []string{"127.0.0.1:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ams, err := c.GetAlertmanagers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ams) != 1 || ams[0].Scheme != "http" || len(ams[0].Targets) != 1 || ams[0].Targets[0] != "127.0.0.1:9093" {
+		t.Errorf("unexpected alertmanagers %+v", ams)
+	}
+}
+
+func TestConfigYaml_AddAlertmanagerTargetsToEmpty(t *testing.T) {
+	c := NewConfigYaml([]byte(`global:
+  scrape_interval: 15s
+scrape_configs:
+  - job_name: prometheus
+    static_configs:
+      - targets: ['localhost:9090']
+`))
+	if err := c.DelAlertmanagerTargets(-1, []string{"localhost:9093"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddAlertmanagerTargets(-1, []string{"localhost:9093"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `global:
+  scrape_interval: 15s
+alerting:
+  alertmanagers:
+    - static_configs:
+        - targets:
+            - localhost:9093
+scrape_configs:
+  - job_name: prometheus
+    static_configs:
+      - targets: ['localhost:9090']
+`
+	if string(c.Data) != expected {
+		t.Fatalf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+}
+
+func TestConfigYaml_DelAlertmanagerLastTarget(t *testing.T) {
+	c := NewConfigYaml([]byte(`alerting:
+  alertmanagers:
+    - scheme: https
+      path_prefix: /am
+      timeout: 5s
+      basic_auth:
+        username: admin
+        password: 123456
+      static_configs:
+        - targets: ['127.0.0.1:9093']
+    - scheme: https
+      static_configs:
+        - targets: ['10.0.0.1:9093']
+        - targets: ['10.0.0.2:9093']
+scrape_configs: []
+`))
+
+	// 删除分组中最后的target时删除static_configs分组，alertmanager分组和设置保留
+	err := c.DelAlertmanagerTargets(1, []string{"10.0.0.2:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.DelAlertmanagerTargets(-1, []string{"127.0.0.1:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `alerting:
+  alertmanagers:
+    - scheme: https
+      path_prefix: /am
+      timeout: 5s
+      basic_auth:
+        username: admin
+        password: 123456
+      static_configs: []
+    - scheme: https
+      static_configs:
+        - targets: ['10.0.0.1:9093']
+scrape_configs: []
+`
+	if string(c.Data) != expected {
+		t.Fatalf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+	if err = c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 再添加target
+	err = c.AddAlertmanagerTargets(0, []string{"127.0.0.2:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ams, err := c.GetAlertmanagers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ams[0].Scheme != "https" || ams[0].PathPrefix != "/am" || len(ams[0].Targets) != 1 || ams[0].Targets[0] != "127.0.0.2:9093" {
+		t.Errorf("unexpected alertmanager %+v", ams[0])
+	}
+}
@@ -0,0 +1,333 @@
+package promConf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// --------------------------------- 声明式修改job ---------------------------------
+
+// 使job与期望状态一致的修改类型
+const (
+	ApplyAdd     = "add"     // 添加job
+	ApplyUpdate  = "update"  // 只修改static_configs分组的target和标签
+	ApplyReplace = "replace" // job的其他配置不同，替换整个job
+	ApplyDelete  = "delete"  // 删除没有声明的job，只在prune时执行
+)
+
+// ApplyChange 一个job的修改
+type ApplyChange struct {
+	Action  string   `json:"action"`
+	Job     string   `json:"job"`
+	Details []string `json:"details,omitempty"` // 修改的内容，例如 + 10.0.0.1:9100 {env=prod}
+}
+
+// ApplyPlan 使配置与期望状态一致的所有修改，没有修改的job不包括在内
+type ApplyPlan struct {
+	Changes []*ApplyChange `json:"changes"`
+}
+
+// IsEmpty 是否没有修改
+func (p *ApplyPlan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count 修改类型为action的job数量
+func (p *ApplyPlan) Count(action string) int {
+	n := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Apply 修改配置使期望状态中声明的job、target和标签与之一致，desired为期望状态文件内容，
+// 可以是job列表或包含scrape_configs的prometheus配置，prune为true时删除没有声明的job，
+// 返回执行的修改，出错时配置不变
+func (c *ConfigYaml) Apply(desired []byte, prune bool) (*ApplyPlan, error) {
+	jobNodes, err := parseDesiredJobs(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := c.root()
+	if err != nil {
+		return nil, err
+	}
+
+	// 在副本上修改，全部成功后才更新配置
+	cy := NewConfigYaml(c.Data)
+	plan := &ApplyPlan{}
+	declared := map[string]bool{}
+	for _, jobNode := range jobNodes {
+		jc := &JobConfig{}
+		err = jobNode.Decode(jc)
+		if err != nil {
+			return nil, err
+		}
+		if err = jc.CheckValid(); err != nil {
+			return nil, fmt.Errorf("desired job '%s' is invalid, %v", jc.JobName, err)
+		}
+		if declared[jc.JobName] {
+			return nil, newError(ErrDuplicateJob, jc.JobName, jobPath(jc.JobName), "job '%s' is declared more than once", jc.JobName)
+		}
+		declared[jc.JobName] = true
+
+		current, _, err := findJobNode(root, jc.JobName)
+		if errors.Is(err, ErrJobNotFound) {
+			change := &ApplyChange{Action: ApplyAdd, Job: jc.JobName}
+			change.Details = diffGroups(nil, jc.StaticConfigs)
+			if err = cy.addJobNode(jc.JobName, jobNode); err != nil {
+				return nil, err
+			}
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+
+		change, groups, err := diffJob(current, jobNode, jc)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case change == nil:
+			continue
+		case change.Action == ApplyUpdate:
+			err = cy.ReplaceJobGroups(jc.JobName, groups)
+		default:
+			err = cy.addJobNode(jc.JobName, jobNode)
+		}
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if prune {
+		for _, name := range jobNames(root) {
+			if declared[name] {
+				continue
+			}
+			if err = cy.DelJob(name); err != nil {
+				return nil, err
+			}
+			plan.Changes = append(plan.Changes, &ApplyChange{Action: ApplyDelete, Job: name})
+		}
+	}
+
+	c.Data = cy.Data
+	return plan, nil
+}
+
+// 解析期望状态中的job节点
+func parseDesiredJobs(data []byte) ([]*yaml.Node, error) {
+	doc, err := parseDesiredDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := doc
+	if doc.Kind == yaml.MappingNode {
+		seq = MappingValue(doc, "scrape_configs")
+	}
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%w: desired state must be a list of jobs or contain field 'scrape_configs'", ErrInvalidConfig)
+	}
+
+	jobs := []*yaml.Node{}
+	for _, item := range seq.Content {
+		item = copyNode(item)
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%w: job of desired state is not a yaml object", ErrInvalidConfig)
+		}
+		jobs = append(jobs, item)
+	}
+
+	return jobs, nil
+}
+
+// 期望状态的根节点，可以是mapping或sequence
+func parseDesiredDocument(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	err := yaml.Unmarshal(data, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%w: desired state is empty", ErrInvalidConfig)
+	}
+
+	node := resolveAlias(doc.Content[0])
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+		// json格式转为块风格
+		clearFlowStyle(node)
+	}
+	return node, nil
+}
+
+// 所有job的名称
+func jobNames(root *yaml.Node) []string {
+	names := []string{}
+	seq := MappingValue(root, "scrape_configs")
+	if seq == nil {
+		return names
+	}
+	for _, item := range seq.Content {
+		if name := MappingValue(item, "job_name"); name != nil {
+			names = append(names, name.Value)
+		}
+	}
+	return names
+}
+
+// 比较当前job与期望的job，相同时返回nil，只有static_configs不同时返回修改后的分组，
+// 已存在的分组复用原节点，保留注释和格式
+func diffJob(current *yaml.Node, desired *yaml.Node, jc *JobConfig) (*ApplyChange, []StaticConfigs, error) {
+	currentFields := map[string]interface{}{}
+	if err := current.Decode(&currentFields); err != nil {
+		return nil, nil, err
+	}
+	desiredFields := map[string]interface{}{}
+	if err := desired.Decode(&desiredFields); err != nil {
+		return nil, nil, err
+	}
+	if reflect.DeepEqual(currentFields, desiredFields) {
+		return nil, nil, nil
+	}
+
+	groups, err := decodeGroups(MappingValue(current, "static_configs"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, hasCurrent := currentFields["static_configs"]
+	_, hasDesired := desiredFields["static_configs"]
+	delete(currentFields, "static_configs")
+	delete(desiredFields, "static_configs")
+	if hasCurrent && hasDesired && reflect.DeepEqual(currentFields, desiredFields) {
+		newGroups := reuseGroupNodes(groups, jc.StaticConfigs)
+		details := diffGroups(groups, newGroups)
+		if len(details) == 0 {
+			details = []string{"~ static_configs groups"}
+		}
+		return &ApplyChange{Action: ApplyUpdate, Job: jc.JobName, Details: details}, newGroups, nil
+	}
+
+	details := diffFields(currentFields, desiredFields)
+	if hasCurrent || hasDesired {
+		details = append(details, diffGroups(groups, jc.StaticConfigs)...)
+	}
+	return &ApplyChange{Action: ApplyReplace, Job: jc.JobName, Details: details}, nil, nil
+}
+
+// 期望的分组复用标签相同的原分组节点，没有标签相同的分组时复用相同位置的原分组节点
+func reuseGroupNodes(oldGroups []StaticConfigs, newGroups []StaticConfigs) []StaticConfigs {
+	used := map[int]bool{}
+	groups := []StaticConfigs{}
+	for _, group := range newGroups {
+		for i, old := range oldGroups {
+			if !used[i] && equalLabels(old.Labels, group.Labels) {
+				used[i] = true
+				group.node = old.node
+				break
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	for i := range groups {
+		if groups[i].node == nil && i < len(oldGroups) && !used[i] {
+			used[i] = true
+			groups[i].node = oldGroups[i].node
+		}
+	}
+	return groups
+}
+
+// 比较分组中的target和标签，返回修改的内容，+表示添加，-表示删除，~表示标签修改
+func diffGroups(oldGroups []StaticConfigs, newGroups []StaticConfigs) []string {
+	oldTargets, oldOrder := targetLabels(oldGroups)
+	newTargets, newOrder := targetLabels(newGroups)
+
+	details := []string{}
+	for _, address := range newOrder {
+		oldLabels, ok := oldTargets[address]
+		switch {
+		case !ok:
+			details = append(details, fmt.Sprintf("+ %s %s", address, formatLabelSet(newTargets[address])))
+		case !equalLabels(oldLabels, newTargets[address]):
+			details = append(details, fmt.Sprintf("~ %s %s -> %s", address, formatLabelSet(oldLabels), formatLabelSet(newTargets[address])))
+		}
+	}
+	for _, address := range oldOrder {
+		if _, ok := newTargets[address]; !ok {
+			details = append(details, fmt.Sprintf("- %s %s", address, formatLabelSet(oldTargets[address])))
+		}
+	}
+
+	return details
+}
+
+// target及其所在分组的标签，target在多个分组中时使用第一个分组
+func targetLabels(groups []StaticConfigs) (map[string]map[string]string, []string) {
+	targets := map[string]map[string]string{}
+	order := []string{}
+	for _, group := range groups {
+		for _, address := range group.Targets {
+			if _, ok := targets[address]; ok {
+				continue
+			}
+			labels := group.Labels
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			targets[address] = labels
+			order = append(order, address)
+		}
+	}
+	return targets, order
+}
+
+// 比较job除static_configs外的字段，返回修改的字段名称
+func diffFields(oldFields map[string]interface{}, newFields map[string]interface{}) []string {
+	keys := []string{}
+	for key := range oldFields {
+		keys = append(keys, key)
+	}
+	for key := range newFields {
+		if _, ok := oldFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	details := []string{}
+	for _, key := range keys {
+		oldValue, inOld := oldFields[key]
+		newValue, inNew := newFields[key]
+		switch {
+		case !inOld:
+			details = append(details, "+ "+key)
+		case !inNew:
+			details = append(details, "- "+key)
+		case !reflect.DeepEqual(oldValue, newValue):
+			details = append(details, "~ "+key)
+		}
+	}
+	return details
+}
+
+// 标签格式为{k1=v1,k2=v2}，按名称排序
+func formatLabelSet(labels map[string]string) string {
+	kvs := []string{}
+	for _, key := range SortedKeys(labels) {
+		kvs = append(kvs, key+"="+labels[key])
+	}
+	return "{" + strings.Join(kvs, ",") + "}"
+}
@@ -0,0 +1,128 @@
+package promConf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var applyData = []byte(`scrape_configs:
+  # node exporter
+  - job_name: node
+    static_configs:
+      - targets: ['10.0.0.1:9100', '10.0.0.2:9100']
+        labels:
+          env: prod
+  - job_name: mysqld
+    static_configs:
+      - targets: ['10.0.0.1:9104']
+  - job_name: redis
+    static_configs:
+      - targets: ['10.0.0.1:9121']
+`)
+
+func TestConfigYaml_Apply(t *testing.T) {
+	desired := []byte(`
+- job_name: node
+  static_configs:
+    - targets: ['10.0.0.1:9100', '10.0.0.3:9100']
+      labels:
+        env: prod
+- job_name: mysqld
+  scrape_interval: 30s
+  static_configs:
+    - targets: ['10.0.0.1:9104']
+- job_name: blackbox
+  static_configs:
+    - targets: ['10.0.0.1:9115']
+`)
+
+	c := NewConfigYaml(applyData)
+	plan, err := c.Apply(desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `scrape_configs:
+  # node exporter
+  - job_name: node
+    static_configs:
+      - targets: ['10.0.0.1:9100', '10.0.0.3:9100']
+        labels:
+          env: prod
+  - job_name: mysqld
+    scrape_interval: 30s
+    static_configs:
+      - targets: ['10.0.0.1:9104']
+  - job_name: redis
+    static_configs:
+      - targets: ['10.0.0.1:9121']
+  - job_name: blackbox
+    static_configs:
+      - targets: ['10.0.0.1:9115']
+`
+	if string(c.Data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+
+	actions := []string{}
+	for _, change := range plan.Changes {
+		actions = append(actions, change.Action+" "+change.Job)
+	}
+	if strings.Join(actions, ",") != "update node,replace mysqld,add blackbox" {
+		t.Errorf("unexpected plan %v", actions)
+	}
+	details := strings.Join(plan.Changes[0].Details, ",")
+	if details != "+ 10.0.0.3:9100 {env=prod},- 10.0.0.2:9100 {env=prod}" {
+		t.Errorf("unexpected details %s", details)
+	}
+
+	// 再次执行没有修改
+	plan, err = c.Apply(desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.IsEmpty() {
+		t.Errorf("expected empty plan, got %+v", plan.Changes[0])
+	}
+
+	// prune删除没有声明的job
+	plan, err = c.Apply(desired, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count(ApplyDelete) != 1 || plan.Changes[0].Job != "redis" {
+		t.Errorf("unexpected plan %+v", plan.Changes)
+	}
+	if strings.Contains(string(c.Data), "redis") {
+		t.Errorf("job redis is not deleted\n%s", c.Data)
+	}
+}
+
+func TestConfigYaml_ApplyInvalid(t *testing.T) {
+	c := NewConfigYaml(applyData)
+
+	_, err := c.Apply([]byte("- job_name: a\n  static_configs:\n    - targets: ['1:1']\n- job_name: a\n  static_configs:\n    - targets: ['1:1']\n"), false)
+	if !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("expected ErrDuplicateJob, got %v", err)
+	}
+
+	_, err = c.Apply([]byte("foo: bar\n"), false)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig, got %v", err)
+	}
+
+	_, err = c.Apply([]byte("- job_name: a\n"), false)
+	if err == nil {
+		t.Error("expected error for job without targets")
+	}
+
+	// 期望状态为prometheus配置
+	plan, err := c.Apply(applyData, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.IsEmpty() {
+		t.Errorf("expected empty plan, got %+v", plan.Changes[0])
+	}
+}
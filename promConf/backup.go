@@ -0,0 +1,275 @@
+package promConf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BackupTimeFormat 备份文件名中的时间格式
+const BackupTimeFormat = "20060102150405.000"
+
+// BackupPolicy 备份保留策略，每次备份后删除超出策略的备份，零值表示不限制
+type BackupPolicy struct {
+	Keep   int           // 每个文件最多保留的备份数量，0表示不限制
+	MaxAge time.Duration // 备份最长保留时间，0表示不限制
+}
+
+// IsUnlimited 是否不限制备份
+func (p BackupPolicy) IsUnlimited() bool {
+	return p.Keep <= 0 && p.MaxAge <= 0
+}
+
+// Backup 备份文件
+type Backup struct {
+	File string    // 备份文件路径
+	Time time.Time // 备份时间
+}
+
+// ID 备份的时间标识，可以用于回滚
+func (b *Backup) ID() string {
+	return b.Time.Format(BackupTimeFormat)
+}
+
+// 备份目录为文件同级目录下的bak
+func backupDir(file string) string {
+	return filepath.Join(filepath.Dir(file), "bak")
+}
+
+// 备份文件到同级目录bak下，文件名为：原文件名.时间
+func backupFile(file string, policy BackupPolicy) error {
+	bkPath := backupDir(file)
+	os.MkdirAll(bkPath, 0777)
+	bkFile := fmt.Sprintf("%s/%s.%s", bkPath, filepath.Base(file), time.Now().Format(BackupTimeFormat))
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(bkFile, data, 0666)
+	if err != nil {
+		return err
+	}
+
+	if !policy.IsUnlimited() {
+		_, err = PruneBackups(file, policy)
+	}
+	return err
+}
+
+// ListBackups 获取文件的所有备份，按时间从新到旧排序
+func ListBackups(file string) ([]*Backup, error) {
+	backups := []*Backup{}
+
+	bkPath := backupDir(file)
+	infos, err := ioutil.ReadDir(bkPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return backups, nil
+		}
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		t, ok := ParseBackupName(filepath.Base(file), info.Name())
+		if !ok {
+			continue
+		}
+		backups = append(backups, &Backup{File: filepath.Join(bkPath, info.Name()), Time: t})
+	}
+
+	SortBackups(backups)
+	return backups, nil
+}
+
+// ParseBackupName 解析备份文件名中的备份时间，备份文件名为：原文件名.时间，不是文件base的备份时返回false
+func ParseBackupName(base string, name string) (time.Time, bool) {
+	prefix := base + "."
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(BackupTimeFormat, strings.TrimPrefix(name, prefix), time.Local)
+	if err != nil {
+		// 不是备份文件，例如名称有相同前缀的其他文件的备份
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// SortBackups 按时间从新到旧排序
+func SortBackups(backups []*Backup) {
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+}
+
+// FindBackup 查找备份，to为备份时间(如 20220102150405.000，可以只指定前缀)或序号N(1表示最新的备份)
+func FindBackup(file string, to string) (*Backup, error) {
+	backups, err := ListBackups(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backup found for %s", file)
+	}
+
+	to = strings.Trim(to, " ")
+	if len(to) < len("20060102") {
+		n, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is invalid, it must be a backup time or number, eg: %s or 1", to, backups[0].ID())
+		}
+		if n < 1 || n > len(backups) {
+			return nil, fmt.Errorf("backup number %d is out of range, there are %d backups", n, len(backups))
+		}
+		return backups[n-1], nil
+	}
+
+	matches := []*Backup{}
+	for _, backup := range backups {
+		if strings.HasPrefix(backup.ID(), to) {
+			matches = append(matches, backup)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no backup found at '%s'", to)
+	case 1:
+		return matches[0], nil
+	}
+
+	return nil, fmt.Errorf("'%s' matches %d backups, please specify a more exact time", to, len(matches))
+}
+
+// PruneBackups 删除超出保留策略的备份，返回被删除的备份
+func PruneBackups(file string, policy BackupPolicy) ([]*Backup, error) {
+	backups, err := ListBackups(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return RemoveExpiredBackups(backups, policy, os.Remove)
+}
+
+// RemoveExpiredBackups 使用remove删除超出保留策略的备份，backups按时间从新到旧排序，
+// 本地文件和远程文件的备份共用，返回被删除的备份
+func RemoveExpiredBackups(backups []*Backup, policy BackupPolicy, remove func(file string) error) ([]*Backup, error) {
+	removed := []*Backup{}
+	for _, backup := range ExpiredBackups(backups, policy) {
+		err := remove(backup.File)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup)
+	}
+
+	return removed, nil
+}
+
+// ExpiredBackups 获取超出保留策略的备份，backups按时间从新到旧排序
+func ExpiredBackups(backups []*Backup, policy BackupPolicy) []*Backup {
+	expired := []*Backup{}
+	now := time.Now()
+	for i, backup := range backups {
+		if (policy.Keep > 0 && i >= policy.Keep) || (policy.MaxAge > 0 && now.Sub(backup.Time) > policy.MaxAge) {
+			expired = append(expired, backup)
+		}
+	}
+	return expired
+}
+
+// SummarizeChange 汇总两个版本之间的修改，包括增删的行数和修改的job
+func SummarizeChange(oldData []byte, newData []byte) string {
+	added, deleted := 0, 0
+	for _, op := range diffLines(splitLines(string(oldData)), splitLines(string(newData))) {
+		switch op.kind {
+		case lineInsert:
+			added++
+		case lineDelete:
+			deleted++
+		}
+	}
+	if added == 0 && deleted == 0 {
+		return "no changes"
+	}
+
+	summary := fmt.Sprintf("+%d -%d", added, deleted)
+	jobChanges := summarizeJobChanges(oldData, newData)
+	if len(jobChanges) > 0 {
+		summary += ", " + strings.Join(jobChanges, ", ")
+	}
+
+	return summary
+}
+
+// 比较两个版本的job，返回新增、删除和修改的job，不是prometheus配置时返回空
+func summarizeJobChanges(oldData []byte, newData []byte) []string {
+	oldJobs, err := jobsByName(oldData)
+	if err != nil {
+		return nil
+	}
+	newJobs, err := jobsByName(newData)
+	if err != nil {
+		return nil
+	}
+
+	added, deleted, changed := []string{}, []string{}, []string{}
+	for name, newJob := range newJobs {
+		oldJob, ok := oldJobs[name]
+		if !ok {
+			added = append(added, name)
+		} else if oldJob != newJob {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldJobs {
+		if _, ok := newJobs[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+
+	changes := []string{}
+	for _, v := range []struct {
+		action string
+		jobs   []string
+	}{{"added", added}, {"deleted", deleted}, {"changed", changed}} {
+		if len(v.jobs) > 0 {
+			sort.Strings(v.jobs)
+			changes = append(changes, fmt.Sprintf("job %s: %s", v.action, strings.Join(v.jobs, ",")))
+		}
+	}
+
+	return changes
+}
+
+// 获取所有job编码后的内容，用于比较job是否修改
+func jobsByName(data []byte) (map[string]string, error) {
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := map[string]string{}
+	for _, job := range cfg.ScrapeConfigs {
+		if job == nil {
+			continue
+		}
+		out, err := yaml.Marshal(job)
+		if err != nil {
+			return nil, err
+		}
+		jobs[job.JobName] = string(out)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,134 @@
+package promConf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeVersions(t *testing.T, file string, versions []string, policy BackupPolicy) {
+	err := ioutil.WriteFile(file, []byte(versions[0]), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range versions[1:] {
+		time.Sleep(2 * time.Millisecond) // 备份文件名精确到毫秒
+		err = NewConfigYaml([]byte(version)).Persistent(file, policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackups(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "prometheus.yml")
+	versions := []string{
+		"scrape_configs:\n  - job_name: a\n    static_configs: [{targets: ['10.0.0.1:9100']}]\n",
+		"scrape_configs:\n  - job_name: a\n    static_configs: [{targets: ['10.0.0.2:9100']}]\n",
+		"scrape_configs:\n  - job_name: b\n    static_configs: [{targets: ['10.0.0.2:9100']}]\n",
+	}
+	writeVersions(t, file, versions, BackupPolicy{})
+
+	backups, err := ListBackups(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || !backups[0].Time.After(backups[1].Time) {
+		t.Fatalf("got %d backups, expected 2 sorted from new to old", len(backups))
+	}
+
+	// 1为最新的备份，即第二个版本
+	backup, err := FindBackup(file, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(backup.File)
+	if string(data) != versions[1] {
+		t.Errorf("backup 1 is %q, expected %q", data, versions[1])
+	}
+
+	backup, err = FindBackup(file, backups[1].ID())
+	if err != nil || backup.File != backups[1].File {
+		t.Errorf("find by time got %v %v, expected %s", backup, err, backups[1].File)
+	}
+	for _, to := range []string{"0", "3", "x", "19700101"} {
+		if _, err = FindBackup(file, to); err == nil {
+			t.Errorf("%s: expected error", to)
+		}
+	}
+
+	removed, err := PruneBackups(file, BackupPolicy{Keep: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, _ = ListBackups(file)
+	if len(removed) != 1 || len(backups) != 1 || removed[0].Time.After(backups[0].Time) {
+		t.Errorf("prune by count removed %d, kept %d", len(removed), len(backups))
+	}
+
+	removed, err = PruneBackups(file, BackupPolicy{MaxAge: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, _ = ListBackups(file)
+	if len(removed) != 1 || len(backups) != 0 {
+		t.Errorf("prune by age removed %d, kept %d", len(removed), len(backups))
+	}
+}
+
+func TestBackupPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "prometheus.yml")
+	version := "scrape_configs:\n  - job_name: a\n    static_configs: [{targets: ['10.0.0.1:9100']}]\n"
+	writeVersions(t, file, []string{version, version, version, version, version}, BackupPolicy{Keep: 2})
+
+	backups, err := ListBackups(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Errorf("got %d backups, expected 2", len(backups))
+	}
+}
+
+func TestRemoveExpiredBackups(t *testing.T) {
+	now := time.Now()
+	backups := []*Backup{
+		{File: "bak/prometheus.yml.3", Time: now},
+		{File: "bak/prometheus.yml.2", Time: now.Add(-time.Hour)},
+		{File: "bak/prometheus.yml.1", Time: now.Add(-48 * time.Hour)},
+	}
+
+	removedFiles := []string{}
+	removed, err := RemoveExpiredBackups(backups, BackupPolicy{MaxAge: 24 * time.Hour}, func(file string) error {
+		removedFiles = append(removedFiles, file)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || strings.Join(removedFiles, ",") != "bak/prometheus.yml.1" {
+		t.Errorf("removed %v, expected bak/prometheus.yml.1", removedFiles)
+	}
+
+	if expired := ExpiredBackups(backups, BackupPolicy{}); len(expired) != 0 {
+		t.Errorf("got %d expired backups without policy, expected 0", len(expired))
+	}
+}
+
+func TestSummarizeChange(t *testing.T) {
+	oldData := []byte("scrape_configs:\n  - job_name: a\n    static_configs: [{targets: ['10.0.0.1:9100']}]\n  - job_name: b\n    static_configs: [{targets: ['10.0.0.1:9100']}]\n")
+	newData := []byte("scrape_configs:\n  - job_name: a\n    static_configs: [{targets: ['10.0.0.2:9100']}]\n  - job_name: c\n    static_configs: [{targets: ['10.0.0.1:9100']}]\n")
+
+	summary := SummarizeChange(oldData, newData)
+	for _, s := range []string{"+2 -2", "job added: c", "job deleted: b", "job changed: a"} {
+		if !strings.Contains(summary, s) {
+			t.Errorf("summary %q does not contain %q", summary, s)
+		}
+	}
+
+	if summary = SummarizeChange(oldData, oldData); summary != "no changes" {
+		t.Errorf("got %q, expected no changes", summary)
+	}
+}
@@ -0,0 +1,139 @@
+package promConf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BlackboxProbers blackbox exporter支持的探测方式
+var BlackboxProbers = []string{"http", "tcp", "icmp", "dns", "grpc"}
+
+// BlackboxConfig blackbox exporter的配置文件blackbox.yml，管理modules中的探测模块
+type BlackboxConfig struct {
+	Data []byte // 文件内容
+}
+
+// BlackboxModule 探测模块
+type BlackboxModule struct {
+	Name    string `json:"name" yaml:"-"`
+	Prober  string `json:"prober" yaml:"prober"`
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// NewBlackboxConfig 实例化，data为空时表示新的配置文件
+func NewBlackboxConfig(data []byte) *BlackboxConfig {
+	return &BlackboxConfig{Data: data}
+}
+
+// Persistent 校验后持久化，文件已存在时先备份，policy为备份保留策略
+func (b *BlackboxConfig) Persistent(file string, policy BackupPolicy) error {
+	err := b.Validate()
+	if err != nil {
+		return err
+	}
+
+	return WriteFileWithBackup(file, b.Data, policy)
+}
+
+// Validate 校验所有模块的探测方式和超时时间
+func (b *BlackboxConfig) Validate() error {
+	modules, err := b.GetModules()
+	if err != nil {
+		return &ValidateError{Problems: []string{err.Error()}}
+	}
+
+	v := &validator{}
+	for _, m := range modules {
+		path := "modules." + m.Name
+		if !isContainString(BlackboxProbers, m.Prober) {
+			v.addf(path+".prober", "unknown prober '%s', supports %s", m.Prober, strings.Join(BlackboxProbers, ", "))
+		}
+		if m.Timeout != "" {
+			if _, err := ParseDuration(m.Timeout); err != nil {
+				v.addf(path+".timeout", "%v", err)
+			}
+		}
+	}
+
+	return v.err()
+}
+
+// GetModules 获取所有探测模块，按名称排序
+func (b *BlackboxConfig) GetModules() ([]*BlackboxModule, error) {
+	cfg := struct {
+		Modules map[string]*BlackboxModule `yaml:"modules"`
+	}{}
+	err := yaml.Unmarshal(b.Data, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := []*BlackboxModule{}
+	for name, m := range cfg.Modules {
+		if m == nil {
+			m = &BlackboxModule{}
+		}
+		m.Name = name
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+
+	return modules, nil
+}
+
+// AddModule 添加探测模块，模块已存在时不修改，prober为空时根据模块名称的前缀推断，例如http_2xx为http
+func (b *BlackboxConfig) AddModule(name string, prober string, timeout string) error {
+	if name == "" {
+		return fmt.Errorf("module name is empty")
+	}
+	if prober == "" {
+		prober = DefaultProber(name)
+		if prober == "" {
+			return fmt.Errorf("can not infer the prober of module '%s', please specify one of %s", name, strings.Join(BlackboxProbers, ", "))
+		}
+	}
+	if !isContainString(BlackboxProbers, prober) {
+		return fmt.Errorf("unknown prober '%s', supports %s", prober, strings.Join(BlackboxProbers, ", "))
+	}
+	if timeout != "" {
+		if _, err := ParseDuration(timeout); err != nil {
+			return err
+		}
+	}
+
+	node, err := ToNode(&BlackboxModule{Prober: prober, Timeout: timeout})
+	if err != nil {
+		return err
+	}
+
+	data, err := EditYaml(b.Data, func(root *yaml.Node) error {
+		modules := editableValue(root, "modules")
+		if modules == nil || modules.Kind != yaml.MappingNode {
+			modules = newMappingNode()
+			SetMappingValue(root, "modules", modules)
+		}
+		if MappingKeyIndex(modules, name) >= 0 {
+			return nil
+		}
+		SetMappingValue(modules, name, node)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	b.Data = data
+	return nil
+}
+
+// DefaultProber 根据模块名称的前缀推断探测方式，例如http_2xx为http、icmp为icmp，无法推断时返回空
+func DefaultProber(module string) string {
+	prefix := strings.SplitN(module, "_", 2)[0]
+	if isContainString(BlackboxProbers, prefix) {
+		return prefix
+	}
+	return ""
+}
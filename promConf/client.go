@@ -0,0 +1,152 @@
+package promConf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout 访问prometheus http接口的默认超时时间
+const DefaultTimeout = 10 * time.Second
+
+// ClientOptions 访问prometheus http接口的认证、TLS和超时设置，零值表示不认证、使用系统CA证书和默认超时时间
+type ClientOptions struct {
+	Username        string // basic auth用户名
+	Password        string // basic auth密码
+	BearerToken     string
+	BearerTokenFile string // 从文件读取bearer token，与BearerToken只能设置一个
+
+	CAFile             string // 校验服务端证书的CA证书
+	CertFile           string // 客户端证书，与KeyFile一起设置
+	KeyFile            string
+	InsecureSkipVerify bool // 不校验服务端证书
+
+	Timeout time.Duration // 请求超时时间，为0时使用DefaultTimeout
+}
+
+// prometheus http接口的客户端
+type promClient struct {
+	client  *http.Client
+	options *ClientOptions
+}
+
+func newPromClient(options *ClientOptions) (*promClient, error) {
+	if options == nil {
+		options = &ClientOptions{}
+	}
+	if options.BearerToken != "" && options.BearerTokenFile != "" {
+		return nil, fmt.Errorf("bearer token and bearer token file cannot be set together")
+	}
+	if options.BearerToken != "" && options.Username != "" {
+		return nil, fmt.Errorf("basic auth and bearer token cannot be set together")
+	}
+	if (options.CertFile == "") != (options.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+	if options.CAFile != "" {
+		data, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if options.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &promClient{
+		client:  &http.Client{Transport: transport, Timeout: timeout},
+		options: options,
+	}, nil
+}
+
+// 发送请求，返回状态码为2xx时的响应内容，其他状态码返回*HTTPError
+func (c *promClient) do(method string, rawURL string) ([]byte, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.options.Username != "":
+		req.SetBasicAuth(c.options.Username, c.options.Password)
+	case c.options.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.options.BearerToken)
+	case c.options.BearerTokenFile != "":
+		token, err := ioutil.ReadFile(c.options.BearerTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPError{Method: method, URL: rawURL, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+
+	return data, nil
+}
+
+// HTTPError prometheus http接口返回的状态码不是2xx
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += ", " + e.Body
+	}
+	return msg
+}
+
+// 检查prometheus地址，只支持http和https
+func checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL(%s) is invalid, eg: http://127.0.0.1:9090", rawURL)
+	}
+	return nil
+}
+
+// PromBaseURL 获取prometheus的根地址，去掉reload接口的路径/-/reload，保留--web.route-prefix设置的路径前缀
+func PromBaseURL(promURL string) string {
+	u := strings.TrimSuffix(promURL, "/")
+	u = strings.TrimSuffix(u, "/-/reload")
+	return strings.TrimSuffix(u, "/")
+}
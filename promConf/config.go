@@ -0,0 +1,192 @@
+package promConf
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// prometheus配置的类型定义，字段与prometheus配置文件一一对应，
+// 没有定义的字段保存在各结构体的Extra中，解析后再编码不会丢失数据。
+
+// Config prometheus配置
+type Config struct {
+	Global             *GlobalConfig          `json:"global,omitempty" yaml:"global,omitempty"`
+	Alerting           *AlertingConfig        `json:"alerting,omitempty" yaml:"alerting,omitempty"`
+	RuleFiles          []string               `json:"rule_files,omitempty" yaml:"rule_files,omitempty"`
+	ScrapeConfigs      []*JobConfig           `json:"scrape_configs,omitempty" yaml:"scrape_configs,omitempty"`
+	RemoteWriteConfigs []*RemoteWriteConfig   `json:"remote_write,omitempty" yaml:"remote_write,omitempty"`
+	RemoteReadConfigs  []*RemoteReadConfig    `json:"remote_read,omitempty" yaml:"remote_read,omitempty"`
+	Storage            *StorageConfig         `json:"storage,omitempty" yaml:"storage,omitempty"`
+	Extra              map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// GlobalConfig 全局配置
+type GlobalConfig struct {
+	ScrapeInterval     string                 `json:"scrape_interval,omitempty" yaml:"scrape_interval,omitempty"`
+	ScrapeTimeout      string                 `json:"scrape_timeout,omitempty" yaml:"scrape_timeout,omitempty"`
+	EvaluationInterval string                 `json:"evaluation_interval,omitempty" yaml:"evaluation_interval,omitempty"`
+	QueryLogFile       string                 `json:"query_log_file,omitempty" yaml:"query_log_file,omitempty"`
+	ExternalLabels     map[string]string      `json:"external_labels,omitempty" yaml:"external_labels,omitempty"`
+	Extra              map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// AlertingConfig 告警配置
+type AlertingConfig struct {
+	AlertRelabelConfigs []*RelabelConfig       `json:"alert_relabel_configs,omitempty" yaml:"alert_relabel_configs,omitempty"`
+	AlertmanagerConfigs []*AlertmanagerConfig  `json:"alertmanagers,omitempty" yaml:"alertmanagers,omitempty"`
+	Extra               map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// AlertmanagerConfig alertmanager配置
+type AlertmanagerConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+
+	Scheme         string                 `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	PathPrefix     string                 `json:"path_prefix,omitempty" yaml:"path_prefix,omitempty"`
+	Timeout        string                 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	APIVersion     string                 `json:"api_version,omitempty" yaml:"api_version,omitempty"`
+	StaticConfigs  []StaticConfigs        `json:"static_configs,omitempty" yaml:"static_configs,omitempty"`
+	FileSDConfigs  []*FileSDConfig        `json:"file_sd_configs,omitempty" yaml:"file_sd_configs,omitempty"`
+	RelabelConfigs []*RelabelConfig       `json:"relabel_configs,omitempty" yaml:"relabel_configs,omitempty"`
+	Extra          map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// HTTPClientConfig http客户端配置，用于抓取、告警、远程读写
+type HTTPClientConfig struct {
+	BasicAuth       *BasicAuth     `json:"basic_auth,omitempty" yaml:"basic_auth,omitempty"`
+	Authorization   *Authorization `json:"authorization,omitempty" yaml:"authorization,omitempty"`
+	BearerToken     string         `json:"bearer_token,omitempty" yaml:"bearer_token,omitempty"`
+	BearerTokenFile string         `json:"bearer_token_file,omitempty" yaml:"bearer_token_file,omitempty"`
+	TLSConfig       *TLSConfig     `json:"tls_config,omitempty" yaml:"tls_config,omitempty"`
+	ProxyURL        string         `json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+	FollowRedirects *bool          `json:"follow_redirects,omitempty" yaml:"follow_redirects,omitempty"`
+	EnableHTTP2     *bool          `json:"enable_http2,omitempty" yaml:"enable_http2,omitempty"`
+}
+
+// BasicAuth basic认证
+type BasicAuth struct {
+	Username     string                 `json:"username" yaml:"username"`
+	Password     string                 `json:"password,omitempty" yaml:"password,omitempty"`
+	PasswordFile string                 `json:"password_file,omitempty" yaml:"password_file,omitempty"`
+	Extra        map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// Authorization 认证头
+type Authorization struct {
+	Type            string                 `json:"type,omitempty" yaml:"type,omitempty"`
+	Credentials     string                 `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+	CredentialsFile string                 `json:"credentials_file,omitempty" yaml:"credentials_file,omitempty"`
+	Extra           map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// TLSConfig tls配置
+type TLSConfig struct {
+	CAFile             string                 `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	CertFile           string                 `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile            string                 `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	ServerName         string                 `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool                   `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+	MinVersion         string                 `json:"min_version,omitempty" yaml:"min_version,omitempty"`
+	Extra              map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// FileSDConfig 文件服务发现配置
+type FileSDConfig struct {
+	Files           []string               `json:"files" yaml:"files"`
+	RefreshInterval string                 `json:"refresh_interval,omitempty" yaml:"refresh_interval,omitempty"`
+	Extra           map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// RelabelConfig 标签重写规则
+type RelabelConfig struct {
+	SourceLabels []string               `json:"source_labels,omitempty" yaml:"source_labels,flow,omitempty"`
+	Separator    string                 `json:"separator,omitempty" yaml:"separator,omitempty"`
+	Regex        string                 `json:"regex,omitempty" yaml:"regex,omitempty"`
+	Modulus      uint64                 `json:"modulus,omitempty" yaml:"modulus,omitempty"`
+	TargetLabel  string                 `json:"target_label,omitempty" yaml:"target_label,omitempty"`
+	Replacement  *string                `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	Action       string                 `json:"action,omitempty" yaml:"action,omitempty"`
+	Extra        map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// RemoteWriteConfig 远程写配置
+type RemoteWriteConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+
+	URL                 string                 `json:"url" yaml:"url"`
+	Name                string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	RemoteTimeout       string                 `json:"remote_timeout,omitempty" yaml:"remote_timeout,omitempty"`
+	Headers             map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
+	WriteRelabelConfigs []*RelabelConfig       `json:"write_relabel_configs,omitempty" yaml:"write_relabel_configs,omitempty"`
+	Extra               map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// RemoteReadConfig 远程读配置
+type RemoteReadConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+
+	URL              string                 `json:"url" yaml:"url"`
+	Name             string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	RemoteTimeout    string                 `json:"remote_timeout,omitempty" yaml:"remote_timeout,omitempty"`
+	Headers          map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
+	ReadRecent       bool                   `json:"read_recent,omitempty" yaml:"read_recent,omitempty"`
+	RequiredMatchers map[string]string      `json:"required_matchers,omitempty" yaml:"required_matchers,omitempty"`
+	Extra            map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// StorageConfig 存储配置
+type StorageConfig struct {
+	TSDB      map[string]interface{} `json:"tsdb,omitempty" yaml:"tsdb,omitempty"`
+	Exemplars map[string]interface{} `json:"exemplars,omitempty" yaml:"exemplars,omitempty"`
+	Extra     map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// ParseConfig 解析prometheus配置，解析失败时返回*ValidateError
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{}
+	err := yaml.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, &ValidateError{Problems: []string{err.Error()}}
+	}
+	return cfg, nil
+}
+
+// GetConfig 获取完整的prometheus配置
+func (c *ConfigYaml) GetConfig() (*Config, error) {
+	return ParseConfig(c.Data)
+}
+
+// GetJobConfig 获取job配置
+func (c *ConfigYaml) GetJobConfig(jobName string) (*JobConfig, error) {
+	root, err := c.root()
+	if err != nil {
+		return nil, err
+	}
+
+	job, _, err := findJobNode(root, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	jc := &JobConfig{}
+	err = job.Decode(jc)
+	if err != nil {
+		return nil, err
+	}
+
+	return jc, nil
+}
+
+// AddJobConfig 添加job配置，job已存在时替换
+func (c *ConfigYaml) AddJobConfig(jc *JobConfig) error {
+	err := jc.CheckValid()
+	if err != nil {
+		return err
+	}
+
+	node, err := ToNode(jc)
+	if err != nil {
+		return err
+	}
+
+	return c.addJobNode(jc.JobName, node)
+}
@@ -0,0 +1,169 @@
+package promConf
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseConfig_NoDataLoss(t *testing.T) {
+	for name := range roundTripCorpus {
+		data := readRoundTripFile(t, name)
+		cfg, err := ParseConfig(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		out, err := yaml.Marshal(cfg)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		// 空值(例如 targets: null)编码后被省略或变为空数组，含义相同，比较前去掉
+		if !reflect.DeepEqual(decodeWithoutEmpty(t, data), decodeWithoutEmpty(t, out)) {
+			t.Errorf("%s: data lost after parse and marshal\n%s", name, out)
+		}
+	}
+}
+
+func decodeWithoutEmpty(t *testing.T, data []byte) interface{} {
+	var v interface{}
+	err := yaml.Unmarshal(data, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return removeEmpty(v)
+}
+
+func removeEmpty(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, item := range val {
+			if item = removeEmpty(item); item != nil {
+				m[k] = item
+			}
+		}
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	case []interface{}:
+		s := []interface{}{}
+		for _, item := range val {
+			if item = removeEmpty(item); item != nil {
+				s = append(s, item)
+			}
+		}
+		if len(s) == 0 {
+			return nil
+		}
+		return s
+	}
+	return v
+}
+
+func TestConfigYaml_GetJobConfig(t *testing.T) {
+	c := NewConfigYaml(readRoundTripFile(t, "kubernetes.yml"))
+
+	jc, err := c.GetJobConfig("kubernetes-apiservers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jc.Scheme != "https" || jc.TLSConfig == nil || jc.Authorization == nil {
+		t.Errorf("http client config not parsed: %+v", jc)
+	}
+	if len(jc.RelabelConfigs) != 1 || jc.RelabelConfigs[0].Action != "keep" || len(jc.RelabelConfigs[0].SourceLabels) != 3 {
+		t.Errorf("relabel_configs not parsed: %+v", jc.RelabelConfigs)
+	}
+	names := jc.SDConfigNames()
+	if len(names) != 1 || names[0] != "kubernetes_sd_configs" {
+		t.Errorf("got %v, expected [kubernetes_sd_configs]", names)
+	}
+}
+
+func TestConfigYaml_AddJobKeepFields(t *testing.T) {
+	jobData := []byte(`job_name: secure
+scheme: https
+honor_labels: true
+params:
+  module: [http_2xx]
+basic_auth:
+  username: admin
+  password: secret
+tls_config:
+  insecure_skip_verify: true
+consul_sd_configs:
+  - server: localhost:8500
+static_configs:
+  - targets: ['localhost:9100']
+relabel_configs:
+  - source_labels: [__address__]
+    target_label: __param_target
+metric_relabel_configs:
+  - action: labeldrop
+    regex: tmp_.*
+sample_limit: 1000
+unknown_field: keep
+`)
+
+	c := NewConfigYaml([]byte("global:\n  scrape_interval: 15s\n"))
+	err := c.AddJob(jobData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := c.GetJob("secure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSameYaml(jobData, out) {
+		t.Errorf("fields lost after AddJob and GetJob\n%s", out)
+	}
+
+	// 通过类型化的配置修改后再添加，也不会丢失字段
+	jc, err := c.GetJobConfig("secure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jc.BasicAuth == nil || jc.BasicAuth.Username != "admin" || jc.Extra["unknown_field"] != "keep" {
+		t.Errorf("job config not parsed: %+v", jc)
+	}
+	jc.ScrapeInterval = "30s"
+	err = c.AddJobConfig(jc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jc2, err := c.GetJobConfig("secure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jc2.ScrapeInterval != "30s" || jc2.Extra["consul_sd_configs"] == nil || len(jc2.MetricRelabelConfigs) != 1 {
+		t.Errorf("fields lost after AddJobConfig: %+v", jc2)
+	}
+}
+
+func TestJobConfig_CheckValid(t *testing.T) {
+	testData := map[string]bool{
+		"job_name: a\nstatic_configs:\n  - targets: ['localhost:9100']": true,
+		"job_name: a\nfile_sd_configs:\n  - files: ['a.json']":          true,
+		"job_name: a\nkubernetes_sd_configs:\n  - role: node":           true,
+		"job_name: a\nscheme: http":                                     false,
+		"static_configs:\n  - targets: ['localhost:9100']":              false,
+		"job_name: a\nstatic_configs:\n  - labels: {env: prod}":         false,
+	}
+
+	for data, isValid := range testData {
+		jc := &JobConfig{}
+		err := yaml.Unmarshal([]byte(data), jc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = jc.CheckValid()
+		if (err == nil) != isValid {
+			t.Errorf("%q: got error %v, expected valid=%v", data, err, isValid)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package promConf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 行比较操作类型
+const (
+	lineEqual  = ' '
+	lineDelete = '-'
+	lineInsert = '+'
+)
+
+// lineOp 行比较结果，a为旧内容的行索引，b为新内容的行索引
+type lineOp struct {
+	kind byte
+	a    int
+	b    int
+}
+
+// diffLines 使用Myers算法比较两组行，返回把a修改为b的最短编辑操作
+func diffLines(a []string, b []string) []lineOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	trace := [][]int{}
+
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+			}
+		}
+
+		// 保存当前的v[-d..d]，用于回溯
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+
+	getV := func(d int, k int) int {
+		return trace[d][k+d]
+	}
+
+	ops := []lineOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && getV(d-1, k-1) < getV(d-1, k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := getV(d-1, prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, lineOp{kind: lineEqual, a: x - 1, b: y - 1})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, lineOp{kind: lineInsert, a: x, b: y - 1})
+			y--
+		} else {
+			ops = append(ops, lineOp{kind: lineDelete, a: x - 1, b: y})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, lineOp{kind: lineEqual, a: x - 1, b: y - 1})
+		x--
+		y--
+	}
+
+	// 反转为正序
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// UnifiedDiff 生成unified格式的差异，每个修改块前后保留3行上下文，内容相同时返回空
+func UnifiedDiff(oldName string, newName string, oldData []byte, newData []byte) string {
+	const context = 3
+
+	a, b := splitLines(string(oldData)), splitLines(string(newData))
+	ops := diffLines(a, b)
+
+	buf := &strings.Builder{}
+	prevEnd := 0
+	for i := 0; i < len(ops); {
+		// 找到下一个修改
+		for i < len(ops) && ops[i].kind == lineEqual {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := i - context
+		if start < prevEnd {
+			start = prevEnd
+		}
+
+		// 相邻修改之间的相同行不超过2*context时合并为一个修改块
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != lineEqual {
+				end++
+			}
+			equalEnd := end
+			for equalEnd < len(ops) && ops[equalEnd].kind == lineEqual {
+				equalEnd++
+			}
+			if equalEnd == len(ops) || equalEnd-end > 2*context {
+				end += context
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = equalEnd
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(buf, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		writeHunk(buf, ops[start:end], a, b)
+
+		prevEnd = end
+		i = end
+	}
+
+	return buf.String()
+}
+
+func writeHunk(buf *strings.Builder, ops []lineOp, a []string, b []string) {
+	oldStart, newStart := ops[0].a, ops[0].b
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.kind != lineInsert {
+			oldCount++
+		}
+		if op.kind != lineDelete {
+			newCount++
+		}
+	}
+
+	// 行号从1开始，修改块没有旧(新)内容时，行号为修改位置的前一行
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+
+	for _, op := range ops {
+		switch op.kind {
+		case lineEqual:
+			buf.WriteString(" " + a[op.a] + "\n")
+		case lineDelete:
+			buf.WriteString("-" + a[op.a] + "\n")
+		case lineInsert:
+			buf.WriteString("+" + b[op.b] + "\n")
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package promConf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	cases := [][2]string{
+		{"", ""},
+		{"a b c", "a b c"},
+		{"", "a b"},
+		{"a b", ""},
+		{"a b c d", "a c d e"},
+		{"a b c a b b a", "c b a b a c"},
+	}
+
+	for _, c := range cases {
+		a, b := strings.Fields(c[0]), strings.Fields(c[1])
+		ops := diffLines(a, b)
+
+		// 根据编辑操作从a重建b
+		rebuilt := []string{}
+		for _, op := range ops {
+			switch op.kind {
+			case lineEqual:
+				if a[op.a] != b[op.b] {
+					t.Errorf("%v: equal line mismatch %s != %s", c, a[op.a], b[op.b])
+				}
+				rebuilt = append(rebuilt, a[op.a])
+			case lineInsert:
+				rebuilt = append(rebuilt, b[op.b])
+			}
+		}
+		if strings.Join(rebuilt, " ") != strings.Join(b, " ") {
+			t.Errorf("%v: rebuilt %v", c, rebuilt)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	oldData := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	newData := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	expected := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	got := UnifiedDiff("old", "new", []byte(oldData), []byte(newData))
+	if got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+
+	// 相距较近的修改合并为一个修改块
+	got = UnifiedDiff("old", "new", []byte("a\nb\nc\nd\ne\n"), []byte("A\nb\nc\nd\nE\n"))
+	if strings.Count(got, "@@ -") != 1 || !strings.Contains(got, "@@ -1,5 +1,5 @@") {
+		t.Errorf("got\n%s", got)
+	}
+
+	got = UnifiedDiff("old", "new", nil, []byte("a\nb\n"))
+	if !strings.Contains(got, "@@ -0,0 +1,2 @@") {
+		t.Errorf("got\n%s", got)
+	}
+
+	if got = UnifiedDiff("old", "new", []byte("a\n"), []byte("a\n")); got != "" {
+		t.Errorf("expected empty diff, got\n%s", got)
+	}
+}
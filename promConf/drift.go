@@ -0,0 +1,491 @@
+package promConf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// --------------------------------- 配置文件与运行中的prometheus的差异 ---------------------------------
+
+// job的差异类型，表示reload后prometheus会发生的变化
+const (
+	DriftAdded   = "added"   // job只在配置文件中，还没有加载
+	DriftChanged = "changed" // job的target、标签或抓取设置不同
+	DriftDeleted = "deleted" // job已从配置文件删除，prometheus仍在抓取
+)
+
+// prometheus加载配置时隐藏的密码、token等字段的值
+const secretValue = "<secret>"
+
+// JobDrift 一个job在配置文件与运行中的prometheus之间的差异
+type JobDrift struct {
+	Job     string   `json:"job"`
+	Status  string   `json:"status"`
+	Details []string `json:"details,omitempty"` // 差异的内容，例如 ~ scrape_interval: 15s -> 30s
+}
+
+// DriftReport 所有job的差异，没有差异的job不包括在内
+type DriftReport struct {
+	Jobs []*JobDrift `json:"jobs"`
+}
+
+// IsEmpty 是否没有差异
+func (r *DriftReport) IsEmpty() bool {
+	return len(r.Jobs) == 0
+}
+
+// Count 差异类型为status的job数量
+func (r *DriftReport) Count(status string) int {
+	n := 0
+	for _, job := range r.Jobs {
+		if job.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// GetLoadedConfig 从prometheus的/api/v1/status/config接口获取已加载的配置，promURL为prometheus的地址，
+// 例如 http://127.0.0.1:9090，也可以是reload接口的地址
+func GetLoadedConfig(promURL string, options *ClientOptions) ([]byte, error) {
+	err := checkURL(promURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newPromClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := PromBaseURL(promURL) + "/api/v1/status/config"
+	data, err := client.do(http.MethodGet, apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			YAML string `json:"yaml"`
+		} `json:"data"`
+	}{}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %v", apiURL, err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("GET %s: status is %s, %s", apiURL, resp.Status, resp.Error)
+	}
+
+	return []byte(resp.Data.YAML), nil
+}
+
+// Drift 比较配置文件与prometheus已加载的配置loaded中的job，包括target、标签和抓取设置，
+// 配置文件中省略的字段使用prometheus的默认值，时间按数值比较，隐藏的密码不比较
+func (c *ConfigYaml) Drift(loaded []byte) (*DriftReport, error) {
+	fileJobs, fileOrder, err := driftJobs(c.Data)
+	if err != nil {
+		return nil, err
+	}
+	loadedJobs, loadedOrder, err := driftJobs(loaded)
+	if err != nil {
+		return nil, fmt.Errorf("loaded configuration of prometheus: %v", err)
+	}
+
+	global, err := c.scrapeDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DriftReport{}
+	for _, name := range fileOrder {
+		fileJob := fileJobs[name]
+		loadedJob, ok := loadedJobs[name]
+		if !ok {
+			report.Jobs = append(report.Jobs, &JobDrift{
+				Job:     name,
+				Status:  DriftAdded,
+				Details: diffGroups(nil, fileJob.groups),
+			})
+			continue
+		}
+
+		fileFields := normalizeJobFields(fileJob.fields, global)
+		loadedFields := normalizeJobFields(loadedJob.fields, global)
+		// 新版本prometheus增加的字段，配置文件中没有时不比较
+		for _, key := range generatedJobFields {
+			if _, ok := fileFields[key]; !ok {
+				delete(loadedFields, key)
+			}
+		}
+
+		details := diffValues("", loadedFields, fileFields)
+		details = append(details, diffGroups(loadedJob.groups, fileJob.groups)...)
+		if len(details) > 0 {
+			report.Jobs = append(report.Jobs, &JobDrift{Job: name, Status: DriftChanged, Details: details})
+		}
+	}
+
+	for _, name := range loadedOrder {
+		if _, ok := fileJobs[name]; !ok {
+			report.Jobs = append(report.Jobs, &JobDrift{
+				Job:     name,
+				Status:  DriftDeleted,
+				Details: diffGroups(loadedJobs[name].groups, nil),
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// 比较用的job，fields为除static_configs外的字段
+type driftJob struct {
+	fields map[string]interface{}
+	groups []StaticConfigs
+}
+
+// 解析配置中的所有job，返回job和job名称的顺序
+func driftJobs(data []byte) (map[string]*driftJob, []string, error) {
+	root, err := NewConfigYaml(data).root()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jobs := map[string]*driftJob{}
+	order := []string{}
+	seq := MappingValue(root, "scrape_configs")
+	if seq == nil {
+		return jobs, order, nil
+	}
+	for _, item := range seq.Content {
+		fields := map[string]interface{}{}
+		err = resolveAlias(item).Decode(&fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		name, _ := fields["job_name"].(string)
+		if name == "" || jobs[name] != nil {
+			continue
+		}
+
+		groups, err := decodeGroups(MappingValue(item, "static_configs"))
+		if err != nil {
+			return nil, nil, err
+		}
+		delete(fields, "static_configs")
+		jobs[name] = &driftJob{fields: fields, groups: groups}
+		order = append(order, name)
+	}
+
+	return jobs, order, nil
+}
+
+// job继承的全局抓取设置，配置文件中没有设置时使用prometheus的默认值
+type scrapeDefaults struct {
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func (c *ConfigYaml) scrapeDefaults() (*scrapeDefaults, error) {
+	cfg, err := c.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &scrapeDefaults{interval: time.Minute, timeout: 10 * time.Second}
+	if cfg.Global == nil {
+		return d, nil
+	}
+	if cfg.Global.ScrapeInterval != "" {
+		if d.interval, err = ParseDuration(cfg.Global.ScrapeInterval); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Global.ScrapeTimeout != "" {
+		if d.timeout, err = ParseDuration(cfg.Global.ScrapeTimeout); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// prometheus加载配置后补充的job字段的默认值
+var jobFieldDefaults = map[string]interface{}{
+	"honor_labels":               false,
+	"honor_timestamps":           true,
+	"metrics_path":               "/metrics",
+	"scheme":                     "http",
+	"follow_redirects":           true,
+	"enable_http2":               true,
+	"enable_compression":         true,
+	"track_timestamps_staleness": false,
+}
+
+// 新版本prometheus补充的job字段，默认值随版本变化
+var generatedJobFields = []string{
+	"scrape_protocols",
+	"metric_name_validation_scheme",
+	"metric_name_escaping_scheme",
+	"always_scrape_classic_histograms",
+	"convert_classic_histograms_to_nhcb",
+}
+
+// 服务发现配置的默认值
+var sdConfigDefaults = map[string]map[string]interface{}{
+	"file_sd_configs": {"refresh_interval": 5 * time.Minute},
+	"dns_sd_configs":  {"refresh_interval": 30 * time.Second, "type": "SRV"},
+}
+
+// relabel规则的默认值
+var relabelDefaults = map[string]interface{}{
+	"separator":   ";",
+	"regex":       "(.*)",
+	"replacement": "$1",
+	"action":      "replace",
+}
+
+// 值为时间的字段
+var durationFields = []string{"scrape_interval", "scrape_timeout", "refresh_interval"}
+
+// 补充job字段的默认值，时间转为time.Duration，返回新的map
+func normalizeJobFields(fields map[string]interface{}, global *scrapeDefaults) map[string]interface{} {
+	out := normalizeValue("", fields).(map[string]interface{})
+	for key, value := range jobFieldDefaults {
+		if _, ok := out[key]; !ok {
+			out[key] = value
+		}
+	}
+
+	// 没有设置scrape_timeout时使用全局值，但不超过scrape_interval
+	interval, ok := out["scrape_interval"].(time.Duration)
+	if _, exist := out["scrape_interval"]; !exist {
+		interval, ok = global.interval, true
+		out["scrape_interval"] = interval
+	}
+	if _, exist := out["scrape_timeout"]; !exist {
+		timeout := global.timeout
+		if ok && timeout > interval {
+			timeout = interval
+		}
+		out["scrape_timeout"] = timeout
+	}
+
+	for key, defaults := range sdConfigDefaults {
+		fillListDefaults(out[key], defaults)
+	}
+	for _, key := range []string{"relabel_configs", "metric_relabel_configs"} {
+		fillListDefaults(out[key], relabelDefaults)
+		if items, ok := out[key].([]interface{}); ok {
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					if action, ok := m["action"].(string); ok {
+						m["action"] = strings.ToLower(action)
+					}
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// 补充列表中每个对象的默认值
+func fillListDefaults(list interface{}, defaults map[string]interface{}) {
+	items, ok := list.([]interface{})
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range defaults {
+			if _, ok := m[key]; !ok {
+				m[key] = value
+			}
+		}
+	}
+}
+
+// 复制值，时间字段转为time.Duration，解析失败时保持原值
+func normalizeValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, item := range v {
+			out[k] = normalizeValue(k, item)
+		}
+		return out
+	case []interface{}:
+		out := []interface{}{}
+		for _, item := range v {
+			out = append(out, normalizeValue("", item))
+		}
+		return out
+	case string:
+		if isContainString(durationFields, key) {
+			if d, err := ParseDuration(v); err == nil {
+				return d
+			}
+		}
+	}
+	return value
+}
+
+// 比较已加载的值和配置文件的值，返回差异的内容，+表示只在配置文件中，-表示只在已加载的配置中，
+// ~表示值不同，已加载的值为<secret>时不比较
+func diffValues(path string, loaded interface{}, file interface{}) []string {
+	if s, ok := loaded.(string); ok && s == secretValue {
+		return nil
+	}
+
+	loadedMap, ok1 := loaded.(map[string]interface{})
+	fileMap, ok2 := file.(map[string]interface{})
+	if ok1 && ok2 {
+		keys := []string{}
+		for key := range loadedMap {
+			keys = append(keys, key)
+		}
+		for key := range fileMap {
+			if _, ok := loadedMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		details := []string{}
+		for _, key := range keys {
+			loadedValue, inLoaded := loadedMap[key]
+			fileValue, inFile := fileMap[key]
+			switch {
+			case !inLoaded:
+				details = append(details, fmt.Sprintf("+ %s: %s", joinPath(path, key), formatValue(fileValue)))
+			case !inFile && isZeroValue(loadedValue):
+				// prometheus输出的零值字段，例如 tls_config: {insecure_skip_verify: false}
+				continue
+			case !inFile:
+				details = append(details, fmt.Sprintf("- %s: %s", joinPath(path, key), formatValue(loadedValue)))
+			default:
+				details = append(details, diffValues(joinPath(path, key), loadedValue, fileValue)...)
+			}
+		}
+		return details
+	}
+
+	loadedList, ok1 := loaded.([]interface{})
+	fileList, ok2 := file.([]interface{})
+	if ok1 && ok2 && len(loadedList) == len(fileList) {
+		details := []string{}
+		for i := range loadedList {
+			details = append(details, diffValues(fmt.Sprintf("%s[%d]", path, i), loadedList[i], fileList[i])...)
+		}
+		return details
+	}
+
+	if reflect.DeepEqual(loaded, file) {
+		return nil
+	}
+	return []string{fmt.Sprintf("~ %s: %s -> %s", path, formatValue(loaded), formatValue(file))}
+}
+
+// 是否为零值，对象的所有字段都是零值时也是零值
+func isZeroValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		for _, item := range v {
+			if !isZeroValue(item) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(v) == 0
+	}
+	return reflect.ValueOf(value).IsZero()
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// 值的单行格式，对象和列表使用yaml的flow风格
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Duration:
+		return formatDuration(v)
+	case map[string]interface{}, []interface{}:
+		node := &yaml.Node{}
+		if err := node.Encode(formatDurations(v)); err != nil {
+			return fmt.Sprint(v)
+		}
+		node.Style = yaml.FlowStyle
+		for _, child := range node.Content {
+			child.Style = yaml.FlowStyle
+		}
+		out, err := yaml.Marshal(node)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return fmt.Sprint(value)
+}
+
+// 把值中的time.Duration转为prometheus格式的字符串
+func formatDurations(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Duration:
+		return formatDuration(v)
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, item := range v {
+			out[k] = formatDurations(item)
+		}
+		return out
+	case []interface{}:
+		out := []interface{}{}
+		for _, item := range v {
+			out = append(out, formatDurations(item))
+		}
+		return out
+	}
+	return value
+}
+
+// prometheus格式的时间，例如 1m30s、1h、500ms
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{7 * 24 * time.Hour, "w"}, {24 * time.Hour, "d"}, {time.Hour, "h"},
+		{time.Minute, "m"}, {time.Second, "s"}, {time.Millisecond, "ms"},
+	}
+	s := ""
+	for _, u := range units {
+		if n := d / u.unit; n > 0 {
+			s += fmt.Sprintf("%d%s", n, u.name)
+			d -= n * u.unit
+		}
+	}
+	return s
+}
@@ -0,0 +1,208 @@
+package promConf
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const driftFile = `global:
+  scrape_interval: 15s
+
+scrape_configs:
+  - job_name: prometheus
+    static_configs:
+      - targets: ['localhost:9090']
+
+  - job_name: node_exporter
+    scrape_interval: 1m
+    basic_auth:
+      username: admin
+      password: 123456
+    file_sd_configs:
+      - files: ['node.json']
+    relabel_configs:
+      - source_labels: [__address__]
+        target_label: instance
+    static_configs:
+      - targets: ['10.0.0.1:9100', '10.0.0.2:9100']
+        labels:
+          env: prod
+
+  - job_name: mysqld_exporter
+    static_configs:
+      - targets: ['10.0.0.1:9104']
+`
+
+// prometheus加载后输出的配置，补充了默认值，隐藏了密码
+const driftLoaded = `global:
+  scrape_interval: 15s
+  scrape_timeout: 10s
+  evaluation_interval: 1m
+scrape_configs:
+- job_name: prometheus
+  honor_timestamps: true
+  track_timestamps_staleness: false
+  scrape_interval: 15s
+  scrape_timeout: 10s
+  scrape_protocols:
+  - OpenMetricsText1.0.0
+  - PrometheusText0.0.4
+  metrics_path: /metrics
+  scheme: http
+  enable_compression: true
+  follow_redirects: true
+  enable_http2: true
+  static_configs:
+  - targets:
+    - localhost:9090
+- job_name: node_exporter
+  honor_timestamps: true
+  scrape_interval: 30s
+  scrape_timeout: 10s
+  metrics_path: /metrics
+  scheme: http
+  basic_auth:
+    username: admin
+    password: <secret>
+  tls_config:
+    insecure_skip_verify: false
+  follow_redirects: true
+  enable_http2: true
+  relabel_configs:
+  - source_labels: [__address__]
+    separator: ;
+    regex: (.*)
+    target_label: instance
+    replacement: $1
+    action: replace
+  file_sd_configs:
+  - files:
+    - node.json
+    refresh_interval: 5m
+  static_configs:
+  - targets:
+    - 10.0.0.1:9100
+    labels:
+      env: prod
+  - targets:
+    - 10.0.0.3:9100
+- job_name: blackbox
+  honor_timestamps: true
+  scrape_interval: 15s
+  scrape_timeout: 10s
+  metrics_path: /probe
+  scheme: http
+  static_configs:
+  - targets:
+    - https://example.com
+`
+
+func TestDrift(t *testing.T) {
+	cy := NewConfigYaml([]byte(driftFile))
+	report, err := cy.Drift([]byte(driftLoaded))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*JobDrift{
+		{Job: "node_exporter", Status: DriftChanged, Details: []string{
+			"~ scrape_interval: 30s -> 1m",
+			"+ 10.0.0.2:9100 {env=prod}",
+			"- 10.0.0.3:9100 {}",
+		}},
+		{Job: "mysqld_exporter", Status: DriftAdded, Details: []string{"+ 10.0.0.1:9104 {}"}},
+		{Job: "blackbox", Status: DriftDeleted, Details: []string{"- https://example.com {}"}},
+	}
+	if !reflect.DeepEqual(report.Jobs, expected) {
+		for _, job := range report.Jobs {
+			t.Logf("%+v", job)
+		}
+		t.Fatalf("unexpected drift report")
+	}
+	if report.IsEmpty() || report.Count(DriftChanged) != 1 || report.Count(DriftDeleted) != 1 {
+		t.Errorf("unexpected count")
+	}
+
+	// 没有差异
+	report, err = NewConfigYaml([]byte(driftFile)).Drift([]byte(driftFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.IsEmpty() {
+		t.Errorf("expected no drift, got %+v", report.Jobs[0])
+	}
+}
+
+func TestDrift_Settings(t *testing.T) {
+	file := `scrape_configs:
+  - job_name: node
+    scrape_timeout: 5s
+    metrics_path: /node/metrics
+    params:
+      module: [http_2xx]
+    static_configs:
+      - targets: ['10.0.0.1:9100']
+`
+	loaded := `scrape_configs:
+- job_name: node
+  scrape_interval: 1m
+  scrape_timeout: 5000ms
+  metrics_path: /metrics
+  params:
+    module: [tcp_connect]
+  honor_labels: true
+  static_configs:
+  - targets: ['10.0.0.1:9100']
+`
+
+	report, err := NewConfigYaml([]byte(file)).Drift([]byte(loaded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Jobs) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(report.Jobs))
+	}
+	expected := []string{
+		"~ honor_labels: true -> false",
+		"~ metrics_path: /metrics -> /node/metrics",
+		"~ params.module[0]: tcp_connect -> http_2xx",
+	}
+	if !reflect.DeepEqual(report.Jobs[0].Details, expected) {
+		t.Errorf("got %q, expected %q", report.Jobs[0].Details, expected)
+	}
+}
+
+func TestGetLoadedConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/status/config" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"yaml":%s}}`, strconv.Quote(driftLoaded))
+	}))
+	defer server.Close()
+
+	data, err := GetLoadedConfig(server.URL+"/-/reload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != driftLoaded {
+		t.Errorf("unexpected loaded configuration:\n%s", data)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for s, expected := range map[string]string{"90s": "1m30s", "1h": "1h", "500ms": "500ms", "0s": "0s", "8d": "1w1d"} {
+		d, err := ParseDuration(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatDuration(d); got != expected {
+			t.Errorf("formatDuration(%s) = %s, expected %s", s, got, expected)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package promConf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 错误类型，使用errors.Is判断，例如 errors.Is(err, promConf.ErrJobNotFound)
+var (
+	// ErrJobNotFound job不存在
+	ErrJobNotFound = errors.New("job not found")
+	// ErrTargetsNotFound target或target所在的static_configs分组不存在，规则分组或规则不存在
+	ErrTargetsNotFound = errors.New("targets not found")
+	// ErrLabelsNotFound 标签所在的static_configs分组不存在
+	ErrLabelsNotFound = errors.New("labels not found")
+	// ErrDuplicateJob job名称已存在
+	ErrDuplicateJob = errors.New("duplicate job")
+	// ErrInvalidConfig 配置文件或规则文件解析、校验失败
+	ErrInvalidConfig = errors.New("invalid configuration")
+	// ErrReloadFailed prometheus reload失败或无法确认配置已生效
+	ErrReloadFailed = errors.New("reload failed")
+	// ErrTargetsUnhealthy target在prometheus中不存在或状态不是up
+	ErrTargetsUnhealthy = errors.New("targets unhealthy")
+)
+
+// Error 操作配置文件的错误，包含job名称和yaml路径，使用errors.As获取，
+// Err为ErrJobNotFound等错误类型
+type Error struct {
+	Err  error  // 错误类型
+	Job  string // job名称，与job无关时为空
+	Path string // 出错位置的yaml路径，例如 scrape_configs[job=node_exporter].static_configs
+	Msg  string // 错误信息
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind error, jobName string, path string, format string, args ...interface{}) *Error {
+	return &Error{Err: kind, Job: jobName, Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// job在配置文件中的yaml路径
+func jobPath(jobName string) string {
+	return fmt.Sprintf("scrape_configs[job=%s]", jobName)
+}
+
+// 补充分组错误的job名称和路径，分组的操作不知道所属的job
+func withJob(err error, jobName string) error {
+	e := &Error{}
+	if errors.As(err, &e) && e.Job == "" {
+		e.Job = jobName
+		e.Path = jobPath(jobName) + "." + e.Path
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package promConf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	c := NewConfigYaml(reorganizeData)
+
+	_, err := c.GetJobTargets("missing")
+	if !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+	e := &Error{}
+	if !errors.As(err, &e) || e.Job != "missing" || e.Path != "scrape_configs" {
+		t.Errorf("unexpected error detail %+v", e)
+	}
+
+	_, err = c.GetJobGroupTargets("node", &GroupSelector{Index: 5})
+	if !errors.Is(err, ErrTargetsNotFound) {
+		t.Fatalf("expected ErrTargetsNotFound, got %v", err)
+	}
+	if !errors.As(err, &e) || e.Job != "node" || e.Path != "scrape_configs[job=node].static_configs" {
+		t.Errorf("unexpected error detail %+v", e)
+	}
+
+	_, err = c.GetJobGroupLabels("node", &GroupSelector{Index: -1, Labels: map[string]string{"env": "dev"}})
+	if !errors.Is(err, ErrLabelsNotFound) {
+		t.Errorf("expected ErrLabelsNotFound, got %v", err)
+	}
+
+	err = c.RenameJob("node", "mysqld")
+	if !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("expected ErrDuplicateJob, got %v", err)
+	}
+
+	err = Validate([]byte("scrape_configs: [\n"))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig, got %v", err)
+	}
+	err = NewConfigYaml([]byte("- a\n")).DelJobTargets("node", []string{"10.0.0.1:9100"})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig, got %v", err)
+	}
+}
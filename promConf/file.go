@@ -0,0 +1,136 @@
+package promConf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLockTimeout 等待文件锁超时
+var ErrLockTimeout = errors.New("timeout waiting for file lock")
+
+// FileLock 文件的排它锁(advisory lock)，用于多个进程同时修改同一个文件
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile 获取文件的排它锁，锁文件为同级目录下的 文件名.lock，释放锁时删除，
+// 其他进程持有锁时等待，超过timeout返回ErrLockTimeout
+func LockFile(file string, timeout time.Duration) (*FileLock, error) {
+	lockFile := file + ".lock"
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0666)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			// 等待期间锁文件可能被持有锁的进程删除，锁住的是已删除的文件时重新获取
+			if isSameFile(f, lockFile) {
+				return &FileLock{file: f}, nil
+			}
+			unlock(f)
+			f.Close()
+			continue
+		}
+		f.Close()
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w %s, it is held by another process", ErrLockTimeout, lockFile)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// 打开的文件是否为路径对应的文件
+func isSameFile(f *os.File, file string) bool {
+	info1, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	info2, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info1, info2)
+}
+
+// Unlock 删除锁文件并释放文件锁
+func (l *FileLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	// 持有锁时删除，等待锁的进程获取锁后发现文件已删除会重新获取
+	os.Remove(l.file.Name())
+	err := unlock(l.file)
+	closeErr := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// WriteFileWithBackup 文件已存在时先备份到同级目录bak下，不存在时创建目录，再原子写入文件，
+// 供prometheus配置文件之外的文件使用，例如告警规则文件，policy为备份保留策略
+func WriteFileWithBackup(file string, data []byte, policy BackupPolicy) error {
+	if _, err := os.Stat(file); err == nil {
+		err = backupFile(file, policy)
+		if err != nil {
+			return err
+		}
+	} else {
+		os.MkdirAll(filepath.Dir(file), 0777)
+	}
+
+	return writeFileAtomic(file, data)
+}
+
+// writeFileAtomic 先写入同级目录下的临时文件，再重命名为目标文件，
+// 写入过程中程序异常退出时，不会留下不完整的文件
+func writeFileAtomic(file string, data []byte) error {
+	// 文件是软链接时，替换链接指向的文件
+	if realFile, err := filepath.EvalSymlinks(file); err == nil {
+		file = realFile
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(file); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpFile := tmp.Name()
+	defer os.Remove(tmpFile) // 重命名成功后临时文件已不存在
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmpFile, perm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, file)
+}
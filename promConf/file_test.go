@@ -0,0 +1,71 @@
+package promConf
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "prometheus.yml")
+
+	lock, err := LockFile(file, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runtime.GOOS != "windows" {
+		_, err = LockFile(file, 100*time.Millisecond)
+		if !errors.Is(err, ErrLockTimeout) {
+			t.Errorf("got %v, expected ErrLockTimeout", err)
+		}
+	}
+
+	err = lock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 释放锁后删除锁文件
+	if _, err = os.Stat(file + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file should be removed, %v", err)
+	}
+
+	lock, err = LockFile(file, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("lock after unlock: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prometheus.yml")
+
+	err := ioutil.WriteFile(file, []byte("old"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeFileAtomic(file, []byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(file)
+	if string(data) != "new" {
+		t.Errorf("got %q, expected new", data)
+	}
+	if info, _ := os.Stat(file); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("got mode %v, expected 0600", info.Mode().Perm())
+	}
+
+	// 不留下临时文件
+	infos, _ := ioutil.ReadDir(dir)
+	if len(infos) != 1 {
+		t.Errorf("got %d files in dir, expected 1", len(infos))
+	}
+}
@@ -0,0 +1,286 @@
+package promConf
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+)
+
+// FileSD file_sd_configs引用的target文件，支持json和yaml格式，
+// prometheus会自动重新读取文件，修改后不需要reload
+type FileSD struct {
+	Data   []byte // 文件内容
+	isJSON bool
+}
+
+// NewFileSD 实例化，文件后缀为.json时使用json格式，否则使用yaml格式
+func NewFileSD(file string, data []byte) *FileSD {
+	return &FileSD{
+		Data:   data,
+		isJSON: strings.ToLower(filepath.Ext(file)) == ".json",
+	}
+}
+
+// Persistent 校验后持久化，文件已存在时先备份，policy为备份保留策略
+func (f *FileSD) Persistent(file string, policy BackupPolicy) error {
+	err := f.Validate()
+	if err != nil {
+		return err
+	}
+
+	return WriteFileWithBackup(file, f.Data, policy)
+}
+
+// GetGroups 获取文件中所有分组
+func (f *FileSD) GetGroups() ([]StaticConfigs, error) {
+	groups := []StaticConfigs{}
+	if len(bytes.TrimSpace(f.Data)) == 0 {
+		return groups, nil
+	}
+
+	var err error
+	if f.isJSON {
+		err = jsoniter.Unmarshal(f.Data, &groups)
+	} else {
+		err = yaml.Unmarshal(f.Data, &groups)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// ReplaceGroups 替换文件中所有分组
+func (f *FileSD) ReplaceGroups(groups []StaticConfigs) error {
+	for i := range groups {
+		if groups[i].Targets == nil {
+			groups[i].Targets = []string{}
+		}
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if f.isJSON {
+		data, err = jsoniter.MarshalIndent(groups, "", "  ")
+		data = append(data, '\n')
+	} else {
+		buf := &bytes.Buffer{}
+		encoder := yaml.NewEncoder(buf)
+		encoder.SetIndent(2)
+		err = encoder.Encode(groups)
+		data = buf.Bytes()
+	}
+	if err != nil {
+		return err
+	}
+
+	f.Data = data
+	return nil
+}
+
+// Validate 校验所有分组的target和标签名称，target可能被job的relabel改写，只检查是否为空
+func (f *FileSD) Validate() error {
+	groups, err := f.GetGroups()
+	if err != nil {
+		return &ValidateError{Problems: []string{err.Error()}}
+	}
+
+	v := &validator{}
+	for i, group := range groups {
+		path := fmt.Sprintf("[%d]", i)
+		for _, target := range group.Targets {
+			if target == "" {
+				v.addf(path+".targets", "target is empty")
+			}
+		}
+		v.checkLabels(path+".labels", group.Labels)
+	}
+
+	return v.err()
+}
+
+func (f *FileSD) updateGroups(fn func([]StaticConfigs) ([]StaticConfigs, error)) error {
+	groups, err := f.GetGroups()
+	if err != nil {
+		return err
+	}
+
+	groups, err = fn(groups)
+	if err != nil {
+		return err
+	}
+
+	return f.ReplaceGroups(groups)
+}
+
+// GetTargets 获取所有分组的target
+func (f *FileSD) GetTargets() ([]string, error) {
+	return f.GetGroupTargets(nil)
+}
+
+// AddTargets 添加新的target到第一个分组
+func (f *FileSD) AddTargets(addTargets []string) error {
+	return f.AddGroupTargets(nil, addTargets)
+}
+
+// DelTargets 从所有分组删除已存在的target
+func (f *FileSD) DelTargets(delTargets []string) error {
+	return f.DelGroupTargets(nil, delTargets)
+}
+
+// ReplaceTargets 修改第一个分组的target，注：直接替换所有旧值
+func (f *FileSD) ReplaceTargets(newTargets []string) error {
+	return f.ReplaceGroupTargets(nil, newTargets)
+}
+
+// GetGroupTargets 获取选择器匹配分组的target，sel为nil时获取所有分组
+func (f *FileSD) GetGroupTargets(sel *GroupSelector) ([]string, error) {
+	groups, err := f.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	return getGroupTargets(groups, sel)
+}
+
+// AddGroupTargets 添加新的target到选择器匹配的分组，sel为nil时添加到第一个分组，
+// 标签选择器没有匹配分组时，新建分组
+func (f *FileSD) AddGroupTargets(sel *GroupSelector, addTargets []string) error {
+	return f.updateGroups(func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return addGroupTargets(groups, sel, addTargets)
+	})
+}
+
+// DelGroupTargets 从选择器匹配的分组删除target，sel为nil时从所有分组删除
+func (f *FileSD) DelGroupTargets(sel *GroupSelector, delTargets []string) error {
+	return f.updateGroups(func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return delGroupTargets(groups, sel, delTargets)
+	})
+}
+
+// ReplaceGroupTargets 替换选择器匹配分组的target，sel为nil时替换第一个分组，
+// 标签选择器没有匹配分组时，新建分组
+func (f *FileSD) ReplaceGroupTargets(sel *GroupSelector, newTargets []string) error {
+	return f.updateGroups(func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return replaceGroupTargets(groups, sel, newTargets)
+	})
+}
+
+// GetTargetsWithLabels 获取所有target及其生效的标签
+func (f *FileSD) GetTargetsWithLabels(jobName string) ([]*Target, error) {
+	groups, err := f.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	return groupTargetsWithLabels(jobName, groups), nil
+}
+
+// GetGroupTargetsWithLabels 获取选择器匹配分组的target及其生效的标签，sel为nil时获取所有分组
+func (f *FileSD) GetGroupTargetsWithLabels(jobName string, sel *GroupSelector) ([]*Target, error) {
+	groups, err := f.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	return selectGroupTargetsWithLabels(jobName, groups, sel)
+}
+
+// AddTargetsWithLabels 添加target到标签完全相同的分组，没有则新建分组
+func (f *FileSD) AddTargetsWithLabels(addTargets []string, labels map[string]string) error {
+	return f.updateGroups(func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return addTargetsWithLabels(groups, addTargets, labels), nil
+	})
+}
+
+// --------------------------------- job file_sd_configs ---------------------------------
+
+// GetJobFileSDFiles 获取job的file_sd_configs引用的所有文件
+func (c *ConfigYaml) GetJobFileSDFiles(jobName string) ([]string, error) {
+	root, err := c.root()
+	if err != nil {
+		return nil, err
+	}
+
+	job, _, err := findJobNode(root, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	seq := MappingValue(job, "file_sd_configs")
+	if seq == nil {
+		// job没有file_sd_configs字段
+		return files, nil
+	}
+
+	sdConfigs := []FileSDConfig{}
+	err = seq.Decode(&sdConfigs)
+	if err != nil {
+		return nil, err
+	}
+	for _, sdConfig := range sdConfigs {
+		files = append(files, sdConfig.Files...)
+	}
+
+	return files, nil
+}
+
+// ConvertJobToFileSD 把job的static_configs转移到file_sd_configs，sdFile为job引用的文件路径，
+// 返回原static_configs的所有分组，需要调用者写入到sdFile文件
+func (c *ConfigYaml) ConvertJobToFileSD(jobName string, sdFile string) ([]StaticConfigs, error) {
+	groups, err := c.GetJobGroups(jobName)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("job '%s' has no static_configs", jobName)
+	}
+
+	sdNode, err := ToNode(&FileSDConfig{Files: []string{sdFile}})
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.edit(func(root *yaml.Node) error {
+		job, _, err := findJobNode(root, jobName)
+		if err != nil {
+			return err
+		}
+
+		seq := editableValue(job, "file_sd_configs")
+		if seq != nil && seq.Kind == yaml.SequenceNode {
+			seq.Content = append(seq.Content, sdNode)
+			DeleteMappingKey(job, "static_configs")
+			return nil
+		}
+
+		// 在static_configs的位置替换为file_sd_configs
+		seq = newSequenceNode()
+		seq.Content = append(seq.Content, sdNode)
+		index := MappingKeyIndex(job, "static_configs")
+		job.Content[index].Value = "file_sd_configs"
+		job.Content[index+1] = seq
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		groups[i].node = nil
+	}
+	return groups, nil
+}
+
+// IsGlobPath 判断文件路径是否包含通配符
+func IsGlobPath(file string) bool {
+	return strings.ContainsAny(file, "*?[")
+}
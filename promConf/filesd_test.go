@@ -0,0 +1,102 @@
+package promConf
+
+import (
+	"fmt"
+	"testing"
+)
+
+var fileSDData = []byte(`[
+  {
+    "targets": ["10.0.0.90:9100", "10.0.0.91:9100"],
+    "labels": {"dc": "sh"}
+  },
+  {
+    "targets": ["10.1.0.90:9100"],
+    "labels": {"dc": "bj"}
+  }
+]`)
+
+func TestFileSD_Targets(t *testing.T) {
+	fsd := NewFileSD("node_exporter.json", fileSDData)
+
+	err := fsd.AddTargets([]string{"127.0.0.1:9100"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = fsd.DelTargets([]string{"10.1.0.90:9100"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	sel, _ := ParseGroupSelector("dc=sh")
+	targets, err := fsd.GetGroupTargets(sel)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(targets) != 3 {
+		t.Errorf("got targets %v, want 3 targets", targets)
+	}
+
+	err = fsd.ReplaceGroupTargets(sel, []string{"10.0.0.92:9100"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	targets, _ = fsd.GetTargets()
+	if len(targets) != 1 || targets[0] != "10.0.0.92:9100" {
+		t.Errorf("got targets %v, want [10.0.0.92:9100]", targets)
+	}
+
+	fmt.Println(string(fsd.Data))
+}
+
+func TestFileSD_Yaml(t *testing.T) {
+	fsd := NewFileSD("node_exporter.yml", nil)
+	err := fsd.AddTargetsWithLabels([]string{"127.0.0.1:9100"}, map[string]string{"env": "dev"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	groups, err := NewFileSD("node_exporter.yml", fsd.Data).GetGroups()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(groups) != 1 || groups[0].Labels["env"] != "dev" {
+		t.Errorf("unexpected groups %v", groups)
+	}
+
+	fmt.Println(string(fsd.Data))
+}
+
+func TestConfigYaml_ConvertJobToFileSD(t *testing.T) {
+	pConfig := NewConfigYaml(data)
+	groups, err := pConfig.ConvertJobToFileSD("node_exporter", "targets.d/node_exporter.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(groups) != 1 || len(groups[0].Targets) != 3 {
+		t.Errorf("unexpected groups %v", groups)
+	}
+
+	files, err := pConfig.GetJobFileSDFiles("node_exporter")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(files) != 1 || files[0] != "targets.d/node_exporter.json" {
+		t.Errorf("got files %v", files)
+	}
+
+	groups, _ = pConfig.GetJobGroups("node_exporter")
+	if len(groups) != 0 {
+		t.Errorf("static_configs should be removed, got %v", groups)
+	}
+
+	fmt.Println(string(pConfig.Data))
+}
@@ -0,0 +1,296 @@
+package promConf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GroupSelector static_configs分组选择器，按索引或标签选择分组
+type GroupSelector struct {
+	Index  int               // 分组索引，小于0表示按标签选择
+	Labels map[string]string // 分组标签包含所有kv时匹配
+}
+
+// ParseGroupSelector 解析分组选择器，支持索引(如 1)或标签(如 dc=sh,env=prod)
+func ParseGroupSelector(s string) (*GroupSelector, error) {
+	s = strings.Trim(s, " ")
+	if s == "" {
+		return nil, nil
+	}
+
+	if index, err := strconv.Atoi(s); err == nil {
+		if index < 0 {
+			return nil, fmt.Errorf("group index '%s' is invalid", s)
+		}
+		return &GroupSelector{Index: index}, nil
+	}
+
+	labels := map[string]string{}
+	for _, kv := range strings.Split(s, ",") {
+		split := strings.SplitN(kv, "=", 2)
+		if len(split) != 2 || strings.Trim(split[0], " ") == "" {
+			return nil, fmt.Errorf("group selector '%s' is invalid, eg: 1 or dc=sh,env=prod", s)
+		}
+		labels[strings.Trim(split[0], " ")] = strings.Trim(split[1], " ")
+	}
+
+	return &GroupSelector{Index: -1, Labels: labels}, nil
+}
+
+// IsIndex 是否按索引选择
+func (g *GroupSelector) IsIndex() bool {
+	return g.Index >= 0
+}
+
+func (g *GroupSelector) String() string {
+	if g == nil {
+		return ""
+	}
+	if g.IsIndex() {
+		return strconv.Itoa(g.Index)
+	}
+
+	kvs := []string{}
+	for k, v := range g.Labels {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return strings.Join(kvs, ",")
+}
+
+// match 返回匹配的分组索引
+func (g *GroupSelector) match(groups []StaticConfigs) []int {
+	indexes := []int{}
+
+	if g.IsIndex() {
+		if g.Index < len(groups) {
+			indexes = append(indexes, g.Index)
+		}
+		return indexes
+	}
+
+	for i, group := range groups {
+		if containLabels(group.Labels, g.Labels) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+// 判断两组标签是否完全相同
+func equalLabels(l1 map[string]string, l2 map[string]string) bool {
+	return len(l1) == len(l2) && containLabels(l1, l2)
+}
+
+// 判断labels是否包含所有subset的kv
+func containLabels(labels map[string]string, subset map[string]string) bool {
+	for k, v := range subset {
+		if val, ok := labels[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+// selectGroups 选择多个分组，未指定选择器时选择所有分组，没有匹配分组时返回notFound类型的错误
+func selectGroups(groups []StaticConfigs, sel *GroupSelector, notFound error) ([]int, error) {
+	if sel == nil {
+		indexes := []int{}
+		for i := range groups {
+			indexes = append(indexes, i)
+		}
+		return indexes, nil
+	}
+
+	indexes := sel.match(groups)
+	if len(indexes) == 0 {
+		return nil, newError(notFound, "", "static_configs", "no static_configs group matches '%s'", sel)
+	}
+
+	return indexes, nil
+}
+
+// selectGroup 选择一个分组，未指定选择器时选择第一个分组，
+// isCreate为true且标签选择器没有匹配分组时，新建一个分组，否则返回notFound类型的错误
+func selectGroup(groups []StaticConfigs, sel *GroupSelector, isCreate bool, notFound error) ([]StaticConfigs, int, error) {
+	if sel == nil {
+		if len(groups) == 0 {
+			if !isCreate {
+				return nil, 0, newError(notFound, "", "static_configs", "static_configs is empty")
+			}
+			groups = append(groups, StaticConfigs{Targets: []string{}})
+		}
+		return groups, 0, nil
+	}
+
+	indexes := sel.match(groups)
+	switch len(indexes) {
+	case 0:
+		if !isCreate || sel.IsIndex() {
+			return nil, 0, newError(notFound, "", "static_configs", "no static_configs group matches '%s'", sel)
+		}
+		labels := map[string]string{}
+		for k, v := range sel.Labels {
+			labels[k] = v
+		}
+		groups = append(groups, StaticConfigs{Targets: []string{}, Labels: labels})
+		return groups, len(groups) - 1, nil
+
+	case 1:
+		return groups, indexes[0], nil
+	}
+
+	return nil, 0, fmt.Errorf("'%s' matches %d static_configs groups, please specify a more exact selector", sel, len(indexes))
+}
+
+// ---------------------------------------------------------------------------------------
+
+// 删除选择器匹配的分组
+func delGroups(groups []StaticConfigs, sel *GroupSelector) ([]StaticConfigs, error) {
+	if sel == nil {
+		return nil, fmt.Errorf("group selector is empty")
+	}
+
+	indexes, err := selectGroups(groups, sel, ErrTargetsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	newGroups := []StaticConfigs{}
+	for i, group := range groups {
+		if !isContainIndex(indexes, i) {
+			newGroups = append(newGroups, group)
+		}
+	}
+
+	return newGroups, nil
+}
+
+// 获取选择器匹配分组的target，sel为nil时获取所有分组
+func getGroupTargets(groups []StaticConfigs, sel *GroupSelector) ([]string, error) {
+	indexes, err := selectGroups(groups, sel, ErrTargetsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []string{}
+	for _, i := range indexes {
+		targets = append(targets, groups[i].Targets...)
+	}
+
+	return removeDuplicate(targets), nil
+}
+
+// 添加target到选择器匹配的分组，sel为nil时添加到第一个分组
+func addGroupTargets(groups []StaticConfigs, sel *GroupSelector, addTargets []string) ([]StaticConfigs, error) {
+	groups, index, err := selectGroup(groups, sel, true, ErrTargetsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	// 添加到旧的targets，并去重
+	groups[index].Targets = addSliceElements(groups[index].Targets, addTargets)
+
+	return groups, nil
+}
+
+// 从选择器匹配的分组删除target，sel为nil时从所有分组删除
+func delGroupTargets(groups []StaticConfigs, sel *GroupSelector, delTargets []string) ([]StaticConfigs, error) {
+	indexes, err := selectGroups(groups, sel, ErrTargetsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	// 从旧的targets移除指定的target，如果不存在，则忽略
+	for _, i := range indexes {
+		groups[i].Targets = delSliceElements(groups[i].Targets, delTargets)
+	}
+
+	return groups, nil
+}
+
+// 替换选择器匹配分组的target，sel为nil时替换第一个分组
+func replaceGroupTargets(groups []StaticConfigs, sel *GroupSelector, newTargets []string) ([]StaticConfigs, error) {
+	groups, index, err := selectGroup(groups, sel, true, ErrTargetsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	groups[index].Targets = removeDuplicate(newTargets)
+
+	return groups, nil
+}
+
+// 添加target到标签完全相同的分组，没有则新建分组，
+// target已在其他分组时，移动到新的分组，移动后为空的分组会被删除
+func addTargetsWithLabels(groups []StaticConfigs, addTargets []string, labels map[string]string) []StaticConfigs {
+	// 从标签不同的分组中移除target
+	newGroups := []StaticConfigs{}
+	for _, group := range groups {
+		if !equalLabels(group.Labels, labels) {
+			oldLen := len(group.Targets)
+			group.Targets = delSliceElements(group.Targets, addTargets)
+			if len(group.Targets) == 0 && oldLen > 0 {
+				continue
+			}
+		}
+		newGroups = append(newGroups, group)
+	}
+
+	index := -1
+	for i, group := range newGroups {
+		if equalLabels(group.Labels, labels) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		newLabels := map[string]string{}
+		for k, v := range labels {
+			newLabels[k] = v
+		}
+		newGroups = append(newGroups, StaticConfigs{Targets: []string{}, Labels: newLabels})
+		index = len(newGroups) - 1
+	}
+	newGroups[index].Targets = addSliceElements(newGroups[index].Targets, addTargets)
+
+	return newGroups
+}
+
+// 获取选择器匹配分组的标签，sel为nil时获取第一个分组，没有分组时返回空的标签
+func getGroupLabels(groups []StaticConfigs, sel *GroupSelector) (map[string]string, error) {
+	if sel == nil && len(groups) == 0 {
+		return map[string]string{}, nil
+	}
+
+	groups, index, err := selectGroup(groups, sel, false, ErrLabelsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for k, v := range groups[index].Labels {
+		labels[k] = v
+	}
+
+	return labels, nil
+}
+
+// 修改选择器匹配分组的标签，sel为nil时修改第一个分组
+func updateGroupLabels(groups []StaticConfigs, sel *GroupSelector, fn func(map[string]string) map[string]string) ([]StaticConfigs, error) {
+	groups, index, err := selectGroup(groups, sel, false, ErrLabelsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	oldLabels := groups[index].Labels
+	if oldLabels == nil {
+		oldLabels = map[string]string{}
+	}
+	groups[index].Labels = fn(oldLabels)
+
+	return groups, nil
+}
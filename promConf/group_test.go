@@ -0,0 +1,176 @@
+package promConf
+
+import (
+	"fmt"
+	"testing"
+)
+
+var groupsData = []byte(`
+scrape_configs:
+  - job_name: 'node_exporter'
+    static_configs:
+      - targets: ['10.0.0.90:9100','10.0.0.91:9100']
+        labels:
+          dc: 'sh'
+          env: 'prod'
+      - targets: ['10.1.0.90:9100']
+        labels:
+          dc: 'bj'
+          env: 'prod'
+`)
+
+func TestParseGroupSelector(t *testing.T) {
+	sel, err := ParseGroupSelector("1")
+	if err != nil || !sel.IsIndex() || sel.Index != 1 {
+		t.Errorf("parse index selector failed, sel=%v, err=%v", sel, err)
+	}
+
+	sel, err = ParseGroupSelector("dc=sh, env=prod")
+	if err != nil || sel.IsIndex() || sel.String() != "dc=sh,env=prod" {
+		t.Errorf("parse label selector failed, sel=%v, err=%v", sel, err)
+	}
+
+	sel, err = ParseGroupSelector("")
+	if err != nil || sel != nil {
+		t.Errorf("empty selector should be nil, sel=%v, err=%v", sel, err)
+	}
+
+	for _, s := range []string{"-1", "dc", "=sh"} {
+		if _, err = ParseGroupSelector(s); err == nil {
+			t.Errorf("selector '%s' should be invalid", s)
+		}
+	}
+}
+
+func TestConfigYaml_GetJobGroups(t *testing.T) {
+	pConfig := NewConfigYaml(groupsData)
+	groups, err := pConfig.GetJobGroups("node_exporter")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(groups) != 2 {
+		t.Errorf("got %d groups, want 2", len(groups))
+	}
+
+	for i, group := range groups {
+		fmt.Println(i, group.Labels, group.Targets)
+	}
+}
+
+func TestConfigYaml_GetJobGroupTargets(t *testing.T) {
+	pConfig := NewConfigYaml(groupsData)
+
+	targets, err := pConfig.GetJobTargets("node_exporter")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(targets) != 3 {
+		t.Errorf("got targets %v, want targets of all groups", targets)
+	}
+
+	sel, _ := ParseGroupSelector("dc=bj")
+	targets, err = pConfig.GetJobGroupTargets("node_exporter", sel)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(targets) != 1 || targets[0] != "10.1.0.90:9100" {
+		t.Errorf("got targets %v, want [10.1.0.90:9100]", targets)
+	}
+}
+
+func TestConfigYaml_AddJobGroupTargets(t *testing.T) {
+	pConfig := NewConfigYaml(groupsData)
+
+	sel, _ := ParseGroupSelector("1")
+	err := pConfig.AddJobGroupTargets("node_exporter", sel, []string{"10.1.0.91:9100"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// 没有匹配分组，新建分组
+	sel, _ = ParseGroupSelector("dc=gz")
+	err = pConfig.AddJobGroupTargets("node_exporter", sel, []string{"10.2.0.90:9100"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	groups, _ := pConfig.GetJobGroups("node_exporter")
+	if len(groups) != 3 || len(groups[1].Targets) != 2 || groups[2].Labels["dc"] != "gz" {
+		t.Errorf("unexpected groups %v", groups)
+	}
+
+	// 多个分组匹配
+	sel, _ = ParseGroupSelector("env=prod")
+	err = pConfig.AddJobGroupTargets("node_exporter", sel, []string{"10.2.0.91:9100"})
+	if err == nil {
+		t.Error("ambiguous selector should return error")
+	}
+
+	fmt.Println(string(pConfig.Data))
+}
+
+func TestConfigYaml_DelJobGroupTargets(t *testing.T) {
+	pConfig := NewConfigYaml(groupsData)
+	err := pConfig.DelJobTargets("node_exporter", []string{"10.0.0.90:9100", "10.1.0.90:9100"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	targets, _ := pConfig.GetJobTargets("node_exporter")
+	if len(targets) != 1 || targets[0] != "10.0.0.91:9100" {
+		t.Errorf("got targets %v, want [10.0.0.91:9100]", targets)
+	}
+
+	fmt.Println(string(pConfig.Data))
+}
+
+func TestConfigYaml_GroupLabels(t *testing.T) {
+	pConfig := NewConfigYaml(groupsData)
+
+	sel, _ := ParseGroupSelector("dc=bj")
+	err := pConfig.AddJobGroupLabels("node_exporter", sel, map[string]string{"team": "db"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	labels, err := pConfig.GetJobGroupLabels("node_exporter", sel)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if labels["team"] != "db" {
+		t.Errorf("got labels %v, want team=db", labels)
+	}
+
+	labels, _ = pConfig.GetJobLabels("node_exporter")
+	if _, ok := labels["team"]; ok {
+		t.Errorf("labels of first group should not be changed, got %v", labels)
+	}
+
+	sel, _ = ParseGroupSelector("dc=gz")
+	if err = pConfig.AddJobGroupLabels("node_exporter", sel, map[string]string{"team": "db"}); err == nil {
+		t.Error("add labels to missing group should return error")
+	}
+}
+
+func TestConfigYaml_DelJobGroup(t *testing.T) {
+	pConfig := NewConfigYaml(groupsData)
+	sel, _ := ParseGroupSelector("dc=sh")
+	err := pConfig.DelJobGroup("node_exporter", sel)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	groups, _ := pConfig.GetJobGroups("node_exporter")
+	if len(groups) != 1 || groups[0].Labels["dc"] != "bj" {
+		t.Errorf("unexpected groups %v", groups)
+	}
+}
@@ -0,0 +1,157 @@
+package promConf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// --------------------------------- blackbox exporter 探测job ---------------------------------
+
+// 探测job的抓取路径和探测目标的参数名称
+const (
+	probeMetricsPath = "/probe"
+	probeTargetLabel = "__param_target"
+)
+
+// ProbeJob blackbox exporter探测job，prometheus通过exporter使用module探测static_configs中的target
+type ProbeJob struct {
+	Job      string   `json:"job"`
+	Module   string   `json:"module"`
+	Exporter string   `json:"exporter"`
+	Targets  []string `json:"targets"`
+}
+
+// 探测job固定的relabel规则，target作为探测参数，instance标签为target，抓取地址为exporter
+func probeRelabelConfigs(exporter string) []*RelabelConfig {
+	return []*RelabelConfig{
+		{SourceLabels: []string{"__address__"}, TargetLabel: probeTargetLabel},
+		{SourceLabels: []string{probeTargetLabel}, TargetLabel: "instance"},
+		{TargetLabel: "__address__", Replacement: &exporter},
+	}
+}
+
+// 从job配置中解析探测设置，不是探测job时返回错误
+func parseProbeJob(jc *JobConfig) (*ProbeJob, int, error) {
+	notProbe := fmt.Errorf("job '%s' is not a blackbox probe job, it must have metrics_path '%s', params.module and "+
+		"relabel_configs setting %s and __address__", jc.JobName, probeMetricsPath, probeTargetLabel)
+	if jc.MetricsPath != probeMetricsPath || len(jc.Params["module"]) == 0 {
+		return nil, -1, notProbe
+	}
+
+	hasTarget, exporterIndex := false, -1
+	for i, rule := range jc.RelabelConfigs {
+		switch {
+		case rule.TargetLabel == probeTargetLabel:
+			hasTarget = true
+		case rule.TargetLabel == "__address__" && rule.Replacement != nil:
+			exporterIndex = i
+		}
+	}
+	if !hasTarget || exporterIndex < 0 {
+		return nil, -1, notProbe
+	}
+
+	targets := []string{}
+	for _, group := range jc.StaticConfigs {
+		targets = addSliceElements(targets, group.Targets)
+	}
+
+	return &ProbeJob{
+		Job:      jc.JobName,
+		Module:   jc.Params["module"][0],
+		Exporter: *jc.RelabelConfigs[exporterIndex].Replacement,
+		Targets:  targets,
+	}, exporterIndex, nil
+}
+
+// GetProbeJob 获取探测job的module、exporter和探测的target
+func (c *ConfigYaml) GetProbeJob(jobName string) (*ProbeJob, error) {
+	jc, err := c.GetJobConfig(jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	probe, _, err := parseProbeJob(jc)
+	return probe, err
+}
+
+// GetProbeJobs 获取所有探测job
+func (c *ConfigYaml) GetProbeJobs() ([]*ProbeJob, error) {
+	cfg, err := c.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	probes := []*ProbeJob{}
+	for _, jc := range cfg.ScrapeConfigs {
+		if probe, _, err := parseProbeJob(jc); err == nil {
+			probes = append(probes, probe)
+		}
+	}
+
+	return probes, nil
+}
+
+// AddProbeTargets 添加探测的target，job不存在时使用module和exporter创建探测job，
+// job已存在时module或exporter不为空且与原有的不同则修改，labels不为空时target添加到标签相同的分组
+func (c *ConfigYaml) AddProbeTargets(jobName string, module string, exporter string, targets []string, labels map[string]string) error {
+	if exporter != "" {
+		if _, _, err := net.SplitHostPort(exporter); err != nil {
+			return fmt.Errorf("exporter '%s' is invalid, eg: 127.0.0.1:9115", exporter)
+		}
+	}
+
+	jc, err := c.GetJobConfig(jobName)
+	if errors.Is(err, ErrJobNotFound) {
+		if module == "" || exporter == "" {
+			return fmt.Errorf("job '%s' not found, the module and exporter are required to create probe job", jobName)
+		}
+		return c.AddJobConfig(&JobConfig{
+			JobName:        jobName,
+			MetricsPath:    probeMetricsPath,
+			Params:         map[string][]string{"module": {module}},
+			StaticConfigs:  []StaticConfigs{{Targets: removeDuplicate(targets), Labels: labels}},
+			RelabelConfigs: probeRelabelConfigs(exporter),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	probe, exporterIndex, err := parseProbeJob(jc)
+	if err != nil {
+		return err
+	}
+	if module != "" && module != probe.Module {
+		err = c.ReplaceJobSettings(jobName, map[string]string{settingParamsPrefix + "module": module})
+		if err != nil {
+			return err
+		}
+	}
+	if exporter != "" && exporter != probe.Exporter {
+		rule := jc.RelabelConfigs[exporterIndex]
+		rule.Replacement = &exporter
+		err = c.ReplaceJobRelabelConfig(jobName, RelabelConfigs, exporterIndex, rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+	if len(labels) > 0 {
+		return c.AddJobTargetsWithLabels(jobName, targets, labels)
+	}
+	return c.AddJobTargets(jobName, targets)
+}
+
+// DelProbeTargets 删除探测的target
+func (c *ConfigYaml) DelProbeTargets(jobName string, targets []string) error {
+	if _, err := c.GetProbeJob(jobName); err != nil {
+		return err
+	}
+
+	return c.DelJobTargets(jobName, targets)
+}
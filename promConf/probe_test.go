@@ -0,0 +1,135 @@
+package promConf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigYaml_AddProbeTargets(t *testing.T) {
+	c := NewConfigYaml([]byte("scrape_configs:\n  - job_name: node\n    static_configs:\n      - targets: ['10.0.0.1:9100']\n"))
+
+	err := c.AddProbeTargets("http_probe", "", "", []string{"https://example.com"}, nil)
+	if err == nil {
+		t.Error("expected error for creating probe job without module and exporter")
+	}
+
+	err = c.AddProbeTargets("http_probe", "http_2xx", "blackbox:9115", []string{"https://example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `scrape_configs:
+  - job_name: node
+    static_configs:
+      - targets: ['10.0.0.1:9100']
+  - job_name: http_probe
+    params:
+      module:
+        - http_2xx
+    metrics_path: /probe
+    static_configs:
+      - targets:
+          - https://example.com
+    relabel_configs:
+      - source_labels: [__address__]
+        target_label: __param_target
+      - source_labels: [__param_target]
+        target_label: instance
+      - target_label: __address__
+        replacement: blackbox:9115
+`
+	if string(c.Data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+	if err = c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 扩展已有的探测job，修改exporter
+	err = c.AddProbeTargets("http_probe", "", "10.0.0.9:9115", []string{"https://example.org"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe, err := c.GetProbeJob("http_probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probe.Module != "http_2xx" || probe.Exporter != "10.0.0.9:9115" ||
+		strings.Join(probe.Targets, ",") != "https://example.com,https://example.org" {
+		t.Errorf("unexpected probe %+v", probe)
+	}
+
+	err = c.DelProbeTargets("http_probe", []string{"https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	probes, err := c.GetProbeJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(probes) != 1 || strings.Join(probes[0].Targets, ",") != "https://example.org" {
+		t.Errorf("unexpected probes %+v", probes)
+	}
+
+	// 普通job不是探测job
+	if _, err = c.GetProbeJob("node"); err == nil || !strings.Contains(err.Error(), "not a blackbox probe job") {
+		t.Errorf("expected not a probe job error, got %v", err)
+	}
+	if err = c.AddProbeTargets("node", "icmp", "", []string{"10.0.0.2"}, nil); err == nil {
+		t.Error("expected error for adding probe targets to normal job")
+	}
+	if err = c.AddProbeTargets("icmp_probe", "icmp", "blackbox", []string{"10.0.0.2"}, nil); err == nil {
+		t.Error("expected error for invalid exporter address")
+	}
+}
+
+func TestBlackboxConfig_AddModule(t *testing.T) {
+	b := NewBlackboxConfig([]byte(`modules:
+  # default http probe
+  http_2xx:
+    prober: http
+`))
+
+	err := b.AddModule("http_2xx", "tcp", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.AddModule("icmp", "", "5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `modules:
+  # default http probe
+  http_2xx:
+    prober: http
+  icmp:
+    prober: icmp
+    timeout: 5s
+`
+	if string(b.Data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", b.Data, expected)
+	}
+
+	modules, err := b.GetModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 2 || modules[1].Name != "icmp" || modules[1].Timeout != "5s" {
+		t.Errorf("unexpected modules %+v", modules)
+	}
+
+	if err = b.AddModule("ssh_banner", "", ""); err == nil {
+		t.Error("expected error for module whose prober can not be inferred")
+	}
+	if err = NewBlackboxConfig([]byte("modules:\n  a:\n    prober: ftp\n")).Validate(); err == nil {
+		t.Error("expected error for unknown prober")
+	}
+
+	// 新文件
+	b = NewBlackboxConfig(nil)
+	if err = b.AddModule("tcp_connect", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if string(b.Data) != "modules:\n  tcp_connect:\n    prober: tcp\n" {
+		t.Errorf("got\n%s", b.Data)
+	}
+}
@@ -2,35 +2,10 @@ package promConf
 
 import (
 	"errors"
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
-	"time"
 
-	"github.com/zhufuyi/pkg/mconf"
-
-	jsoniter "github.com/json-iterator/go"
-)
-
-var (
-	getJobTargetsSelect = func(jobName string) string {
-		return fmt.Sprintf(".scrape_configs.(job_name=%s).static_configs.[0].targets.[*]", jobName)
-	}
-	getJobTargetsSelect2 = func(jobName string) string {
-		return fmt.Sprintf(".scrape_configs.(job_name=%s).static_configs.[0].targets", jobName)
-	}
-
-	getJobLabelsSelect = func(jobName string) string {
-		return fmt.Sprintf(".scrape_configs.(job_name=%s).static_configs.[0].labels", jobName)
-	}
-
-	getJobSelect = func(jobName string) string {
-		return fmt.Sprintf(".scrape_configs.(job_name=%s)", jobName)
-	}
+	"gopkg.in/yaml.v3"
 )
 
 // ConfigYaml 切分后只有一个deployment资源定义yaml文件
@@ -43,163 +18,405 @@ func NewConfigYaml(data []byte) *ConfigYaml {
 	return &ConfigYaml{Data: data}
 }
 
-// Persistent 持久化
-func (c *ConfigYaml) Persistent(file string) error {
+// Persistent 校验配置后持久化，配置无效时不写入，policy为备份保留策略
+func (c *ConfigYaml) Persistent(file string, policy BackupPolicy) error {
 	if len(c.Data) == 0 {
 		return nil
 	}
 
-	// 备份文件
-	name := filepath.Base(file)
-	path := strings.TrimRight(file, name)
-	bkPath := path + "bak"
-	os.MkdirAll(bkPath, 0777)
-	bkFile := fmt.Sprintf("%s/%s.%s", bkPath, name, time.Now().Format("20060102150405.000"))
-	data, err := ioutil.ReadFile(file)
+	err := c.Validate()
 	if err != nil {
 		return err
 	}
 
-	err = ioutil.WriteFile(bkFile, data, 0666)
+	// 备份文件
+	err = backupFile(file, policy)
 	if err != nil {
 		return err
 	}
 
 	// 写入文件
-	return ioutil.WriteFile(file, c.Data, 0666)
+	return writeFileAtomic(file, c.Data)
 }
 
-// --------------------------------- job target 增删改查 ---------------------------------
+// --------------------------------- job static_configs 分组 ---------------------------------
 
-// GetJobTargets 获取job target
-func (c *ConfigYaml) GetJobTargets(jobName string) ([]string, error) {
-	val, err := mconf.FindYaml(c.Data, getJobTargetsSelect(jobName))
+// GetJobGroups 获取job的所有static_configs分组
+func (c *ConfigYaml) GetJobGroups(jobName string) ([]StaticConfigs, error) {
+	root, err := c.root()
+	if err != nil {
+		return nil, err
+	}
+
+	job, _, err := findJobNode(root, jobName)
 	if err != nil {
 		return nil, err
 	}
-	return mconf.Bytes2Slice(val), nil
+
+	return decodeGroups(MappingValue(job, "static_configs"))
 }
 
-// AddJobTargets 添加新的job target
-func (c *ConfigYaml) AddJobTargets(jobName string, addTargets []string) error {
-	// 获取原来的targets
-	oldTargets, err := c.GetJobTargets(jobName)
+// ReplaceJobGroups 替换job的所有static_configs分组
+func (c *ConfigYaml) ReplaceJobGroups(jobName string, groups []StaticConfigs) error {
+	return c.edit(func(root *yaml.Node) error {
+		job, _, err := findJobNode(root, jobName)
+		if err != nil {
+			return err
+		}
+
+		setJobGroups(job, groups)
+		return nil
+	})
+}
+
+// 设置job节点的static_configs分组
+func setJobGroups(job *yaml.Node, groups []StaticConfigs) {
+	seq := editableValue(job, "static_configs")
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		seq = newSequenceNode()
+		SetMappingValue(job, "static_configs", seq)
+	}
+	seq.Content = encodeGroups(groups)
+}
+
+// 解析static_configs节点
+func decodeGroups(seq *yaml.Node) ([]StaticConfigs, error) {
+	groups := []StaticConfigs{}
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		// job没有static_configs字段
+		return groups, nil
+	}
+
+	for _, item := range seq.Content {
+		group := StaticConfigs{}
+		err := item.Decode(&group)
+		if err != nil {
+			return nil, err
+		}
+		group.node = item
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// 分组转为static_configs的元素节点，已存在的分组在原节点上修改
+func encodeGroups(groups []StaticConfigs) []*yaml.Node {
+	content := []*yaml.Node{}
+	for _, group := range groups {
+		node := resolveAlias(group.node)
+		if node == nil || node.Kind != yaml.MappingNode {
+			node = newMappingNode()
+		} else if group.node.Kind == yaml.AliasNode {
+			node = copyNode(node)
+		}
+
+		// targets
+		targets := editableValue(node, "targets")
+		if targets == nil || targets.Kind != yaml.SequenceNode {
+			targets = newSequenceNode()
+			SetMappingValue(node, "targets", targets)
+		}
+		if len(targets.Content) == 0 {
+			// 空的[]不代表原有风格，添加target时使用块风格
+			targets.Style &^= yaml.FlowStyle
+		}
+		updateStringSequence(targets, group.Targets)
+
+		// labels
+		oldLabels := map[string]string{}
+		if labels := MappingValue(node, "labels"); labels != nil {
+			_ = labels.Decode(&oldLabels)
+		}
+		switch {
+		case len(group.Labels) == 0:
+			DeleteMappingKey(node, "labels")
+		case !equalLabels(oldLabels, group.Labels):
+			labels := editableValue(node, "labels")
+			if labels == nil || labels.Kind != yaml.MappingNode {
+				labels = newMappingNode()
+				SetMappingValue(node, "labels", labels)
+			}
+			updateStringMapping(labels, group.Labels)
+		}
+
+		content = append(content, node)
+	}
+
+	return content
+}
+
+// DelJobGroup 删除job中选择器匹配的static_configs分组
+func (c *ConfigYaml) DelJobGroup(jobName string, sel *GroupSelector) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return delGroups(groups, sel)
+	})
+}
+
+func (c *ConfigYaml) updateJobGroups(jobName string, fn func([]StaticConfigs) ([]StaticConfigs, error)) error {
+	groups, err := c.GetJobGroups(jobName)
 	if err != nil {
 		return err
 	}
 
-	// 从旧的targets移除指定的target，如果不存在，则忽略
-	newTargets := addSliceElements(oldTargets, addTargets)
+	groups, err = fn(groups)
+	if err != nil {
+		return withJob(err, jobName)
+	}
+
+	return c.ReplaceJobGroups(jobName, groups)
+}
+
+// --------------------------------- job target 增删改查 ---------------------------------
 
-	jsonData, _ := jsoniter.Marshal(newTargets)
-	c.Data, err = mconf.PutDocumentYaml(c.Data, getJobTargetsSelect2(jobName), string(jsonData))
-	return err
+// GetJobTargets 获取job所有分组的target
+func (c *ConfigYaml) GetJobTargets(jobName string) ([]string, error) {
+	return c.GetJobGroupTargets(jobName, nil)
 }
 
-// DelJobTargets 删除已存在的job target
+// AddJobTargets 添加新的job target到第一个分组
+func (c *ConfigYaml) AddJobTargets(jobName string, addTargets []string) error {
+	return c.AddJobGroupTargets(jobName, nil, addTargets)
+}
+
+// DelJobTargets 从所有分组删除已存在的job target
 func (c *ConfigYaml) DelJobTargets(jobName string, delTargets []string) error {
-	// 获取原来的targets
-	oldTargets, err := c.GetJobTargets(jobName)
+	return c.DelJobGroupTargets(jobName, nil, delTargets)
+}
+
+// ReplaceJobTargets 修改第一个分组的job target，注：直接替换所有旧值
+func (c *ConfigYaml) ReplaceJobTargets(jobName string, newTargets []string) error {
+	return c.ReplaceJobGroupTargets(jobName, nil, newTargets)
+}
+
+// GetJobGroupTargets 获取选择器匹配分组的target，sel为nil时获取所有分组
+func (c *ConfigYaml) GetJobGroupTargets(jobName string, sel *GroupSelector) ([]string, error) {
+	groups, err := c.GetJobGroups(jobName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// 从旧的targets移除指定的target，如果不存在，则忽略
-	newTargets := delSliceElements(oldTargets, delTargets)
+	targets, err := getGroupTargets(groups, sel)
+	return targets, withJob(err, jobName)
+}
 
-	jsonData, _ := jsoniter.Marshal(newTargets)
-	c.Data, err = mconf.PutDocumentYaml(c.Data, getJobTargetsSelect2(jobName), string(jsonData))
-	return err
+// AddJobGroupTargets 添加新的target到选择器匹配的分组，sel为nil时添加到第一个分组，
+// 标签选择器没有匹配分组时，新建分组
+func (c *ConfigYaml) AddJobGroupTargets(jobName string, sel *GroupSelector, addTargets []string) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return addGroupTargets(groups, sel, addTargets)
+	})
 }
 
-// ReplaceJobTargets 修改job target，注：直接替换所有旧值
-func (c *ConfigYaml) ReplaceJobTargets(jobName string, newTargets []string) error {
-	var err error
-	jsonData, _ := jsoniter.Marshal(newTargets)
-	c.Data, err = mconf.PutDocumentYaml(c.Data, getJobTargetsSelect2(jobName), string(jsonData))
-	return err
+// DelJobGroupTargets 从选择器匹配的分组删除target，sel为nil时从所有分组删除
+func (c *ConfigYaml) DelJobGroupTargets(jobName string, sel *GroupSelector, delTargets []string) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return delGroupTargets(groups, sel, delTargets)
+	})
 }
 
-// --------------------------------- job label 增删改查 ---------------------------------
+// ReplaceJobGroupTargets 替换选择器匹配分组的target，sel为nil时替换第一个分组，
+// 标签选择器没有匹配分组时，新建分组
+func (c *ConfigYaml) ReplaceJobGroupTargets(jobName string, sel *GroupSelector, newTargets []string) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return replaceGroupTargets(groups, sel, newTargets)
+	})
+}
 
-// GetJobLabels 获取job标签
-func (c *ConfigYaml) GetJobLabels(jobName string) (map[string]string, error) {
-	val, err := mconf.FindYaml(c.Data, getJobLabelsSelect(jobName))
+// Target target及其生效的标签
+type Target struct {
+	Job     string            `json:"job"`
+	Address string            `json:"address"`
+	Group   int               `json:"group"`  // 所在static_configs分组索引
+	Labels  map[string]string `json:"labels"` // 生效的标签，包括job标签和分组标签
+}
+
+// GetJobTargetsWithLabels 获取job所有target及其生效的标签
+func (c *ConfigYaml) GetJobTargetsWithLabels(jobName string) ([]*Target, error) {
+	groups, err := c.GetJobGroups(jobName)
 	if err != nil {
 		return nil, err
 	}
 
-	return mconf.Bytes2Map(val), nil
+	return groupTargetsWithLabels(jobName, groups), nil
 }
 
-// AddJobLabels 添加新的job label
-func (c *ConfigYaml) AddJobLabels(jobName string, addLabels map[string]string) error {
-	// 获取原来的labels
-	oldLabels, err := c.GetJobLabels(jobName)
+// GetJobGroupTargetsWithLabels 获取job中选择器匹配分组的target及其生效的标签，sel为nil时获取所有分组
+func (c *ConfigYaml) GetJobGroupTargetsWithLabels(jobName string, sel *GroupSelector) ([]*Target, error) {
+	groups, err := c.GetJobGroups(jobName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	targets, err := selectGroupTargetsWithLabels(jobName, groups, sel)
+	return targets, withJob(err, jobName)
+}
+
+// 选择器匹配分组的target及其生效的标签
+func selectGroupTargetsWithLabels(jobName string, groups []StaticConfigs, sel *GroupSelector) ([]*Target, error) {
+	indexes, err := selectGroups(groups, sel, ErrTargetsNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []*Target{}
+	for _, target := range groupTargetsWithLabels(jobName, groups) {
+		if isContainIndex(indexes, target.Group) {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, nil
+}
+
+func groupTargetsWithLabels(jobName string, groups []StaticConfigs) []*Target {
+	targets := []*Target{}
+	for i, group := range groups {
+		for _, address := range group.Targets {
+			labels := map[string]string{"job": jobName}
+			for k, v := range group.Labels {
+				labels[k] = v
+			}
+			targets = append(targets, &Target{Job: jobName, Address: address, Group: i, Labels: labels})
+		}
 	}
 
-	// 从旧的labels添加指定的label，如果不存在，则忽略
-	newLabels := addMapKVs(oldLabels, addLabels)
+	return targets
+}
+
+// AddJobTargetsWithLabels 添加target到标签完全相同的分组，没有则新建分组，
+// target已在其他分组时，移动到新的分组，移动后为空的分组会被删除
+func (c *ConfigYaml) AddJobTargetsWithLabels(jobName string, addTargets []string, labels map[string]string) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return addTargetsWithLabels(groups, addTargets, labels), nil
+	})
+}
+
+// --------------------------------- job label 增删改查 ---------------------------------
 
-	jsonData, _ := jsoniter.Marshal(newLabels)
-	c.Data, err = mconf.PutDocumentYaml(c.Data, getJobLabelsSelect(jobName), string(jsonData))
+// GetJobLabels 获取第一个分组的job标签
+func (c *ConfigYaml) GetJobLabels(jobName string) (map[string]string, error) {
+	return c.GetJobGroupLabels(jobName, nil)
+}
 
-	return err
+// AddJobLabels 添加新的job label到第一个分组
+func (c *ConfigYaml) AddJobLabels(jobName string, addLabels map[string]string) error {
+	return c.AddJobGroupLabels(jobName, nil, addLabels)
 }
 
-// DelJobLabels 删除已存在的label
+// DelJobLabels 删除第一个分组已存在的label
 func (c *ConfigYaml) DelJobLabels(jobName string, delLabelKeys []string) error {
-	// 获取原来的labels
-	oldLabels, err := c.GetJobLabels(jobName)
+	return c.DelJobGroupLabels(jobName, nil, delLabelKeys)
+}
+
+// ReplaceJobLabels 修改第一个分组的label，注：直接替换所有旧值
+func (c *ConfigYaml) ReplaceJobLabels(jobName string, newLabels map[string]string) error {
+	return c.ReplaceJobGroupLabels(jobName, nil, newLabels)
+}
+
+// GetJobGroupLabels 获取选择器匹配分组的标签，sel为nil时获取第一个分组
+func (c *ConfigYaml) GetJobGroupLabels(jobName string, sel *GroupSelector) (map[string]string, error) {
+	groups, err := c.GetJobGroups(jobName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// 从旧的labels移除指定的target，如果不存在，则忽略
-	newLabels := delMapKVs(oldLabels, delLabelKeys)
+	labels, err := getGroupLabels(groups, sel)
+	return labels, withJob(err, jobName)
+}
 
-	jsonData, _ := jsoniter.Marshal(newLabels)
-	c.Data, err = mconf.PutDocumentYaml(c.Data, getJobLabelsSelect(jobName), string(jsonData))
+// AddJobGroupLabels 添加新的label到选择器匹配的分组，存在则替换
+func (c *ConfigYaml) AddJobGroupLabels(jobName string, sel *GroupSelector, addLabels map[string]string) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return updateGroupLabels(groups, sel, func(oldLabels map[string]string) map[string]string {
+			return addMapKVs(oldLabels, addLabels)
+		})
+	})
+}
 
-	return err
+// DelJobGroupLabels 删除选择器匹配分组已存在的label
+func (c *ConfigYaml) DelJobGroupLabels(jobName string, sel *GroupSelector, delLabelKeys []string) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return updateGroupLabels(groups, sel, func(oldLabels map[string]string) map[string]string {
+			return delMapKVs(oldLabels, delLabelKeys)
+		})
+	})
 }
 
-// ReplaceJobLabels 修改label，注：直接替换所有旧值
-func (c *ConfigYaml) ReplaceJobLabels(jobName string, newLabels map[string]string) error {
-	var err error
-	jsonData, _ := jsoniter.Marshal(newLabels)
-	c.Data, err = mconf.PutDocumentYaml(c.Data, getJobLabelsSelect(jobName), string(jsonData))
-	return err
+// ReplaceJobGroupLabels 替换选择器匹配分组的label，注：直接替换所有旧值
+func (c *ConfigYaml) ReplaceJobGroupLabels(jobName string, sel *GroupSelector, newLabels map[string]string) error {
+	return c.updateJobGroups(jobName, func(groups []StaticConfigs) ([]StaticConfigs, error) {
+		return updateGroupLabels(groups, sel, func(oldLabels map[string]string) map[string]string {
+			return newLabels
+		})
+	})
 }
 
 // --------------------------------- job 增删查 ---------------------------------
 
-// JobConfig job配置
+// JobConfig job配置，没有定义的字段(例如consul_sd_configs)保存在Extra中
 type JobConfig struct {
-	JobName        string          `json:"job_name"`
-	ScrapeInterval string          `json:"scrape_interval,omitempty"`
-	ScrapeTimeout  string          `json:"scrape_timeout,omitempty"`
-	MetricsPath    string          `json:"metrics_path,omitempty"`
-	Scheme         string          `json:"scheme,omitempty"`
-	StaticConfigs  []StaticConfigs `json:"static_configs"`
+	HTTPClientConfig `yaml:",inline"`
+
+	JobName               string              `json:"job_name" yaml:"job_name"`
+	HonorLabels           bool                `json:"honor_labels,omitempty" yaml:"honor_labels,omitempty"`
+	HonorTimestamps       *bool               `json:"honor_timestamps,omitempty" yaml:"honor_timestamps,omitempty"`
+	Params                map[string][]string `json:"params,omitempty" yaml:"params,omitempty"`
+	ScrapeInterval        string              `json:"scrape_interval,omitempty" yaml:"scrape_interval,omitempty"`
+	ScrapeTimeout         string              `json:"scrape_timeout,omitempty" yaml:"scrape_timeout,omitempty"`
+	MetricsPath           string              `json:"metrics_path,omitempty" yaml:"metrics_path,omitempty"`
+	Scheme                string              `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	BodySizeLimit         string              `json:"body_size_limit,omitempty" yaml:"body_size_limit,omitempty"`
+	SampleLimit           uint                `json:"sample_limit,omitempty" yaml:"sample_limit,omitempty"`
+	TargetLimit           uint                `json:"target_limit,omitempty" yaml:"target_limit,omitempty"`
+	LabelLimit            uint                `json:"label_limit,omitempty" yaml:"label_limit,omitempty"`
+	LabelNameLengthLimit  uint                `json:"label_name_length_limit,omitempty" yaml:"label_name_length_limit,omitempty"`
+	LabelValueLengthLimit uint                `json:"label_value_length_limit,omitempty" yaml:"label_value_length_limit,omitempty"`
+	StaticConfigs         []StaticConfigs     `json:"static_configs,omitempty" yaml:"static_configs,omitempty"`
+	FileSDConfigs         []*FileSDConfig     `json:"file_sd_configs,omitempty" yaml:"file_sd_configs,omitempty"`
+	RelabelConfigs        []*RelabelConfig    `json:"relabel_configs,omitempty" yaml:"relabel_configs,omitempty"`
+	MetricRelabelConfigs  []*RelabelConfig    `json:"metric_relabel_configs,omitempty" yaml:"metric_relabel_configs,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
 }
 
 // StaticConfigs 静态配置
 type StaticConfigs struct {
-	Targets []string    `json:"targets"`
-	Labels  interface{} `json:"labels,omitempty"`
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	node *yaml.Node // 分组在配置文件中的节点，修改时保留原有格式
 }
 
-// CheckValid 检查服务器是否可以连接
+// SDConfigNames 获取job所有服务发现配置的名称，例如static_configs、file_sd_configs、consul_sd_configs
+func (j *JobConfig) SDConfigNames() []string {
+	names := []string{}
+	if len(j.StaticConfigs) > 0 {
+		names = append(names, "static_configs")
+	}
+	if len(j.FileSDConfigs) > 0 {
+		names = append(names, "file_sd_configs")
+	}
+
+	extraNames := []string{}
+	for name := range j.Extra {
+		if strings.HasSuffix(name, "_sd_configs") {
+			extraNames = append(extraNames, name)
+		}
+	}
+	sort.Strings(extraNames)
+
+	return append(names, extraNames...)
+}
+
+// CheckValid 检查job配置是否有效
 func (j *JobConfig) CheckValid() error {
 	if j.JobName == "" {
 		return errors.New("field 'job_name' is empty")
 	}
 
-	if len(j.StaticConfigs) == 0 {
-		return errors.New("field 'static_configs' is empty")
+	if len(j.SDConfigNames()) == 0 {
+		return errors.New("field 'static_configs' or '*_sd_configs' is empty")
 	}
 
 	for _, v := range j.StaticConfigs {
@@ -213,23 +430,29 @@ func (j *JobConfig) CheckValid() error {
 
 // GetJob 获取job静态配置
 func (c *ConfigYaml) GetJob(jobName string) ([]byte, error) {
-	val, err := mconf.FindYaml(c.Data, getJobSelect(jobName))
+	root, err := c.root()
 	if err != nil {
 		return nil, err
 	}
 
-	return val, nil
+	job, _, err := findJobNode(root, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeNode(job, detectIndent(c.Data))
 }
 
-// AddJob 添加job静态配置，支持yaml、json数据格式
+// AddJob 添加job静态配置，支持yaml、json数据格式，job已存在时替换，
+// 插入的是原始数据的节点，所有字段都会保留
 func (c *ConfigYaml) AddJob(jobData []byte) error {
-	val, err := mconf.Find(jobData, ".", "yaml", mconf.JsonFormat)
+	jobNode, err := parseMappingData(jobData)
 	if err != nil {
 		return err
 	}
 
 	jc := &JobConfig{}
-	err = jsoniter.Unmarshal(val, jc)
+	err = jobNode.Decode(jc)
 	if err != nil {
 		return err
 	}
@@ -238,22 +461,106 @@ func (c *ConfigYaml) AddJob(jobData []byte) error {
 		return err
 	}
 
-	jsonData, _ := jsoniter.Marshal(jc)
-	c.Data, err = mconf.PutDocumentYaml(c.Data, getJobSelect(jc.JobName), string(jsonData))
+	return c.addJobNode(jc.JobName, jobNode)
+}
+
+// 添加job节点，job已存在时替换，保留原job的注释
+func (c *ConfigYaml) addJobNode(jobName string, jobNode *yaml.Node) error {
+	return c.edit(func(root *yaml.Node) error {
+		seq := editableValue(root, "scrape_configs")
+		if seq == nil || seq.Kind != yaml.SequenceNode {
+			seq = newSequenceNode()
+			SetMappingValue(root, "scrape_configs", seq)
+		}
+
+		for i, item := range seq.Content {
+			if name := MappingValue(item, "job_name"); name != nil && name.Value == jobName {
+				if jobNode.HeadComment == "" {
+					jobNode.HeadComment = item.HeadComment
+				}
+				seq.Content[i] = jobNode
+				return nil
+			}
+		}
+		seq.Content = append(seq.Content, jobNode)
 
-	return err
+		return nil
+	})
 }
 
 // DelJob 删除job静态配置
 func (c *ConfigYaml) DelJob(jobName string) error {
-	var err error
-	c.Data, err = mconf.DeleteYaml(c.Data, getJobSelect(jobName))
+	return c.edit(func(root *yaml.Node) error {
+		_, index, err := findJobNode(root, jobName)
+		if err != nil {
+			return err
+		}
+
+		seq := editableValue(root, "scrape_configs")
+		seq.Content = append(seq.Content[:index], seq.Content[index+1:]...)
+
+		return nil
+	})
+}
+
+// --------------------------------- yaml节点 ---------------------------------
 
-	return err
+// 解析后的根节点
+func (c *ConfigYaml) root() (*yaml.Node, error) {
+	doc, err := parseDocument(c.Data)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// 修改根节点，只有被修改的节点内容会变化，保留原有的注释和格式
+func (c *ConfigYaml) edit(fn func(root *yaml.Node) error) error {
+	data, err := editDocument(c.Data, fn)
+	if err != nil {
+		return err
+	}
+
+	c.Data = data
+	return nil
+}
+
+// 查找job节点，返回job节点和在scrape_configs中的索引
+func findJobNode(root *yaml.Node, jobName string) (*yaml.Node, int, error) {
+	seq := MappingValue(root, "scrape_configs")
+	if seq != nil {
+		for i, item := range seq.Content {
+			name := MappingValue(item, "job_name")
+			if name != nil && name.Value == jobName {
+				return resolveAlias(item), i, nil
+			}
+		}
+	}
+
+	return nil, -1, newError(ErrJobNotFound, jobName, "scrape_configs", "job '%s' not found", jobName)
 }
 
 // ---------------------------------------------------------------------------------------
 
+func isContainIndex(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+// SortedKeys 按字母顺序排序的map的key
+func SortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func removeDuplicate(slice []string) []string {
 	uniqueSlice := []string{}
 	isDup := false
@@ -331,38 +638,3 @@ func compatibleKey(key string) string {
 	}
 	return strings.Trim(key, " ")
 }
-
-// ---------------------------------------------------------------------------------------
-
-func checkURL(url string) error {
-	re := regexp.MustCompile(`(http|https):\/\/[\w\-_]+(\.[\w\-_]+)+([\w\-\.,@?^=%&:/~\+#]*[\w\-\@?^=%&/~\+#])?`)
-	result := re.FindAllStringSubmatch(url, -1)
-	if result == nil {
-		return fmt.Errorf("URL(%s) is invalid\n", url)
-	}
-	return nil
-}
-
-// ConfReload 使prometheus配置生效
-func ConfReload(promURL string) error {
-	err := checkURL(promURL)
-	if err != nil {
-		return err
-	}
-
-	resp, err := http.PostForm(promURL, nil)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if string(body) != "" {
-		fmt.Println(string(body))
-	}
-
-	return nil
-}
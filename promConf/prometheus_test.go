@@ -234,3 +234,40 @@ func TestConfReload(t *testing.T) {
 		return
 	}
 }
+
+func TestConfigYaml_AddJobTargetsWithLabels(t *testing.T) {
+	pConfig := NewConfigYaml(data)
+
+	// 新建分组
+	err := pConfig.AddJobTargetsWithLabels("node_exporter", []string{"10.0.0.5:9100"}, map[string]string{"env": "prod", "team": "db"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// 移动target到已存在的分组
+	err = pConfig.AddJobTargetsWithLabels("node_exporter", []string{"10.0.0.90:9100"}, map[string]string{"env": "prod", "team": "db"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	targets, err := pConfig.GetJobTargetsWithLabels("node_exporter")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, target := range targets {
+		switch target.Address {
+		case "10.0.0.5:9100", "10.0.0.90:9100":
+			if target.Group != 1 || target.Labels["team"] != "db" || target.Labels["job"] != "node_exporter" {
+				t.Errorf("unexpected target %v", target)
+			}
+		default:
+			if target.Group != 0 || target.Labels["project"] != "local" {
+				t.Errorf("unexpected target %v", target)
+			}
+		}
+	}
+
+	pp.Println(targets)
+}
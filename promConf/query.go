@@ -0,0 +1,218 @@
+package promConf
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// --------------------------------- 跨job查询 ---------------------------------
+
+// 标签匹配操作符
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
+
+// LabelMatcher 标签匹配条件
+type LabelMatcher struct {
+	Name  string
+	Op    string
+	Value string
+
+	re *regexp.Regexp
+}
+
+// Matches 标签值是否匹配，标签不存在时值为空
+func (m *LabelMatcher) Matches(value string) bool {
+	switch m.Op {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+func (m *LabelMatcher) String() string {
+	return m.Name + m.Op + m.Value
+}
+
+// LabelSelector 标签选择器，所有条件都匹配时才匹配
+type LabelSelector []*LabelMatcher
+
+// ParseLabelSelector 解析标签选择器，支持=、!=、=~、!~，多个条件用逗号分隔，例如 env=prod,team!=db
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	selector := LabelSelector{}
+	s = strings.Trim(s, " ")
+	if s == "" {
+		return selector, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.Trim(item, " ")
+		index := strings.IndexAny(item, "=!")
+		if index <= 0 {
+			return nil, fmt.Errorf("label selector '%s' is invalid, eg: env=prod,team!=db", item)
+		}
+
+		m := &LabelMatcher{Name: strings.Trim(item[:index], " ")}
+		rest := item[index:]
+		for _, op := range []string{MatchNotEqual, MatchRegexp, MatchNotRegexp, MatchEqual} {
+			if strings.HasPrefix(rest, op) {
+				m.Op = op
+				m.Value = strings.Trim(rest[len(op):], " ")
+				break
+			}
+		}
+		if m.Op == "" {
+			return nil, fmt.Errorf("label selector '%s' is invalid, eg: env=prod,team!=db", item)
+		}
+		if err := CheckLabelName(m.Name); err != nil {
+			return nil, err
+		}
+		if m.Op == MatchRegexp || m.Op == MatchNotRegexp {
+			// 与prometheus一致，正则表达式完全匹配
+			re, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("label selector '%s' is invalid, %v", item, err)
+			}
+			m.re = re
+		}
+
+		selector = append(selector, m)
+	}
+
+	return selector, nil
+}
+
+// Matches 标签是否匹配所有条件
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, m := range s {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s LabelSelector) String() string {
+	items := []string{}
+	for _, m := range s {
+		items = append(items, m.String())
+	}
+	return strings.Join(items, ",")
+}
+
+// TargetQuery target查询条件，字段为空时不作为条件
+type TargetQuery struct {
+	Job      string        // job名称
+	Host     string        // target的主机名或ip，不包括端口
+	Selector LabelSelector // 生效标签的选择器
+}
+
+// Match target是否满足查询条件
+func (q *TargetQuery) Match(t *Target) bool {
+	if q == nil {
+		return true
+	}
+	if q.Job != "" && t.Job != q.Job {
+		return false
+	}
+	if q.Host != "" && !strings.EqualFold(TargetHost(t.Address), q.Host) {
+		return false
+	}
+	return q.Selector.Matches(t.Labels)
+}
+
+// FilterTargets 过滤出满足查询条件的target
+func FilterTargets(targets []*Target, q *TargetQuery) []*Target {
+	result := []*Target{}
+	for _, t := range targets {
+		if q.Match(t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// TargetHost 获取target的主机名或ip，支持host:port、[ipv6]:port和url(例如blackbox的探测地址)
+func TargetHost(address string) string {
+	if strings.Contains(address, "://") {
+		if u, err := url.Parse(address); err == nil {
+			return u.Hostname()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return strings.Trim(address, "[]")
+	}
+	return host
+}
+
+// GetAllTargetsWithLabels 获取所有job的static_configs中的target及其生效的标签
+func (c *ConfigYaml) GetAllTargetsWithLabels() ([]*Target, error) {
+	cfg, err := c.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []*Target{}
+	for _, job := range cfg.ScrapeConfigs {
+		if job == nil {
+			continue
+		}
+		targets = append(targets, groupTargetsWithLabels(job.JobName, job.StaticConfigs)...)
+	}
+
+	return targets, nil
+}
+
+// QueryTargets 查询所有job的static_configs中满足条件的target
+func (c *ConfigYaml) QueryTargets(q *TargetQuery) ([]*Target, error) {
+	targets, err := c.GetAllTargetsWithLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterTargets(targets, q), nil
+}
+
+// JobSummary job概要
+type JobSummary struct {
+	Name      string   `json:"name"`
+	Groups    int      `json:"groups"`     // static_configs分组数量
+	Targets   int      `json:"targets"`    // static_configs中target数量
+	SDConfigs []string `json:"sd_configs"` // 服务发现配置名称，例如static_configs、file_sd_configs
+}
+
+// GetJobs 获取所有job的概要，按在配置文件中的顺序排列
+func (c *ConfigYaml) GetJobs() ([]*JobSummary, error) {
+	cfg, err := c.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*JobSummary{}
+	for _, job := range cfg.ScrapeConfigs {
+		if job == nil {
+			continue
+		}
+		js := &JobSummary{Name: job.JobName, Groups: len(job.StaticConfigs), SDConfigs: job.SDConfigNames()}
+		for _, group := range job.StaticConfigs {
+			js.Targets += len(group.Targets)
+		}
+		jobs = append(jobs, js)
+	}
+
+	return jobs, nil
+}
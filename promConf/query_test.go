@@ -0,0 +1,127 @@
+package promConf
+
+import (
+	"testing"
+)
+
+var queryData = []byte(`scrape_configs:
+  - job_name: node
+    static_configs:
+      - targets: ['10.0.0.91:9100', '10.0.0.92:9100']
+        labels:
+          env: prod
+          team: db
+      - targets: ['10.0.0.93:9100']
+        labels:
+          env: prod
+          team: web
+  - job_name: mysqld
+    static_configs:
+      - targets: ['10.0.0.91:9104']
+        labels:
+          env: dev
+  - job_name: blackbox
+    metrics_path: /probe
+    static_configs:
+      - targets: ['http://10.0.0.91:8080/health']
+    relabel_configs:
+      - source_labels: [__address__]
+        target_label: __param_target
+      - target_label: __address__
+        replacement: 127.0.0.1:9115
+  - job_name: consul
+    consul_sd_configs:
+      - server: 127.0.0.1:8500
+`)
+
+func TestParseLabelSelector(t *testing.T) {
+	sel, err := ParseLabelSelector("env=prod, team!=db,instance=~10\\.0\\..*,zone!~sh|bj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel) != 4 || sel.String() != "env=prod,team!=db,instance=~10\\.0\\..*,zone!~sh|bj" {
+		t.Fatalf("got %v", sel)
+	}
+
+	if !sel.Matches(map[string]string{"env": "prod", "instance": "10.0.0.1"}) {
+		t.Error("expected match")
+	}
+	if sel.Matches(map[string]string{"env": "prod", "team": "db", "instance": "10.0.0.1"}) {
+		t.Error("unexpected match")
+	}
+	if sel.Matches(map[string]string{"env": "prod", "instance": "10.0.0.1", "zone": "sh"}) {
+		t.Error("unexpected match")
+	}
+
+	for _, s := range []string{"env", "=prod", "env~prod", "a-b=c", "env=~(prod"} {
+		if _, err = ParseLabelSelector(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestTargetHost(t *testing.T) {
+	testData := map[string]string{
+		"10.0.0.91:9100":               "10.0.0.91",
+		"node1.example.com:9100":       "node1.example.com",
+		"[::1]:9100":                   "::1",
+		"http://10.0.0.91:8080/health": "10.0.0.91",
+		"10.0.0.91":                    "10.0.0.91",
+	}
+	for address, host := range testData {
+		if got := TargetHost(address); got != host {
+			t.Errorf("%s: got %s, expected %s", address, got, host)
+		}
+	}
+}
+
+func TestConfigYaml_QueryTargets(t *testing.T) {
+	c := NewConfigYaml(queryData)
+
+	targets, err := c.QueryTargets(&TargetQuery{Host: "10.0.0.91"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []string{}
+	for _, target := range targets {
+		jobs = append(jobs, target.Job)
+	}
+	if len(jobs) != 3 || jobs[0] != "node" || jobs[1] != "mysqld" || jobs[2] != "blackbox" {
+		t.Errorf("got %v", jobs)
+	}
+
+	sel, _ := ParseLabelSelector("env=prod,team!=db")
+	targets, err = c.QueryTargets(&TargetQuery{Selector: sel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Address != "10.0.0.93:9100" || targets[0].Group != 1 || targets[0].Labels["job"] != "node" {
+		t.Errorf("got %+v", targets)
+	}
+
+	sel, _ = ParseLabelSelector("job=~node|mysqld")
+	targets, err = c.QueryTargets(&TargetQuery{Job: "mysqld", Selector: sel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Address != "10.0.0.91:9104" {
+		t.Errorf("got %+v", targets)
+	}
+}
+
+func TestConfigYaml_GetJobs(t *testing.T) {
+	c := NewConfigYaml(queryData)
+	jobs, err := c.GetJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 4 {
+		t.Fatalf("got %d jobs", len(jobs))
+	}
+	if jobs[0].Name != "node" || jobs[0].Groups != 2 || jobs[0].Targets != 3 {
+		t.Errorf("got %+v", jobs[0])
+	}
+	if jobs[3].Name != "consul" || jobs[3].Targets != 0 || len(jobs[3].SDConfigs) != 1 || jobs[3].SDConfigs[0] != "consul_sd_configs" {
+		t.Errorf("got %+v", jobs[3])
+	}
+}
@@ -0,0 +1,159 @@
+package promConf
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// job的relabel规则类型
+const (
+	RelabelConfigs       = "relabel_configs"        // 抓取前改写target标签
+	MetricRelabelConfigs = "metric_relabel_configs" // 抓取后改写或丢弃指标
+)
+
+func (r *RelabelConfig) String() string {
+	node, err := ToNode(r)
+	if err != nil {
+		return err.Error()
+	}
+	node.Style = yaml.FlowStyle
+
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return err.Error()
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func checkRelabelKind(kind string) error {
+	if kind != RelabelConfigs && kind != MetricRelabelConfigs {
+		return fmt.Errorf("unknown relabel kind '%s', only supports %s and %s", kind, RelabelConfigs, MetricRelabelConfigs)
+	}
+	return nil
+}
+
+// GetJobRelabelConfigs 获取job的relabel规则，kind为RelabelConfigs或MetricRelabelConfigs
+func (c *ConfigYaml) GetJobRelabelConfigs(jobName string, kind string) ([]*RelabelConfig, error) {
+	err := checkRelabelKind(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := c.root()
+	if err != nil {
+		return nil, err
+	}
+
+	job, _, err := findJobNode(root, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []*RelabelConfig{}
+	seq := MappingValue(job, kind)
+	if seq == nil {
+		return rules, nil
+	}
+	err = seq.Decode(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("field '%s' is invalid, %v", kind, err)
+	}
+
+	return rules, nil
+}
+
+// AddJobRelabelConfig 插入relabel规则到index位置，index小于0或超出范围时添加到最后
+func (c *ConfigYaml) AddJobRelabelConfig(jobName string, kind string, index int, rule *RelabelConfig) error {
+	node, err := relabelNode(kind, rule)
+	if err != nil {
+		return err
+	}
+
+	return c.updateJobRelabelConfigs(jobName, kind, func(seq *yaml.Node) error {
+		if index < 0 || index > len(seq.Content) {
+			index = len(seq.Content)
+		}
+
+		content := make([]*yaml.Node, 0, len(seq.Content)+1)
+		content = append(content, seq.Content[:index]...)
+		content = append(content, node)
+		seq.Content = append(content, seq.Content[index:]...)
+		return nil
+	})
+}
+
+// DelJobRelabelConfig 删除index位置的relabel规则，删除后没有规则时删除字段
+func (c *ConfigYaml) DelJobRelabelConfig(jobName string, kind string, index int) error {
+	return c.updateJobRelabelConfigs(jobName, kind, func(seq *yaml.Node) error {
+		if index < 0 || index >= len(seq.Content) {
+			return fmt.Errorf("%s index %d is out of range, job '%s' has %d rules", kind, index, jobName, len(seq.Content))
+		}
+
+		seq.Content = append(seq.Content[:index], seq.Content[index+1:]...)
+		return nil
+	})
+}
+
+// ReplaceJobRelabelConfig 替换index位置的relabel规则
+func (c *ConfigYaml) ReplaceJobRelabelConfig(jobName string, kind string, index int, rule *RelabelConfig) error {
+	node, err := relabelNode(kind, rule)
+	if err != nil {
+		return err
+	}
+
+	return c.updateJobRelabelConfigs(jobName, kind, func(seq *yaml.Node) error {
+		if index < 0 || index >= len(seq.Content) {
+			return fmt.Errorf("%s index %d is out of range, job '%s' has %d rules", kind, index, jobName, len(seq.Content))
+		}
+
+		node.HeadComment = seq.Content[index].HeadComment
+		node.LineComment = seq.Content[index].LineComment
+		seq.Content[index] = node
+		return nil
+	})
+}
+
+func relabelNode(kind string, rule *RelabelConfig) (*yaml.Node, error) {
+	err := checkRelabelKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	err = rule.CheckValid()
+	if err != nil {
+		return nil, err
+	}
+
+	return ToNode(rule)
+}
+
+func (c *ConfigYaml) updateJobRelabelConfigs(jobName string, kind string, fn func(seq *yaml.Node) error) error {
+	err := checkRelabelKind(kind)
+	if err != nil {
+		return err
+	}
+
+	return c.edit(func(root *yaml.Node) error {
+		job, _, err := findJobNode(root, jobName)
+		if err != nil {
+			return err
+		}
+
+		seq := editableValue(job, kind)
+		if seq == nil || seq.Kind != yaml.SequenceNode {
+			seq = newSequenceNode()
+			SetMappingValue(job, kind, seq)
+		}
+
+		err = fn(seq)
+		if err != nil {
+			return err
+		}
+
+		if len(seq.Content) == 0 {
+			DeleteMappingKey(job, kind)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package promConf
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var relabelData = []byte(`scrape_configs:
+  - job_name: node
+    static_configs:
+      - targets: ['10.0.0.1:9100']
+    metric_relabel_configs:
+      # drop go runtime metrics
+      - source_labels: [__name__]
+        regex: go_.*
+        action: drop
+`)
+
+func TestConfigYaml_JobRelabelConfigs(t *testing.T) {
+	c := NewConfigYaml(relabelData)
+
+	rules, err := c.GetJobRelabelConfigs("node", MetricRelabelConfigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Action != "drop" || rules[0].SourceLabels[0] != "__name__" {
+		t.Fatalf("got %v", rules)
+	}
+
+	// 插入到第一个位置
+	err = c.AddJobRelabelConfig("node", MetricRelabelConfigs, 0, &RelabelConfig{Action: "labeldrop", Regex: "tmp_.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, _ = c.GetJobRelabelConfigs("node", MetricRelabelConfigs)
+	if len(rules) != 2 || rules[0].Action != "labeldrop" || rules[1].Action != "drop" {
+		t.Fatalf("got %v", rules)
+	}
+
+	replacement := "${1}"
+	err = c.AddJobRelabelConfig("node", RelabelConfigs, -1, &RelabelConfig{
+		SourceLabels: []string{"__address__"},
+		Regex:        "([^:]+):.*",
+		TargetLabel:  "instance",
+		Replacement:  &replacement,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, _ = c.GetJobRelabelConfigs("node", RelabelConfigs)
+	if len(rules) != 1 || rules[0].TargetLabel != "instance" || *rules[0].Replacement != "${1}" {
+		t.Fatalf("got %v", rules)
+	}
+
+	err = c.ReplaceJobRelabelConfig("node", MetricRelabelConfigs, 1, &RelabelConfig{SourceLabels: []string{"__name__"}, Regex: "node_.*", Action: "keep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, _ = c.GetJobRelabelConfigs("node", MetricRelabelConfigs)
+	if rules[1].String() != "{source_labels: [__name__], regex: node_.*, action: keep}" {
+		t.Errorf("got %s", rules[1])
+	}
+
+	for _, index := range []int{1, 0} {
+		err = c.DelJobRelabelConfig("node", MetricRelabelConfigs, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if MappingKeyIndex(mustJobNode(t, c), "metric_relabel_configs") >= 0 {
+		t.Errorf("empty metric_relabel_configs should be deleted\n%s", c.Data)
+	}
+	if err = Validate(c.Data); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigYaml_JobRelabelConfigsInvalid(t *testing.T) {
+	c := NewConfigYaml(relabelData)
+
+	if err := c.AddJobRelabelConfig("node", MetricRelabelConfigs, -1, &RelabelConfig{Action: "drop", Regex: "go_(.*"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected invalid regex error, got %v", err)
+	}
+	if err := c.AddJobRelabelConfig("node", "relabel", -1, &RelabelConfig{Action: "drop"}); err == nil {
+		t.Error("expected unknown kind error")
+	}
+	if err := c.DelJobRelabelConfig("node", MetricRelabelConfigs, 1); err == nil {
+		t.Error("expected index out of range error")
+	}
+	if err := c.ReplaceJobRelabelConfig("node", RelabelConfigs, 0, &RelabelConfig{Action: "drop"}); err == nil {
+		t.Error("expected index out of range error")
+	}
+}
+
+func mustJobNode(t *testing.T, c *ConfigYaml) *yaml.Node {
+	root, err := c.root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, _, err := findJobNode(root, "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
@@ -0,0 +1,189 @@
+package promConf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prometheus报告配置加载状态的指标
+const (
+	metricReloadSuccessful = "prometheus_config_last_reload_successful"
+	metricReloadTimestamp  = "prometheus_config_last_reload_success_timestamp_seconds"
+)
+
+// ReloadStatus prometheus报告的配置加载状态
+type ReloadStatus struct {
+	Successful     bool      `json:"successful"`       // 最后一次加载配置是否成功
+	LastReloadTime time.Time `json:"last_reload_time"` // 最后一次成功加载配置的时间
+}
+
+// 确认配置已生效时读取/metrics的间隔，通过信号reload时prometheus异步加载配置
+const reloadPollInterval = 200 * time.Millisecond
+
+// ConfReload 使prometheus配置生效，不认证，使用默认超时时间
+func ConfReload(promURL string) error {
+	_, err := ConfReloadWithOptions(promURL, nil)
+	return err
+}
+
+// ConfReloadWithOptions 调用reload接口使prometheus配置生效，promURL为reload接口或prometheus的地址，
+// 例如 http://127.0.0.1:9090/-/reload，检查返回的状态码，再从/metrics读取配置加载状态确认
+// 配置已生效，失败时返回的错误为ErrReloadFailed类型
+func ConfReloadWithOptions(promURL string, options *ClientOptions) (*ReloadStatus, error) {
+	return confReload(promURL, options, func(client *promClient, baseURL string) error {
+		_, err := client.do(http.MethodPost, baseURL+"/-/reload")
+		if err != nil {
+			httpErr := &HTTPError{}
+			if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusForbidden {
+				return newReloadError(err, "%v, start prometheus with --web.enable-lifecycle to enable the reload api", err)
+			}
+			return newReloadError(err, "%v", err)
+		}
+		return nil
+	})
+}
+
+// ConfReloadWithTrigger 调用trigger使prometheus重新加载配置，例如通过ssh发送SIGHUP信号，
+// 再和reload接口一样从/metrics读取配置加载状态确认配置已生效，promURL用于访问/metrics，
+// 失败时返回的错误为ErrReloadFailed类型
+func ConfReloadWithTrigger(promURL string, options *ClientOptions, trigger func() error) (*ReloadStatus, error) {
+	return confReload(promURL, options, func(client *promClient, baseURL string) error {
+		err := trigger()
+		if err != nil {
+			return newReloadError(err, "%v", err)
+		}
+		return nil
+	})
+}
+
+func confReload(promURL string, options *ClientOptions, trigger func(client *promClient, baseURL string) error) (*ReloadStatus, error) {
+	err := checkURL(promURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newPromClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := PromBaseURL(promURL)
+	metricsURL := baseURL + "/metrics"
+
+	// reload前的加载时间，读取失败时只检查reload后的状态
+	before, _ := readReloadStatus(client, metricsURL)
+
+	err = trigger(client, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return waitReloadStatus(client, metricsURL, before)
+}
+
+// 读取reload后的配置加载状态，直到加载时间更新或超时，超时时间与请求的超时时间相同
+func waitReloadStatus(client *promClient, metricsURL string, before *ReloadStatus) (*ReloadStatus, error) {
+	deadline := time.Now().Add(client.client.Timeout)
+	for {
+		after, err := readReloadStatus(client, metricsURL)
+		if err != nil {
+			return nil, newReloadError(err, "can not confirm the reload, %v", err)
+		}
+
+		switch {
+		case before == nil:
+			// 没有reload前的加载时间，只检查加载状态
+			if after.Successful {
+				return after, nil
+			}
+		case after.LastReloadTime.After(before.LastReloadTime):
+			return after, nil
+		case !after.Successful && before.Successful:
+			// 加载失败时不更新加载时间
+			return after, newReloadError(nil, "%s is 0, prometheus could not load the configuration, see its logs for details",
+				metricReloadSuccessful)
+		}
+
+		if time.Now().Add(reloadPollInterval).After(deadline) {
+			if !after.Successful {
+				return after, newReloadError(nil, "%s is 0, prometheus could not load the configuration, see its logs for details",
+					metricReloadSuccessful)
+			}
+			return after, newReloadError(nil, "%s is not updated after reload, it is still %s",
+				metricReloadTimestamp, after.LastReloadTime.Format(time.RFC3339))
+		}
+		time.Sleep(reloadPollInterval)
+	}
+}
+
+// reload失败的错误，属于ErrReloadFailed类型，可以通过errors.As获取原因，例如*HTTPError
+type reloadError struct {
+	cause error
+	msg   string
+}
+
+func newReloadError(cause error, format string, args ...interface{}) error {
+	return &reloadError{cause: cause, msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *reloadError) Error() string {
+	return ErrReloadFailed.Error() + ": " + e.msg
+}
+
+func (e *reloadError) Is(target error) bool {
+	return target == ErrReloadFailed
+}
+
+func (e *reloadError) Unwrap() error {
+	return e.cause
+}
+
+// 从/metrics读取配置加载状态
+func readReloadStatus(client *promClient, metricsURL string) (*ReloadStatus, error) {
+	data, err := client.do(http.MethodGet, metricsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	values := parseMetrics(data, metricReloadSuccessful, metricReloadTimestamp)
+	successful, ok := values[metricReloadSuccessful]
+	if !ok {
+		return nil, fmt.Errorf("metric %s not found in %s", metricReloadSuccessful, metricsURL)
+	}
+	timestamp, ok := values[metricReloadTimestamp]
+	if !ok {
+		return nil, fmt.Errorf("metric %s not found in %s", metricReloadTimestamp, metricsURL)
+	}
+
+	sec, frac := math.Modf(timestamp)
+	return &ReloadStatus{
+		Successful:     successful == 1,
+		LastReloadTime: time.Unix(int64(sec), int64(frac*1e9)),
+	}, nil
+}
+
+// 解析prometheus文本格式的指标，只返回names中没有标签的指标值
+func parseMetrics(data []byte, names ...string) map[string]float64 {
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !isContainString(names, fields[0]) {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values
+}
@@ -0,0 +1,217 @@
+package promConf
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 模拟prometheus的reload接口和/metrics
+type fakePrometheus struct {
+	mu         sync.Mutex
+	lifecycle  bool    // 是否开启reload接口
+	loadOK     bool    // reload是否成功
+	timestamp  float64 // 最后一次成功加载配置的时间
+	authHeader string  // 要求的Authorization头，为空时不认证
+}
+
+func (p *fakePrometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.authHeader != "" && r.Header.Get("Authorization") != p.authHeader {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/-/reload":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !p.lifecycle {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "Lifecycle API is not enabled.")
+			return
+		}
+		if !p.loadOK {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "failed to reload config")
+			return
+		}
+		p.timestamp += 1.5
+
+	case "/metrics":
+		successful := 0
+		if p.loadOK {
+			successful = 1
+		}
+		fmt.Fprintf(w, "# HELP %s Whether the last configuration reload attempt was successful.\n", metricReloadSuccessful)
+		fmt.Fprintf(w, "%s %d\n", metricReloadSuccessful, successful)
+		fmt.Fprintf(w, "%s %s\n", metricReloadTimestamp, strconv.FormatFloat(p.timestamp, 'g', -1, 64))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestConfReloadWithOptions(t *testing.T) {
+	prom := &fakePrometheus{lifecycle: true, loadOK: true, timestamp: 1654900000.25}
+	server := httptest.NewServer(prom)
+	defer server.Close()
+
+	status, err := ConfReloadWithOptions(server.URL+"/-/reload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Successful || status.LastReloadTime.Unix() != 1654900001 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	// 只指定prometheus地址
+	if _, err = ConfReloadWithOptions(server.URL, nil); err != nil {
+		t.Error(err)
+	}
+
+	prom.lifecycle = false
+	_, err = ConfReloadWithOptions(server.URL+"/-/reload", nil)
+	if !errors.Is(err, ErrReloadFailed) || !strings.Contains(err.Error(), "--web.enable-lifecycle") {
+		t.Errorf("expected reload api disabled error, got %v", err)
+	}
+	httpErr := &HTTPError{}
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected HTTPError with status 403, got %v", err)
+	}
+
+	prom.lifecycle, prom.loadOK = true, false
+	_, err = ConfReloadWithOptions(server.URL+"/-/reload", nil)
+	if !errors.Is(err, ErrReloadFailed) || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected reload failed error, got %v", err)
+	}
+
+	if _, err = ConfReloadWithOptions("127.0.0.1:9090", nil); err == nil {
+		t.Error("expected error for invalid url")
+	}
+}
+
+func TestConfReloadWithOptions_Auth(t *testing.T) {
+	prom := &fakePrometheus{lifecycle: true, loadOK: true, authHeader: "Bearer secret"}
+	server := httptest.NewTLSServer(prom)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	err = ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有CA证书时校验服务端证书失败
+	_, err = ConfReloadWithOptions(server.URL, &ClientOptions{BearerToken: "secret"})
+	if err == nil {
+		t.Error("expected certificate error")
+	}
+
+	_, err = ConfReloadWithOptions(server.URL, &ClientOptions{BearerTokenFile: tokenFile, CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ConfReloadWithOptions(server.URL, &ClientOptions{BearerToken: "secret", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ConfReloadWithOptions(server.URL, &ClientOptions{BearerToken: "wrong", CAFile: caFile})
+	httpErr := &HTTPError{}
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected HTTPError with status 401, got %v", err)
+	}
+
+	prom.authHeader = "Basic YWRtaW46MTIzNDU2" // admin:123456
+	_, err = ConfReloadWithOptions(server.URL, &ClientOptions{Username: "admin", Password: "123456", CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ConfReloadWithOptions(server.URL, &ClientOptions{CertFile: "client.pem"})
+	if err == nil {
+		t.Error("expected error for client certificate without key")
+	}
+}
+
+func TestReadReloadStatus_NotUpdated(t *testing.T) {
+	// reload接口返回成功，但加载时间没有更新
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			fmt.Fprintf(w, "%s 1\n%s 1.6549e+09\n", metricReloadSuccessful, metricReloadTimestamp)
+		}
+	}))
+	defer server.Close()
+
+	_, err := ConfReloadWithOptions(server.URL, &ClientOptions{Timeout: time.Second})
+	if !errors.Is(err, ErrReloadFailed) || !strings.Contains(err.Error(), "not updated") {
+		t.Errorf("expected not updated error, got %v", err)
+	}
+}
+
+func TestConfReloadWithTrigger(t *testing.T) {
+	prom := &fakePrometheus{loadOK: true, timestamp: 1654900000}
+	server := httptest.NewServer(prom)
+	defer server.Close()
+
+	// 模拟SIGHUP，prometheus异步加载配置
+	status, err := ConfReloadWithTrigger(server.URL, nil, func() error {
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			prom.mu.Lock()
+			prom.timestamp += 2
+			prom.mu.Unlock()
+		}()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.LastReloadTime.Unix() != 1654900002 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	// 加载失败
+	_, err = ConfReloadWithTrigger(server.URL, nil, func() error {
+		prom.mu.Lock()
+		prom.loadOK = false
+		prom.mu.Unlock()
+		return nil
+	})
+	if !errors.Is(err, ErrReloadFailed) || !strings.Contains(err.Error(), "could not load") {
+		t.Errorf("expected load failed error, got %v", err)
+	}
+
+	// trigger失败
+	_, err = ConfReloadWithTrigger(server.URL, nil, func() error {
+		return fmt.Errorf("pkill: no process found")
+	})
+	if !errors.Is(err, ErrReloadFailed) || !strings.Contains(err.Error(), "no process found") {
+		t.Errorf("expected trigger error, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package promConf
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// --------------------------------- job 重命名、复制、移动target ---------------------------------
+
+// RenameJob 修改job名称，job的其他字段和注释保持不变
+func (c *ConfigYaml) RenameJob(oldName string, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("new job name is empty")
+	}
+	if oldName == newName {
+		return nil
+	}
+
+	return c.edit(func(root *yaml.Node) error {
+		if _, _, err := findJobNode(root, newName); err == nil {
+			return newError(ErrDuplicateJob, newName, jobPath(newName), "job '%s' already exists", newName)
+		}
+
+		_, index, err := findJobNode(root, oldName)
+		if err != nil {
+			return err
+		}
+
+		job := editableJobNode(root, index)
+		SetMappingValue(job, "job_name", newScalarNode(newName))
+		return nil
+	})
+}
+
+// CloneJob 复制job，新job添加到原job后面，withoutTargets为true时清空static_configs分组的target，
+// 保留分组的标签，其他服务发现配置(例如file_sd_configs)原样复制
+func (c *ConfigYaml) CloneJob(srcName string, dstName string, withoutTargets bool) error {
+	if dstName == "" {
+		return fmt.Errorf("new job name is empty")
+	}
+
+	return c.edit(func(root *yaml.Node) error {
+		if _, _, err := findJobNode(root, dstName); err == nil {
+			return newError(ErrDuplicateJob, dstName, jobPath(dstName), "job '%s' already exists", dstName)
+		}
+
+		src, index, err := findJobNode(root, srcName)
+		if err != nil {
+			return err
+		}
+
+		job := copyNode(src)
+		job.HeadComment = ""
+		job.FootComment = ""
+		SetMappingValue(job, "job_name", newScalarNode(dstName))
+
+		if withoutTargets {
+			groups, err := decodeGroups(MappingValue(job, "static_configs"))
+			if err != nil {
+				return err
+			}
+			if len(groups) > 0 {
+				for i := range groups {
+					groups[i].Targets = []string{}
+				}
+				setJobGroups(job, groups)
+			}
+		}
+
+		seq := editableValue(root, "scrape_configs")
+		content := make([]*yaml.Node, 0, len(seq.Content)+1)
+		content = append(content, seq.Content[:index+1]...)
+		content = append(content, job)
+		seq.Content = append(content, seq.Content[index+1:]...)
+
+		return nil
+	})
+}
+
+// MoveJobTargets 把target从一个job移动到另一个job，target必须在源job的static_configs中，
+// sel为nil时保留target在源job分组中的标签，添加到目标job标签相同的分组，没有则新建分组，
+// sel不为nil时添加到目标job选择器匹配的分组，源job移动后为空的分组会被删除，但至少保留一个分组
+func (c *ConfigYaml) MoveJobTargets(fromJob string, toJob string, sel *GroupSelector, targets []string) error {
+	if fromJob == toJob {
+		return fmt.Errorf("source and destination job are both '%s'", fromJob)
+	}
+	targets = removeDuplicate(targets)
+
+	return c.edit(func(root *yaml.Node) error {
+		_, fromIndex, err := findJobNode(root, fromJob)
+		if err != nil {
+			return err
+		}
+		_, toIndex, err := findJobNode(root, toJob)
+		if err != nil {
+			return err
+		}
+		from := editableJobNode(root, fromIndex)
+		to := editableJobNode(root, toIndex)
+
+		fromGroups, err := decodeGroups(MappingValue(from, "static_configs"))
+		if err != nil {
+			return err
+		}
+		toGroups, err := decodeGroups(MappingValue(to, "static_configs"))
+		if err != nil {
+			return err
+		}
+
+		// 从源job的分组中移除target，记录target所在分组的标签
+		moved := map[string]bool{}
+		newFromGroups := []StaticConfigs{}
+		movedGroups := []StaticConfigs{}
+		for _, group := range fromGroups {
+			remain := []string{}
+			movedTargets := []string{}
+			for _, target := range group.Targets {
+				if isContainString(targets, target) {
+					moved[target] = true
+					movedTargets = append(movedTargets, target)
+				} else {
+					remain = append(remain, target)
+				}
+			}
+			if len(movedTargets) > 0 {
+				movedGroups = append(movedGroups, StaticConfigs{Targets: movedTargets, Labels: group.Labels})
+				if len(remain) == 0 {
+					continue
+				}
+			}
+			group.Targets = remain
+			newFromGroups = append(newFromGroups, group)
+		}
+		for _, target := range targets {
+			if !moved[target] {
+				return newError(ErrTargetsNotFound, fromJob, jobPath(fromJob)+".static_configs",
+					"target '%s' not found in static_configs of job '%s'", target, fromJob)
+			}
+		}
+
+		for _, group := range movedGroups {
+			if sel != nil {
+				toGroups, err = addGroupTargets(toGroups, sel, group.Targets)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			toGroups = addTargetsWithLabels(toGroups, group.Targets, group.Labels)
+		}
+
+		// 源job所有target都被移走时保留第一个分组，targets为空
+		if len(newFromGroups) == 0 && len(fromGroups) > 0 {
+			fromGroups[0].Targets = []string{}
+			newFromGroups = fromGroups[:1]
+		}
+		setJobGroups(from, newFromGroups)
+		setJobGroups(to, toGroups)
+
+		return nil
+	})
+}
+
+// 获取scrape_configs中第index个可修改的job节点，别名节点替换为锚点内容的副本
+func editableJobNode(root *yaml.Node, index int) *yaml.Node {
+	seq := editableValue(root, "scrape_configs")
+	job := seq.Content[index]
+	if job.Kind == yaml.AliasNode {
+		job = copyNode(job)
+		seq.Content[index] = job
+	}
+	return job
+}
+
+func isContainString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
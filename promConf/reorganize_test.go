@@ -0,0 +1,140 @@
+package promConf
+
+import (
+	"strings"
+	"testing"
+)
+
+var reorganizeData = []byte(`scrape_configs:
+  # node exporter
+  - job_name: node
+    scrape_interval: 30s # slow
+    consul_sd_configs:
+      - server: 127.0.0.1:8500
+    static_configs:
+      - targets: ['10.0.0.1:9100', '10.0.0.2:9100']
+        labels:
+          env: prod
+      - targets: ['10.0.0.3:9100']
+
+  - job_name: mysqld
+    static_configs:
+      - targets: ['10.0.0.1:9104']
+`)
+
+func TestConfigYaml_RenameJob(t *testing.T) {
+	c := NewConfigYaml(reorganizeData)
+	err := c.RenameJob("node", "node_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Replace(string(reorganizeData), "job_name: node\n", "job_name: node_exporter\n", 1)
+	if string(c.Data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+
+	if err = c.RenameJob("node_exporter", "mysqld"); err == nil {
+		t.Error("expected error for existing job")
+	}
+	if err = c.RenameJob("node", "node2"); err == nil {
+		t.Error("expected error for not found job")
+	}
+}
+
+func TestConfigYaml_CloneJob(t *testing.T) {
+	c := NewConfigYaml(reorganizeData)
+	err := c.CloneJob("node", "node_dev", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jc, err := c.GetJobConfig("node_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jc.ScrapeInterval != "30s" || jc.Extra["consul_sd_configs"] == nil {
+		t.Errorf("unexpected job %+v", jc)
+	}
+	if len(jc.StaticConfigs) != 2 || len(jc.StaticConfigs[0].Targets) != 0 || jc.StaticConfigs[0].Labels["env"] != "prod" {
+		t.Errorf("unexpected static_configs %+v", jc.StaticConfigs)
+	}
+
+	// 新job在原job后面，原job不变
+	data := string(c.Data)
+	if !strings.Contains(data, "  - job_name: node\n") || strings.Index(data, "job_name: node_dev") > strings.Index(data, "job_name: mysqld") {
+		t.Errorf("got\n%s", data)
+	}
+	if strings.Count(data, "# node exporter") != 1 {
+		t.Errorf("head comment should not be copied\n%s", data)
+	}
+
+	err = c.CloneJob("mysqld", "mysqld2", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, _ := c.GetJobTargets("mysqld2")
+	if len(targets) != 1 || targets[0] != "10.0.0.1:9104" {
+		t.Errorf("got %v", targets)
+	}
+
+	if err = c.CloneJob("node", "mysqld", false); err == nil {
+		t.Error("expected error for existing job")
+	}
+}
+
+func TestConfigYaml_MoveJobTargets(t *testing.T) {
+	c := NewConfigYaml(reorganizeData)
+	err := c.MoveJobTargets("node", "mysqld", nil, []string{"10.0.0.2:9100", "10.0.0.3:9100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `scrape_configs:
+  # node exporter
+  - job_name: node
+    scrape_interval: 30s # slow
+    consul_sd_configs:
+      - server: 127.0.0.1:8500
+    static_configs:
+      - targets: ['10.0.0.1:9100']
+        labels:
+          env: prod
+
+  - job_name: mysqld
+    static_configs:
+      - targets: ['10.0.0.1:9104', '10.0.0.3:9100']
+      - targets:
+          - 10.0.0.2:9100
+        labels:
+          env: prod
+`
+	if string(c.Data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+
+	// 指定目标分组，源job保留空分组
+	err = c.MoveJobTargets("node", "mysqld", &GroupSelector{Index: 0}, []string{"10.0.0.1:9100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := c.GetJobGroups("node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || len(groups[0].Targets) != 0 {
+		t.Errorf("got %+v", groups)
+	}
+	targets, _ := c.GetJobGroupTargets("mysqld", &GroupSelector{Index: 0})
+	if len(targets) != 3 || targets[2] != "10.0.0.1:9100" {
+		t.Errorf("got %v", targets)
+	}
+
+	before := string(c.Data)
+	if err = c.MoveJobTargets("mysqld", "node", nil, []string{"10.0.0.1:9104", "10.0.0.9:9100"}); err == nil {
+		t.Error("expected error for not found target")
+	}
+	if string(c.Data) != before {
+		t.Error("data should not be changed when moving failed")
+	}
+}
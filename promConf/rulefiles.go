@@ -0,0 +1,88 @@
+package promConf
+
+import (
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleFiles prometheus配置中的rule_files字段
+const RuleFiles = "rule_files"
+
+// GetRuleFiles 获取rule_files中的规则文件路径，路径可以包含通配符
+func (c *ConfigYaml) GetRuleFiles() ([]string, error) {
+	cfg, err := c.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.RuleFiles, nil
+}
+
+// AddRuleFiles 添加规则文件路径到rule_files，已存在的路径不会重复添加，
+// rule_files不存在时添加到scrape_configs前面
+func (c *ConfigYaml) AddRuleFiles(files []string) error {
+	return c.updateRuleFiles(func(oldFiles []string) []string {
+		return addSliceElements(oldFiles, files)
+	})
+}
+
+// DelRuleFiles 从rule_files删除规则文件路径，删除后为空时移除rule_files
+func (c *ConfigYaml) DelRuleFiles(files []string) error {
+	return c.updateRuleFiles(func(oldFiles []string) []string {
+		return delSliceElements(oldFiles, files)
+	})
+}
+
+func (c *ConfigYaml) updateRuleFiles(fn func([]string) []string) error {
+	return c.edit(func(root *yaml.Node) error {
+		oldFiles := []string{}
+		seq := editableValue(root, RuleFiles)
+		if seq != nil && seq.Kind == yaml.SequenceNode {
+			err := seq.Decode(&oldFiles)
+			if err != nil {
+				return err
+			}
+		}
+
+		files := fn(oldFiles)
+		if len(files) == 0 {
+			DeleteMappingKey(root, RuleFiles)
+			return nil
+		}
+
+		if seq == nil {
+			seq = newSequenceNode()
+			InsertMappingValue(root, MappingKeyIndex(root, "scrape_configs"), RuleFiles, seq)
+		} else if seq.Kind != yaml.SequenceNode {
+			seq = newSequenceNode()
+			SetMappingValue(root, RuleFiles, seq)
+		}
+		updateStringSequence(seq, files)
+		return nil
+	})
+}
+
+// MatchRuleFiles 判断规则文件是否被rule_files中的路径或通配符匹配，
+// dir为prometheus配置文件所在目录，相对路径以dir为基准
+func MatchRuleFiles(patterns []string, dir string, file string) bool {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		pattern, err = filepath.Abs(pattern)
+		if err != nil {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, file); ok {
+			return true
+		}
+	}
+
+	return false
+}
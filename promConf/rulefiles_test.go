@@ -0,0 +1,92 @@
+package promConf
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigYaml_RuleFiles(t *testing.T) {
+	data := []byte(`global:
+  evaluation_interval: 15s
+scrape_configs:
+  - job_name: prometheus
+    static_configs:
+      - targets: ['localhost:9090']
+`)
+	c := NewConfigYaml(data)
+	err := c.AddRuleFiles([]string{"rules/node.yml", "rules/mysql.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.AddRuleFiles([]string{"rules/node.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `global:
+  evaluation_interval: 15s
+rule_files:
+  - rules/node.yml
+  - rules/mysql.yml
+scrape_configs:
+  - job_name: prometheus
+    static_configs:
+      - targets: ['localhost:9090']
+`
+	if string(c.Data) != expected {
+		t.Fatalf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+
+	files, err := c.GetRuleFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 rule files, got %v", files)
+	}
+
+	err = c.DelRuleFiles([]string{"rules/node.yml", "rules/mysql.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(c.Data) != string(data) {
+		t.Fatalf("got\n%s\nexpected\n%s", c.Data, data)
+	}
+}
+
+func TestConfigYaml_AddRuleFilesToEmpty(t *testing.T) {
+	c := NewConfigYaml([]byte(`rule_files:
+  # - "first_rules.yml"
+
+scrape_configs: []
+`))
+	err := c.AddRuleFiles([]string{"first_rules.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := c.GetRuleFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != "first_rules.yml" {
+		t.Fatalf("unexpected rule files %v\n%s", files, c.Data)
+	}
+}
+
+func TestMatchRuleFiles(t *testing.T) {
+	dir := filepath.Join("testdata", "prometheus")
+	patterns := []string{"rules/*.yml", "/etc/prometheus/alert.yml"}
+
+	testData := map[string]bool{
+		filepath.Join(dir, "rules", "node.yml"):  true,
+		filepath.Join(dir, "rules", "node.yaml"): false,
+		filepath.Join(dir, "node.yml"):           false,
+		"/etc/prometheus/alert.yml":              true,
+	}
+	for file, expected := range testData {
+		if got := MatchRuleFiles(patterns, dir, file); got != expected {
+			t.Errorf("MatchRuleFiles(%s) = %v, expected %v", file, got, expected)
+		}
+	}
+}
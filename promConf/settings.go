@@ -0,0 +1,265 @@
+package promConf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// job的抓取设置名称
+const (
+	SettingScrapeInterval = "scrape_interval"
+	SettingScrapeTimeout  = "scrape_timeout"
+	SettingMetricsPath    = "metrics_path"
+	SettingScheme         = "scheme"
+	SettingHonorLabels    = "honor_labels"
+	SettingParams         = "params"
+
+	// 设置params的某个参数时，名称为 params.参数名，例如 params.module
+	settingParamsPrefix = SettingParams + "."
+)
+
+// SettingNames 支持修改的job抓取设置，按在job中的排列顺序
+var SettingNames = []string{
+	SettingScrapeInterval,
+	SettingScrapeTimeout,
+	SettingMetricsPath,
+	SettingScheme,
+	SettingHonorLabels,
+	SettingParams,
+}
+
+// GetJobSettings 获取job已配置的抓取设置，params的每个参数为一项，名称为 params.参数名，多个值用逗号分隔
+func (c *ConfigYaml) GetJobSettings(jobName string) (map[string]string, error) {
+	root, err := c.root()
+	if err != nil {
+		return nil, err
+	}
+
+	job, _, err := findJobNode(root, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]string{}
+	for _, name := range SettingNames {
+		value := MappingValue(job, name)
+		if value == nil {
+			continue
+		}
+
+		if name != SettingParams {
+			settings[name] = value.Value
+			continue
+		}
+
+		params := map[string][]string{}
+		err = value.Decode(&params)
+		if err != nil {
+			return nil, fmt.Errorf("field 'params' is invalid, %v", err)
+		}
+		for k, v := range params {
+			settings[settingParamsPrefix+k] = strings.Join(v, ",")
+		}
+	}
+
+	return settings, nil
+}
+
+// ReplaceJobSettings 修改job的抓取设置，没有的设置添加到job_name后面，
+// params的参数名称为 params.参数名，例如 params.module=http_2xx
+func (c *ConfigYaml) ReplaceJobSettings(jobName string, settings map[string]string) error {
+	names := []string{}
+	for name, value := range settings {
+		err := checkSetting(name, value)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	sortSettingNames(names)
+
+	return c.edit(func(root *yaml.Node) error {
+		job, _, err := findJobNode(root, jobName)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if strings.HasPrefix(name, settingParamsPrefix) {
+				setJobParam(job, strings.TrimPrefix(name, settingParamsPrefix), settings[name])
+				continue
+			}
+			setJobSetting(job, name, settingNode(name, settings[name]))
+		}
+
+		return nil
+	})
+}
+
+// DelJobSettings 删除job的抓取设置，删除后使用全局配置或默认值，
+// params.参数名 删除某个参数，params 删除所有参数
+func (c *ConfigYaml) DelJobSettings(jobName string, names []string) error {
+	for _, name := range names {
+		if !isSettingName(name) {
+			return unknownSettingError(name)
+		}
+	}
+
+	return c.edit(func(root *yaml.Node) error {
+		job, _, err := findJobNode(root, jobName)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if !strings.HasPrefix(name, settingParamsPrefix) {
+				DeleteMappingKey(job, name)
+				continue
+			}
+
+			params := editableValue(job, SettingParams)
+			if params == nil || params.Kind != yaml.MappingNode {
+				continue
+			}
+			DeleteMappingKey(params, strings.TrimPrefix(name, settingParamsPrefix))
+			if len(params.Content) == 0 {
+				DeleteMappingKey(job, SettingParams)
+			}
+		}
+
+		return nil
+	})
+}
+
+// 检查设置的名称和值
+func checkSetting(name string, value string) error {
+	switch name {
+	case SettingScrapeInterval, SettingScrapeTimeout:
+		d, err := ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if d == 0 {
+			return fmt.Errorf("%s: duration must be greater than 0", name)
+		}
+
+	case SettingMetricsPath:
+		if !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("%s: '%s' must start with '/'", name, value)
+		}
+
+	case SettingScheme:
+		if value != "http" && value != "https" {
+			return fmt.Errorf("%s: unknown scheme '%s', only supports http and https", name, value)
+		}
+
+	case SettingHonorLabels:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: '%s' is not a boolean, eg: true or false", name, value)
+		}
+
+	case SettingParams:
+		return fmt.Errorf("%s: please specify the parameter name, eg: params.module=http_2xx", name)
+
+	default:
+		if !strings.HasPrefix(name, settingParamsPrefix) || name == settingParamsPrefix {
+			return unknownSettingError(name)
+		}
+		for _, v := range strings.Split(value, ",") {
+			if strings.TrimSpace(v) == "" {
+				return fmt.Errorf("%s: value is empty", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isSettingName(name string) bool {
+	if strings.HasPrefix(name, settingParamsPrefix) {
+		return name != settingParamsPrefix
+	}
+	for _, v := range SettingNames {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func unknownSettingError(name string) error {
+	return fmt.Errorf("unknown setting '%s', supports: %s, params.<name>", name, strings.Join(SettingNames[:len(SettingNames)-1], ", "))
+}
+
+// 按在job中的排列顺序排序，params的参数排在最后
+func sortSettingNames(names []string) {
+	order := func(name string) int {
+		for i, v := range SettingNames {
+			if v == name {
+				return i
+			}
+		}
+		return len(SettingNames)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := order(names[i]), order(names[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+}
+
+func settingNode(name string, value string) *yaml.Node {
+	node := newScalarNode(value)
+	if name == SettingHonorLabels {
+		b, _ := strconv.ParseBool(value)
+		node.Tag = "!!bool"
+		node.Value = strconv.FormatBool(b)
+	}
+	return node
+}
+
+// 修改job的设置，不存在时插入到job_name及已有设置的后面
+func setJobSetting(job *yaml.Node, name string, value *yaml.Node) {
+	if MappingKeyIndex(job, name) >= 0 {
+		SetMappingValue(job, name, value)
+		return
+	}
+
+	index := MappingKeyIndex(job, "job_name")
+	for _, v := range SettingNames {
+		if v == name {
+			break
+		}
+		if i := MappingKeyIndex(job, v); i > index {
+			index = i
+		}
+	}
+	InsertMappingValue(job, index+2, name, value)
+}
+
+// 修改job的params参数，多个值用逗号分隔，与GetJobSettings获取的格式一致
+func setJobParam(job *yaml.Node, name string, value string) {
+	params := editableValue(job, SettingParams)
+	if params == nil || params.Kind != yaml.MappingNode {
+		params = newMappingNode()
+		setJobSetting(job, SettingParams, params)
+	}
+
+	seq := editableValue(params, name)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		seq = newSequenceNode()
+		seq.Style = yaml.FlowStyle
+		SetMappingValue(params, name, seq)
+	}
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		values = append(values, strings.TrimSpace(v))
+	}
+	updateStringSequence(seq, values)
+}
@@ -0,0 +1,113 @@
+package promConf
+
+import (
+	"strings"
+	"testing"
+)
+
+var settingsData = []byte(`scrape_configs:
+  # blackbox
+  - job_name: blackbox
+    scrape_interval: 15s # fast
+    static_configs:
+      - targets: ['10.0.0.1:9100']
+`)
+
+func TestConfigYaml_ReplaceJobSettings(t *testing.T) {
+	c := NewConfigYaml(settingsData)
+	err := c.ReplaceJobSettings("blackbox", map[string]string{
+		"scrape_interval": "30s",
+		"metrics_path":    "/probe",
+		"honor_labels":    "true",
+		"params.module":   "http_2xx",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `scrape_configs:
+  # blackbox
+  - job_name: blackbox
+    scrape_interval: 30s # fast
+    metrics_path: /probe
+    honor_labels: true
+    params:
+      module: [http_2xx]
+    static_configs:
+      - targets: ['10.0.0.1:9100']
+`
+	if string(c.Data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+
+	settings, err := c.GetJobSettings("blackbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settings) != 4 || settings["honor_labels"] != "true" || settings["params.module"] != "http_2xx" {
+		t.Errorf("got %v", settings)
+	}
+
+	err = c.DelJobSettings("blackbox", []string{"params.module", "metrics_path", "honor_labels"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(c.Data), "scrape_interval: 30s # fast") || strings.Contains(string(c.Data), "params") {
+		t.Errorf("got\n%s", c.Data)
+	}
+}
+
+func TestConfigYaml_ReplaceJobSettingsMultiParams(t *testing.T) {
+	c := NewConfigYaml(settingsData)
+	err := c.ReplaceJobSettings("blackbox", map[string]string{"params.module": "http_2xx,tcp_connect"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(c.Data), "    params:\n      module: [http_2xx, tcp_connect]\n") {
+		t.Errorf("got\n%s", c.Data)
+	}
+
+	// 获取的设置可以再写入
+	settings, err := c.GetJobSettings("blackbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings["params.module"] != "http_2xx,tcp_connect" {
+		t.Errorf("got %v", settings)
+	}
+	data := string(c.Data)
+	err = c.ReplaceJobSettings("blackbox", settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(c.Data) != data {
+		t.Errorf("got\n%s\nexpected\n%s", c.Data, data)
+	}
+}
+
+func TestConfigYaml_ReplaceJobSettingsInvalid(t *testing.T) {
+	testData := []map[string]string{
+		{"scrape_interval": "30"},
+		{"scrape_timeout": "0s"},
+		{"metrics_path": "probe"},
+		{"scheme": "ftp"},
+		{"honor_labels": "yes please"},
+		{"params": "x"},
+		{"params.": "x"},
+		{"params.module": ""},
+		{"params.module": "a,"},
+		{"job_name": "x"},
+	}
+
+	for _, settings := range testData {
+		c := NewConfigYaml(settingsData)
+		if err := c.ReplaceJobSettings("blackbox", settings); err == nil {
+			t.Errorf("%v: expected error", settings)
+		}
+	}
+
+	c := NewConfigYaml(settingsData)
+	if err := c.DelJobSettings("blackbox", []string{"static_configs"}); err == nil {
+		t.Error("expected error for unknown setting")
+	}
+}
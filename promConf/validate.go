@@ -0,0 +1,434 @@
+package promConf
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认的抓取间隔和超时时间，与prometheus一致
+const (
+	defaultScrapeInterval = time.Minute
+	defaultScrapeTimeout  = 10 * time.Second
+)
+
+var (
+	labelNameRe   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	targetLabelRe = regexp.MustCompile(`^(?:(?:[a-zA-Z_]|\$(?:\{\w+\}|\w+))+\w*)+$`)
+	durationRe    = regexp.MustCompile(`^(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?$`)
+
+	relabelActions = map[string]bool{
+		"replace": true, "keep": true, "drop": true, "keepequal": true, "dropequal": true, "hashmod": true,
+		"labelmap": true, "labeldrop": true, "labelkeep": true, "lowercase": true, "uppercase": true,
+	}
+)
+
+// ValidateError 配置校验错误，包含检查出的所有问题
+type ValidateError struct {
+	Problems []string
+}
+
+func (e *ValidateError) Error() string {
+	return "invalid prometheus configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Is 校验错误属于ErrInvalidConfig类型
+func (e *ValidateError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidateError{Problems: v.problems}
+}
+
+// Validate 离线校验prometheus配置，检查job名称重复、抓取时间、target地址、标签名称、
+// relabel规则和scheme等，返回的错误包含所有问题
+func Validate(data []byte) error {
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return err
+	}
+
+	return cfg.Validate()
+}
+
+// Validate 校验prometheus配置
+func (c *ConfigYaml) Validate() error {
+	return Validate(c.Data)
+}
+
+// Validate 校验prometheus配置
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	globalInterval, globalTimeout := defaultScrapeInterval, defaultScrapeTimeout
+	if c.Global != nil {
+		globalInterval = v.checkDuration("global.scrape_interval", c.Global.ScrapeInterval, globalInterval)
+		globalTimeout = v.checkDuration("global.scrape_timeout", c.Global.ScrapeTimeout, globalTimeout)
+		v.checkDuration("global.evaluation_interval", c.Global.EvaluationInterval, 0)
+		if globalTimeout > globalInterval {
+			v.addf("global", "scrape_timeout %s is greater than scrape_interval %s", globalTimeout, globalInterval)
+		}
+		v.checkLabels("global.external_labels", c.Global.ExternalLabels)
+	}
+
+	if c.Alerting != nil {
+		v.checkRelabelConfigs("alerting.alert_relabel_configs", c.Alerting.AlertRelabelConfigs)
+		for i, am := range c.Alerting.AlertmanagerConfigs {
+			if am == nil {
+				continue
+			}
+			path := fmt.Sprintf("alerting.alertmanagers[%d]", i)
+			v.checkScheme(path, am.Scheme)
+			v.checkDuration(path+".timeout", am.Timeout, 0)
+			v.checkHTTPClientConfig(path, &am.HTTPClientConfig)
+			v.checkStaticConfigs(path, am.StaticConfigs, true)
+			v.checkFileSDConfigs(path, am.FileSDConfigs)
+			v.checkRelabelConfigs(path+".relabel_configs", am.RelabelConfigs)
+		}
+	}
+
+	for i, file := range c.RuleFiles {
+		if _, err := filepath.Match(file, ""); err != nil {
+			v.addf(fmt.Sprintf("rule_files[%d]", i), "invalid file pattern '%s': %v", file, err)
+		}
+	}
+
+	jobNames := map[string]bool{}
+	for i, job := range c.ScrapeConfigs {
+		if job == nil {
+			continue
+		}
+		path := fmt.Sprintf("scrape_configs[%d]", i)
+		if job.JobName == "" {
+			v.addf(path, "job_name is empty")
+		} else {
+			path = fmt.Sprintf("scrape_configs[job=%s]", job.JobName)
+			if jobNames[job.JobName] {
+				v.addf(path, "job_name is duplicated")
+			}
+			jobNames[job.JobName] = true
+		}
+		v.checkJob(path, job, globalInterval, globalTimeout)
+	}
+
+	for i, rw := range c.RemoteWriteConfigs {
+		if rw == nil {
+			continue
+		}
+		path := fmt.Sprintf("remote_write[%d]", i)
+		v.checkRemoteURL(path, rw.URL)
+		v.checkDuration(path+".remote_timeout", rw.RemoteTimeout, 0)
+		v.checkHTTPClientConfig(path, &rw.HTTPClientConfig)
+		v.checkRelabelConfigs(path+".write_relabel_configs", rw.WriteRelabelConfigs)
+	}
+
+	for i, rr := range c.RemoteReadConfigs {
+		if rr == nil {
+			continue
+		}
+		path := fmt.Sprintf("remote_read[%d]", i)
+		v.checkRemoteURL(path, rr.URL)
+		v.checkDuration(path+".remote_timeout", rr.RemoteTimeout, 0)
+		v.checkHTTPClientConfig(path, &rr.HTTPClientConfig)
+	}
+
+	return v.err()
+}
+
+func (v *validator) checkJob(path string, job *JobConfig, globalInterval time.Duration, globalTimeout time.Duration) {
+	interval := v.checkDuration(path+".scrape_interval", job.ScrapeInterval, globalInterval)
+	timeout := v.checkDuration(path+".scrape_timeout", job.ScrapeTimeout, 0)
+	if timeout == 0 {
+		// 没有设置时使用全局超时时间，但不能超过job的抓取间隔
+		timeout = globalTimeout
+		if timeout > interval {
+			timeout = interval
+		}
+	}
+	if timeout > interval {
+		v.addf(path, "scrape_timeout %s is greater than scrape_interval %s", timeout, interval)
+	}
+
+	v.checkScheme(path, job.Scheme)
+	if job.MetricsPath != "" && !strings.HasPrefix(job.MetricsPath, "/") {
+		v.addf(path+".metrics_path", "'%s' must start with '/'", job.MetricsPath)
+	}
+	v.checkHTTPClientConfig(path, &job.HTTPClientConfig)
+
+	// relabel改写了__address__时，target可以不是host:port，例如blackbox的url
+	isCheckAddress := true
+	for _, rc := range job.RelabelConfigs {
+		if rc != nil && rc.TargetLabel == "__address__" {
+			isCheckAddress = false
+		}
+	}
+	v.checkStaticConfigs(path, job.StaticConfigs, isCheckAddress)
+	v.checkFileSDConfigs(path, job.FileSDConfigs)
+	v.checkRelabelConfigs(path+".relabel_configs", job.RelabelConfigs)
+	v.checkRelabelConfigs(path+".metric_relabel_configs", job.MetricRelabelConfigs)
+}
+
+// 校验时间，为空时返回默认值
+func (v *validator) checkDuration(path string, s string, defaultValue time.Duration) time.Duration {
+	if s == "" {
+		return defaultValue
+	}
+
+	d, err := ParseDuration(s)
+	if err != nil {
+		v.addf(path, "%v", err)
+		return defaultValue
+	}
+	if d == 0 {
+		v.addf(path, "duration must be greater than 0")
+		return defaultValue
+	}
+
+	return d
+}
+
+func (v *validator) checkScheme(path string, scheme string) {
+	if scheme != "" && scheme != "http" && scheme != "https" {
+		v.addf(path+".scheme", "unknown scheme '%s', only supports http and https", scheme)
+	}
+}
+
+func (v *validator) checkHTTPClientConfig(path string, hc *HTTPClientConfig) {
+	authCount := 0
+	if hc.BasicAuth != nil {
+		authCount++
+		if hc.BasicAuth.Password != "" && hc.BasicAuth.PasswordFile != "" {
+			v.addf(path+".basic_auth", "at most one of password and password_file must be configured")
+		}
+	}
+	if hc.Authorization != nil {
+		authCount++
+		if hc.Authorization.Credentials != "" && hc.Authorization.CredentialsFile != "" {
+			v.addf(path+".authorization", "at most one of credentials and credentials_file must be configured")
+		}
+	}
+	if hc.BearerToken != "" || hc.BearerTokenFile != "" {
+		authCount++
+		if hc.BearerToken != "" && hc.BearerTokenFile != "" {
+			v.addf(path, "at most one of bearer_token and bearer_token_file must be configured")
+		}
+	}
+	if authCount > 1 {
+		v.addf(path, "at most one of basic_auth, authorization and bearer_token must be configured")
+	}
+
+	if hc.ProxyURL != "" {
+		if _, err := url.Parse(hc.ProxyURL); err != nil {
+			v.addf(path+".proxy_url", "%v", err)
+		}
+	}
+}
+
+func (v *validator) checkStaticConfigs(path string, groups []StaticConfigs, isCheckAddress bool) {
+	for i, group := range groups {
+		groupPath := fmt.Sprintf("%s.static_configs[%d]", path, i)
+		for _, target := range group.Targets {
+			if isCheckAddress {
+				if err := CheckTargetAddress(target); err != nil {
+					v.addf(groupPath+".targets", "%v", err)
+				}
+			} else if target == "" {
+				v.addf(groupPath+".targets", "target is empty")
+			}
+		}
+		v.checkLabels(groupPath+".labels", group.Labels)
+	}
+}
+
+func (v *validator) checkFileSDConfigs(path string, sdConfigs []*FileSDConfig) {
+	for i, sdConfig := range sdConfigs {
+		if sdConfig == nil {
+			continue
+		}
+		sdPath := fmt.Sprintf("%s.file_sd_configs[%d]", path, i)
+		if len(sdConfig.Files) == 0 {
+			v.addf(sdPath, "files is empty")
+		}
+		for _, file := range sdConfig.Files {
+			ext := strings.ToLower(filepath.Ext(file))
+			if ext != ".json" && ext != ".yml" && ext != ".yaml" {
+				v.addf(sdPath+".files", "'%s' must end with .json, .yml or .yaml", file)
+			}
+		}
+		v.checkDuration(sdPath+".refresh_interval", sdConfig.RefreshInterval, 0)
+	}
+}
+
+func (v *validator) checkLabels(path string, labels map[string]string) {
+	for _, name := range SortedKeys(labels) {
+		if err := CheckLabelName(name); err != nil {
+			v.addf(path, "%v", err)
+		}
+	}
+}
+
+func (v *validator) checkRelabelConfigs(path string, rcs []*RelabelConfig) {
+	for i, rc := range rcs {
+		if rc == nil {
+			continue
+		}
+		if err := rc.checkValid(); err != nil {
+			v.addf(fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+}
+
+func (v *validator) checkRemoteURL(path string, rawURL string) {
+	if rawURL == "" {
+		v.addf(path+".url", "url is empty")
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		v.addf(path+".url", "%v", err)
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		v.addf(path+".url", "'%s' is not a valid http or https url", rawURL)
+	}
+}
+
+// CheckValid 检查relabel规则是否有效，包括action、正则表达式和标签名称，
+// 返回的错误属于ErrInvalidConfig类型
+func (r *RelabelConfig) CheckValid() error {
+	err := r.checkValid()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return nil
+}
+
+func (r *RelabelConfig) checkValid() error {
+	action := r.Action
+	if action == "" {
+		action = "replace"
+	}
+	if !relabelActions[action] {
+		return fmt.Errorf("unknown relabel action '%s'", r.Action)
+	}
+
+	if r.Regex != "" {
+		if _, err := regexp.Compile("^(?:" + r.Regex + ")$"); err != nil {
+			return fmt.Errorf("regex '%s' is invalid, %v", r.Regex, err)
+		}
+	}
+
+	for _, name := range r.SourceLabels {
+		if !labelNameRe.MatchString(name) {
+			return fmt.Errorf("source label '%s' is invalid", name)
+		}
+	}
+
+	switch action {
+	case "replace", "hashmod", "lowercase", "uppercase", "keepequal", "dropequal":
+		if r.TargetLabel == "" {
+			return fmt.Errorf("relabel action '%s' requires target_label", action)
+		}
+	}
+	if r.TargetLabel != "" {
+		if action == "replace" && !targetLabelRe.MatchString(r.TargetLabel) || action != "replace" && !labelNameRe.MatchString(r.TargetLabel) {
+			return fmt.Errorf("target label '%s' is invalid", r.TargetLabel)
+		}
+	}
+	if action == "hashmod" && r.Modulus == 0 {
+		return fmt.Errorf("relabel action 'hashmod' requires non-zero modulus")
+	}
+
+	return nil
+}
+
+// 分组标签中不能设置的保留名称，__address__由target设置，__name__是指标名称，
+// 其他__开头的标签可以在分组中设置，例如__metrics_path__、__scheme__、__param_<name>，relabel后被删除
+var reservedLabelNames = map[string]bool{"__address__": true, "__name__": true}
+
+// CheckLabelName 检查标签名称，只能包含字母、数字和下划线，不能以数字开头，不能是__address__和__name__
+func CheckLabelName(name string) error {
+	if !labelNameRe.MatchString(name) {
+		return fmt.Errorf("label name '%s' is invalid", name)
+	}
+	if reservedLabelNames[name] {
+		return fmt.Errorf("label name '%s' is reserved, it can not be set in labels", name)
+	}
+	return nil
+}
+
+// CheckTargetAddress 检查target地址，格式为host或host:port，不能包含scheme和路径
+func CheckTargetAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("target is empty")
+	}
+	if strings.Contains(address, "/") {
+		return fmt.Errorf("target '%s' is invalid, it must be host:port without scheme and path", address)
+	}
+
+	host, port := address, ""
+	if strings.HasPrefix(address, "[") || strings.Count(address, ":") == 1 {
+		var err error
+		host, port, err = net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("target '%s' is invalid, %v", address, err)
+		}
+	} else if strings.Contains(address, ":") {
+		return fmt.Errorf("target '%s' is invalid, ipv6 address must be enclosed in []", address)
+	}
+
+	if host == "" || strings.ContainsAny(host, " \t") {
+		return fmt.Errorf("target '%s' has an invalid host", address)
+	}
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("target '%s' has an invalid port", address)
+		}
+	}
+
+	return nil
+}
+
+// ParseDuration 解析prometheus格式的时间，例如 15s、1m30s、1h、1d、1w、500ms
+func ParseDuration(s string) (time.Duration, error) {
+	matches := durationRe.FindStringSubmatch(s)
+	if s == "" || matches == nil {
+		return 0, fmt.Errorf("duration '%s' is invalid, eg: 15s, 1m30s, 1h", s)
+	}
+
+	units := []time.Duration{
+		365 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour,
+		time.Hour, time.Minute, time.Second, time.Millisecond,
+	}
+	var d time.Duration
+	for i, unit := range units {
+		value := matches[2*i+2]
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("duration '%s' is invalid, %v", s, err)
+		}
+		d += time.Duration(n) * unit
+	}
+
+	return d, nil
+}
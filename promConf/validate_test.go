@@ -0,0 +1,234 @@
+package promConf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate_Corpus(t *testing.T) {
+	for name := range roundTripCorpus {
+		err := Validate(readRoundTripFile(t, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testData := []struct {
+		name    string
+		data    string
+		problem string // 为空表示配置有效
+	}{
+		{
+			name: "valid",
+			data: `
+global:
+  scrape_interval: 15s
+scrape_configs:
+  - job_name: node
+    scrape_timeout: 5s
+    static_configs:
+      - targets: ['10.0.0.1:9100', 'localhost', '[::1]:9100']
+        labels: {env: prod}
+`,
+		},
+		{
+			name: "internal labels of target",
+			data: `
+scrape_configs:
+  - job_name: blackbox
+    static_configs:
+      - targets: ['10.0.0.1:9115']
+        labels: {__param_module: http_2xx, __metrics_path__: /probe, __scheme__: https}
+`,
+		},
+		{
+			name: "duplicate job",
+			data: `
+scrape_configs:
+  - job_name: node
+    static_configs: [{targets: ['10.0.0.1:9100']}]
+  - job_name: node
+    static_configs: [{targets: ['10.0.0.2:9100']}]
+`,
+			problem: "job_name is duplicated",
+		},
+		{
+			name: "timeout greater than interval",
+			data: `
+scrape_configs:
+  - job_name: node
+    scrape_interval: 10s
+    scrape_timeout: 20s
+    static_configs: [{targets: ['10.0.0.1:9100']}]
+`,
+			problem: "scrape_timeout 20s is greater than scrape_interval 10s",
+		},
+		{
+			name: "global timeout is limited by job interval",
+			data: `
+global:
+  scrape_timeout: 10s
+scrape_configs:
+  - job_name: node
+    scrape_interval: 5s
+    static_configs: [{targets: ['10.0.0.1:9100']}]
+`,
+		},
+		{
+			name: "malformed duration",
+			data: `
+global:
+  scrape_interval: 15 seconds
+`,
+			problem: "duration '15 seconds' is invalid",
+		},
+		{
+			name: "invalid target",
+			data: `
+scrape_configs:
+  - job_name: node
+    static_configs: [{targets: ['http://10.0.0.1:9100/metrics']}]
+`,
+			problem: "must be host:port",
+		},
+		{
+			name: "invalid port",
+			data: `
+scrape_configs:
+  - job_name: node
+    static_configs: [{targets: ['10.0.0.1:99999']}]
+`,
+			problem: "invalid port",
+		},
+		{
+			name: "url target rewritten by relabel",
+			data: `
+scrape_configs:
+  - job_name: blackbox
+    metrics_path: /probe
+    static_configs: [{targets: ['https://example.com']}]
+    relabel_configs:
+      - source_labels: [__address__]
+        target_label: __param_target
+      - target_label: __address__
+        replacement: 127.0.0.1:9115
+`,
+		},
+		{
+			name: "invalid label name",
+			data: `
+scrape_configs:
+  - job_name: node
+    static_configs: [{targets: ['10.0.0.1:9100'], labels: {1env: prod}}]
+`,
+			problem: "label name '1env' is invalid",
+		},
+		{
+			name: "reserved label name",
+			data: `
+scrape_configs:
+  - job_name: node
+    static_configs: [{targets: ['10.0.0.1:9100'], labels: {__address__: '10.0.0.2:9100'}}]
+`,
+			problem: "is reserved",
+		},
+		{
+			name: "bad relabel regex",
+			data: `
+scrape_configs:
+  - job_name: node
+    static_configs: [{targets: ['10.0.0.1:9100']}]
+    metric_relabel_configs:
+      - action: drop
+        source_labels: [__name__]
+        regex: 'go_(.*'
+`,
+			problem: "regex 'go_(.*' is invalid",
+		},
+		{
+			name: "unknown relabel action",
+			data: `
+scrape_configs:
+  - job_name: node
+    static_configs: [{targets: ['10.0.0.1:9100']}]
+    relabel_configs:
+      - action: rename
+`,
+			problem: "unknown relabel action 'rename'",
+		},
+		{
+			name: "unknown scheme",
+			data: `
+scrape_configs:
+  - job_name: node
+    scheme: ftp
+    static_configs: [{targets: ['10.0.0.1:9100']}]
+`,
+			problem: "unknown scheme 'ftp'",
+		},
+		{
+			name: "invalid remote write url",
+			data: `
+remote_write:
+  - url: localhost:9201
+`,
+			problem: "is not a valid http or https url",
+		},
+	}
+
+	for _, td := range testData {
+		err := Validate([]byte(td.data))
+		if td.problem == "" {
+			if err != nil {
+				t.Errorf("%s: %v", td.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: expected error contains '%s'", td.name, td.problem)
+			continue
+		}
+		var vErr *ValidateError
+		if !errors.As(err, &vErr) {
+			t.Errorf("%s: error type is %T", td.name, err)
+		}
+		if !strings.Contains(err.Error(), td.problem) {
+			t.Errorf("%s: got '%v', expected contains '%s'", td.name, err, td.problem)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	testData := map[string]time.Duration{
+		"15s":   15 * time.Second,
+		"1m30s": 90 * time.Second,
+		"500ms": 500 * time.Millisecond,
+		"2h":    2 * time.Hour,
+		"1d":    24 * time.Hour,
+		"1w":    7 * 24 * time.Hour,
+	}
+	for s, expected := range testData {
+		d, err := ParseDuration(s)
+		if err != nil || d != expected {
+			t.Errorf("%s: got %v %v, expected %v", s, d, err, expected)
+		}
+	}
+
+	for _, s := range []string{"", "15", "1.5s", "s", "1s1m"} {
+		if _, err := ParseDuration(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestConfigYaml_PersistentValidate(t *testing.T) {
+	c := NewConfigYaml([]byte("scrape_configs:\n  - job_name: node\n    scheme: ftp\n    static_configs: [{targets: ['10.0.0.1:9100']}]\n"))
+	err := c.Persistent(t.TempDir()+"/prometheus.yml", BackupPolicy{})
+	if err == nil {
+		t.Error("expected invalid configuration not to be persisted")
+	}
+}
@@ -0,0 +1,133 @@
+package promConf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// target的健康状态，up、down、unknown为prometheus报告的状态
+const (
+	HealthUp      = "up"
+	HealthDown    = "down"
+	HealthUnknown = "unknown" // 还没有抓取过
+	HealthMissing = "missing" // prometheus没有这个target，配置未生效或被relabel丢弃
+)
+
+// ActiveTarget prometheus /api/v1/targets接口返回的活动target
+type ActiveTarget struct {
+	DiscoveredLabels   map[string]string `json:"discoveredLabels"` // relabel之前的标签，__address__为配置的target
+	Labels             map[string]string `json:"labels"`
+	ScrapePool         string            `json:"scrapePool"` // 所属job名称
+	ScrapeURL          string            `json:"scrapeUrl"`
+	LastError          string            `json:"lastError"`
+	LastScrape         time.Time         `json:"lastScrape"`
+	LastScrapeDuration float64           `json:"lastScrapeDuration"` // 单位秒
+	Health             string            `json:"health"`
+}
+
+// TargetHealth 配置的target在prometheus中的健康状态
+type TargetHealth struct {
+	Job            string        `json:"job"`
+	Target         string        `json:"target"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"last_error,omitempty"`
+	LastScrape     time.Time     `json:"last_scrape"`     // 最后一次抓取的时间，没有抓取过时为零值
+	ScrapeDuration time.Duration `json:"scrape_duration"` // 最后一次抓取的耗时，单位纳秒
+	ScrapeURL      string        `json:"scrape_url,omitempty"`
+}
+
+// IsUp target是否正常
+func (h *TargetHealth) IsUp() bool {
+	return h.Health == HealthUp
+}
+
+// GetActiveTargets 从prometheus的/api/v1/targets接口获取所有活动target，promURL为prometheus的地址，
+// 例如 http://127.0.0.1:9090，也可以是reload接口的地址
+func GetActiveTargets(promURL string, options *ClientOptions) ([]*ActiveTarget, error) {
+	err := checkURL(promURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newPromClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := PromBaseURL(promURL) + "/api/v1/targets?state=active"
+	data, err := client.do(http.MethodGet, apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ActiveTargets []*ActiveTarget `json:"activeTargets"`
+		} `json:"data"`
+	}{}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %v", apiURL, err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("GET %s: status is %s, %s", apiURL, resp.Status, resp.Error)
+	}
+
+	return resp.Data.ActiveTargets, nil
+}
+
+// MatchJobTargets 匹配job配置的target和prometheus的活动target，按配置的target顺序返回健康状态，
+// 通过job名称和relabel之前的__address__匹配，同一个target有多个活动target时(例如在多个分组中)，
+// 只要有一个不正常就返回不正常的状态
+func MatchJobTargets(jobName string, targets []string, active []*ActiveTarget) []*TargetHealth {
+	matched := map[string]*ActiveTarget{}
+	for _, at := range active {
+		pool := at.ScrapePool
+		if pool == "" {
+			pool = at.Labels["job"]
+		}
+		address := at.DiscoveredLabels["__address__"]
+		if pool != jobName || address == "" {
+			continue
+		}
+		if old, ok := matched[address]; ok && old.Health != HealthUp {
+			continue
+		}
+		matched[address] = at
+	}
+
+	healths := []*TargetHealth{}
+	for _, target := range uniqueStrings(targets) {
+		h := &TargetHealth{Job: jobName, Target: target, Health: HealthMissing}
+		if at, ok := matched[target]; ok {
+			h.Health = at.Health
+			if h.Health == "" {
+				h.Health = HealthUnknown
+			}
+			h.LastError = at.LastError
+			h.LastScrape = at.LastScrape
+			h.ScrapeDuration = time.Duration(at.LastScrapeDuration * float64(time.Second))
+			h.ScrapeURL = at.ScrapeURL
+		}
+		healths = append(healths, h)
+	}
+
+	return healths
+}
+
+// 去重，保持原顺序
+func uniqueStrings(ss []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, s := range ss {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
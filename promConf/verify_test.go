@@ -0,0 +1,162 @@
+package promConf
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const activeTargetsResponse = `{
+  "status": "success",
+  "data": {
+    "activeTargets": [
+      {
+        "discoveredLabels": {"__address__": "10.0.0.1:9100", "job": "node_exporter"},
+        "labels": {"instance": "10.0.0.1:9100", "job": "node_exporter"},
+        "scrapePool": "node_exporter",
+        "scrapeUrl": "http://10.0.0.1:9100/metrics",
+        "lastError": "",
+        "lastScrape": "2022-06-11T10:00:00.5+08:00",
+        "lastScrapeDuration": 0.0125,
+        "health": "up"
+      },
+      {
+        "discoveredLabels": {"__address__": "10.0.0.2:9100", "job": "node_exporter"},
+        "labels": {"instance": "10.0.0.2:9100", "job": "node_exporter"},
+        "scrapePool": "node_exporter",
+        "scrapeUrl": "http://10.0.0.2:9100/metrics",
+        "lastError": "connection refused",
+        "lastScrape": "2022-06-11T10:00:01+08:00",
+        "lastScrapeDuration": 0.001,
+        "health": "down"
+      },
+      {
+        "discoveredLabels": {"__address__": "10.0.0.3:9100", "job": "other"},
+        "labels": {"instance": "10.0.0.3:9100", "job": "other"},
+        "scrapePool": "other",
+        "scrapeUrl": "http://10.0.0.3:9100/metrics",
+        "health": "up"
+      }
+    ],
+    "droppedTargets": []
+  }
+}`
+
+func TestGetActiveTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/targets" || r.URL.Query().Get("state") != "active" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, activeTargetsResponse)
+	}))
+	defer server.Close()
+
+	// 也支持reload接口的地址
+	active, err := GetActiveTargets(server.URL+"/-/reload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 3 {
+		t.Fatalf("expected 3 active targets, got %d", len(active))
+	}
+
+	healths := MatchJobTargets("node_exporter", []string{"10.0.0.1:9100", "10.0.0.2:9100", "10.0.0.3:9100", "10.0.0.1:9100"}, active)
+	expected := []struct {
+		target, health, lastError string
+		duration                  time.Duration
+	}{
+		{"10.0.0.1:9100", HealthUp, "", 12500 * time.Microsecond},
+		{"10.0.0.2:9100", HealthDown, "connection refused", time.Millisecond},
+		{"10.0.0.3:9100", HealthMissing, "", 0},
+	}
+	if len(healths) != len(expected) {
+		t.Fatalf("expected %d healths, got %d", len(expected), len(healths))
+	}
+	for i, e := range expected {
+		h := healths[i]
+		if h.Job != "node_exporter" || h.Target != e.target || h.Health != e.health || h.LastError != e.lastError || h.ScrapeDuration != e.duration {
+			t.Errorf("healths[%d] = %+v, expected %+v", i, h, e)
+		}
+	}
+	if !healths[0].IsUp() || healths[1].IsUp() || healths[2].IsUp() {
+		t.Errorf("unexpected IsUp result")
+	}
+	if healths[0].LastScrape.IsZero() || !healths[2].LastScrape.IsZero() {
+		t.Errorf("unexpected last scrape time %v, %v", healths[0].LastScrape, healths[2].LastScrape)
+	}
+}
+
+func TestGetActiveTargets_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := GetActiveTargets(server.URL, nil)
+	httpErr := &HTTPError{}
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 HTTPError, got %v", err)
+	}
+
+	_, err = GetActiveTargets("127.0.0.1:9090", nil)
+	if err == nil {
+		t.Errorf("expected invalid url error")
+	}
+}
+
+func TestMatchJobTargets_MultipleGroups(t *testing.T) {
+	// 同一个target在两个分组中，有一个不正常时返回不正常
+	active := []*ActiveTarget{
+		{DiscoveredLabels: map[string]string{"__address__": "10.0.0.1:9100", "env": "prod"}, ScrapePool: "node", Health: HealthUp},
+		{DiscoveredLabels: map[string]string{"__address__": "10.0.0.1:9100", "env": "test"}, ScrapePool: "node", Health: HealthDown},
+		{DiscoveredLabels: map[string]string{"__address__": "10.0.0.2:9100"}, Labels: map[string]string{"job": "node"}},
+	}
+
+	healths := MatchJobTargets("node", []string{"10.0.0.1:9100", "10.0.0.2:9100"}, active)
+	if healths[0].Health != HealthDown {
+		t.Errorf("expected down, got %s", healths[0].Health)
+	}
+	if healths[1].Health != HealthUnknown {
+		t.Errorf("expected unknown, got %s", healths[1].Health)
+	}
+}
+
+func TestMatchJobTargets_FileSD(t *testing.T) {
+	// job的target在file_sd_configs文件中，发现的标签包含文件路径和文件中的标签
+	c := NewConfigYaml([]byte(`scrape_configs:
+  - job_name: node
+    file_sd_configs:
+      - files: ['targets.d/node.json']
+`))
+	files, err := c.GetJobFileSDFiles("node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != "targets.d/node.json" {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	fsd := NewFileSD(files[0], []byte(`[{"targets": ["10.0.0.1:9100", "10.0.0.2:9100"], "labels": {"env": "prod"}}]`))
+	targets, err := fsd.GetTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := []*ActiveTarget{
+		{DiscoveredLabels: map[string]string{"__address__": "10.0.0.1:9100", "__meta_filepath": "/etc/prometheus/targets.d/node.json", "env": "prod"}, ScrapePool: "node", Health: HealthUp},
+		{DiscoveredLabels: map[string]string{"__address__": "10.0.0.2:9100", "__meta_filepath": "/etc/prometheus/targets.d/node.json", "env": "prod"}, ScrapePool: "node", Health: HealthUp},
+	}
+	healths := MatchJobTargets("node", targets, active)
+	if len(healths) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(healths))
+	}
+	for _, h := range healths {
+		if !h.IsUp() {
+			t.Errorf("expected %s is up, got %s", h.Target, h.Health)
+		}
+	}
+}
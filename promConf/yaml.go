@@ -0,0 +1,561 @@
+package promConf
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 对yaml节点进行修改，只有被修改的节点会变化，注释、key顺序、锚点和引号风格都会保留。
+// 修改前后的节点树分别规范化编码，比较两者的差异，再把差异合并到原始内容，
+// 使得原始内容中未修改部分的缩进、空行、注释位置等格式保持不变。
+
+// 解析yaml文档，内容为空时返回空的文档
+func parseDocument(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	err := yaml.Unmarshal(data, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = append(doc.Content, newMappingNode())
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w: the root of yaml document is not a mapping", ErrInvalidConfig)
+	}
+
+	return doc, nil
+}
+
+// 编码yaml节点
+func encodeNode(node *yaml.Node, indent int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(indent)
+	err := encoder.Encode(node)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// 检测yaml内容的缩进空格数，默认为2
+func detectIndent(data []byte) int {
+	prevIndent := -1
+	prevIsKey := false
+	for _, line := range strings.Split(string(data), "\n") {
+		content := strings.TrimLeft(line, " ")
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+
+		indent := len(line) - len(content)
+		if prevIsKey && indent > prevIndent {
+			step := indent - prevIndent
+			if step >= 2 && step <= 8 && !strings.HasPrefix(content, "- ") {
+				return step
+			}
+		}
+
+		prevIndent = indent
+		content = strings.TrimRight(strings.SplitN(content, " #", 2)[0], " \r")
+		prevIsKey = strings.HasSuffix(content, ":")
+	}
+
+	return 2
+}
+
+// editDocument 解析yaml内容，通过fn修改节点，返回保留原始格式的新内容
+func editDocument(data []byte, fn func(root *yaml.Node) error) ([]byte, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	indent := detectIndent(data)
+	before, err := encodeNode(doc, indent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fn(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := encodeNode(doc, indent)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(before, after) {
+		return data, nil
+	}
+
+	// 原始内容中mapping下的sequence不缩进时，规范化编码也使用相同的风格，新增的行与原始内容风格一致
+	if detectCompactSequence(data) {
+		compactBefore, compactAfter := compactSequences(before, indent), compactSequences(after, indent)
+		if isSameYaml(compactBefore, before) && isSameYaml(compactAfter, after) {
+			before, after = compactBefore, compactAfter
+		}
+	}
+
+	return mergeFormat(data, before, after), nil
+}
+
+// 检测yaml内容中mapping下的sequence是否不缩进，即sequence元素的"- "与key对齐
+func detectCompactSequence(data []byte) bool {
+	keyColumn := -1
+	for _, line := range splitLines(string(data)) {
+		content := strings.TrimLeft(line, " ")
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+
+		indent := len(line) - len(content)
+		if keyColumn >= 0 && isSequenceItem(content) {
+			return indent == keyColumn
+		}
+
+		keyColumn = -1
+		if isKeyLine(content) {
+			keyColumn = indent + sequencePrefixLen(content)
+		}
+	}
+
+	return false
+}
+
+// compactSequences 把yaml.v3编码结果中mapping下缩进的sequence改为不缩进
+func compactSequences(data []byte, indent int) []byte {
+	lines := splitLines(string(data))
+	regions := []int{} // 不缩进的sequence所属key的列
+	keyColumn := -1
+	for i, line := range lines {
+		content := strings.TrimLeft(line, " ")
+		if content == "" {
+			continue
+		}
+
+		column := len(line) - len(content)
+		for len(regions) > 0 && column <= regions[len(regions)-1] {
+			regions = regions[:len(regions)-1]
+		}
+		if !strings.HasPrefix(content, "#") {
+			if keyColumn >= 0 && column == keyColumn+indent && isSequenceItem(content) {
+				regions = append(regions, keyColumn)
+			}
+			keyColumn = -1
+			if isKeyLine(content) {
+				keyColumn = column + sequencePrefixLen(content)
+			}
+		}
+
+		lines[i] = reindentLine(line, -indent*len(regions))
+	}
+
+	if len(lines) == 0 {
+		return data
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// 是否为值在下一行的key，例如 targets:
+func isKeyLine(content string) bool {
+	content = strings.TrimRight(strings.SplitN(content, " #", 2)[0], " \r")
+	return strings.HasSuffix(content, ":")
+}
+
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// 行首sequence元素标记"- "的长度，例如 "- - key:" 为4
+func sequencePrefixLen(content string) int {
+	n := 0
+	for strings.HasPrefix(content[n:], "- ") {
+		n += 2
+	}
+	return n
+}
+
+// EditYaml 解析yaml内容，通过fn修改根节点，返回新内容，只有被修改的节点会变化，
+// 保留原有的注释和格式，内容为空时根节点为空的mapping
+func EditYaml(data []byte, fn func(root *yaml.Node) error) ([]byte, error) {
+	return editDocument(data, fn)
+}
+
+// --------------------------------- 格式合并 ---------------------------------
+
+type indentAnchor struct {
+	canonIndent int
+	delta       int
+}
+
+// mergeFormat 把before到after的变化合并到原始内容src，before是src规范化编码的结果，
+// 合并结果和after内容不一致时，返回after
+func mergeFormat(src []byte, before []byte, after []byte) []byte {
+	lineBreak := "\n"
+	if bytes.Contains(src, []byte("\r\n")) {
+		lineBreak = "\r\n"
+	}
+	srcLines := splitLines(string(src))
+	beforeLines := splitLines(string(before))
+	afterLines := splitLines(string(after))
+
+	// before的行与原始内容的行对应关系，忽略空白字符的差异
+	srcIndex := make([]int, len(beforeLines))
+	for i := range srcIndex {
+		srcIndex[i] = -1
+	}
+	for _, op := range diffLines(normalizeLines(beforeLines), normalizeLines(srcLines)) {
+		if op.kind == lineEqual {
+			srcIndex[op.a] = op.b
+		}
+	}
+
+	out := []string{}
+	anchors := []indentAnchor{}
+	addAnchor := func(line string, delta int) {
+		if !isCommentLine(line) {
+			anchors = append(anchors, indentAnchor{canonIndent: lineIndent(line), delta: delta})
+		}
+	}
+	findDelta := func(line string) int {
+		indent := lineIndent(line)
+		for i := len(anchors) - 1; i >= 0; i-- {
+			if anchors[i].canonIndent <= indent {
+				return anchors[i].delta
+			}
+		}
+		return 0
+	}
+
+	pos := 0
+	isPrevDeleted := false
+	for _, op := range diffLines(beforeLines, afterLines) {
+		switch op.kind {
+		case lineEqual:
+			if s := srcIndex[op.a]; s >= 0 {
+				out = append(out, srcLines[pos:s+1]...)
+				pos = s + 1
+				addAnchor(beforeLines[op.a], lineIndent(srcLines[s])-lineIndent(beforeLines[op.a]))
+			}
+			isPrevDeleted = false
+
+		case lineDelete:
+			if s := srcIndex[op.a]; s >= 0 {
+				// 连续删除的行之间的空行也一起删除
+				if !isPrevDeleted {
+					out = append(out, srcLines[pos:s]...)
+				}
+				pos = s + 1
+				addAnchor(beforeLines[op.a], lineIndent(srcLines[s])-lineIndent(beforeLines[op.a]))
+				isPrevDeleted = true
+			}
+
+		case lineInsert:
+			line := afterLines[op.b]
+			delta := findDelta(line)
+			out = append(out, reindentLine(line, delta))
+			addAnchor(line, delta)
+		}
+	}
+	out = append(out, srcLines[pos:]...)
+
+	merged := []byte(strings.Join(out, lineBreak))
+	if len(out) > 0 {
+		merged = append(merged, lineBreak...)
+	}
+
+	if !isSameYaml(merged, after) {
+		return after
+	}
+
+	return merged
+}
+
+// 分割行，忽略最后的换行符
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+// 去掉所有空白字符，用于比较格式不同但内容相同的行
+func normalizeLines(lines []string) []string {
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = strings.Join(strings.Fields(line), "")
+	}
+	return normalized
+}
+
+func isCommentLine(line string) bool {
+	content := strings.TrimLeft(line, " ")
+	return content == "" || strings.HasPrefix(content, "#")
+}
+
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func reindentLine(line string, delta int) string {
+	indent := lineIndent(line) + delta
+	if indent < 0 {
+		indent = 0
+	}
+	return strings.Repeat(" ", indent) + strings.TrimLeft(line, " ")
+}
+
+// 判断两个yaml内容的数据是否相同，忽略注释和格式
+func isSameYaml(data1 []byte, data2 []byte) bool {
+	var v1, v2 interface{}
+	if err := yaml.Unmarshal(data1, &v1); err != nil {
+		return false
+	}
+	if err := yaml.Unmarshal(data2, &v2); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(v1, v2)
+}
+
+// --------------------------------- 节点操作 ---------------------------------
+
+func newMappingNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func newSequenceNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+}
+
+func newScalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// ToNode 把值编码为yaml节点
+func ToNode(v interface{}) (*yaml.Node, error) {
+	node := &yaml.Node{}
+	err := node.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// 别名节点返回锚点节点
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// 深度复制节点，别名节点复制为锚点节点的内容
+func copyNode(node *yaml.Node) *yaml.Node {
+	node = resolveAlias(node)
+	if node == nil {
+		return nil
+	}
+
+	newNode := *node
+	newNode.Anchor = ""
+	newNode.Content = nil
+	for _, child := range node.Content {
+		newNode.Content = append(newNode.Content, copyNode(child))
+	}
+
+	return &newNode
+}
+
+// MappingKeyIndex 获取mapping节点中key的索引，不存在返回-1
+func MappingKeyIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// MappingValue 获取mapping节点中key对应的值节点，不存在返回nil
+func MappingValue(m *yaml.Node, key string) *yaml.Node {
+	m = resolveAlias(m)
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	index := MappingKeyIndex(m, key)
+	if index < 0 {
+		return nil
+	}
+
+	return resolveAlias(m.Content[index+1])
+}
+
+// SetMappingValue 设置mapping节点中key对应的值节点，key不存在时添加到最后
+func SetMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	index := MappingKeyIndex(m, key)
+	if index < 0 {
+		m.Content = append(m.Content, newScalarNode(key), value)
+		return
+	}
+
+	// 保留旧值的注释
+	old := m.Content[index+1]
+	if value.LineComment == "" {
+		value.LineComment = old.LineComment
+	}
+	if value.HeadComment == "" {
+		value.HeadComment = old.HeadComment
+	}
+	if value.FootComment == "" {
+		value.FootComment = old.FootComment
+	}
+	m.Content[index+1] = value
+}
+
+// InsertMappingValue 在mapping节点的index位置插入kv，index为key在Content中的位置，超出范围时添加到最后
+func InsertMappingValue(m *yaml.Node, index int, key string, value *yaml.Node) {
+	if index < 0 || index > len(m.Content) {
+		index = len(m.Content)
+	}
+
+	content := make([]*yaml.Node, 0, len(m.Content)+2)
+	content = append(content, m.Content[:index]...)
+	content = append(content, newScalarNode(key), value)
+	m.Content = append(content, m.Content[index:]...)
+}
+
+// DeleteMappingKey 删除mapping节点中的key，key存在时返回true
+func DeleteMappingKey(m *yaml.Node, key string) bool {
+	index := MappingKeyIndex(m, key)
+	if index < 0 {
+		return false
+	}
+
+	m.Content = append(m.Content[:index], m.Content[index+2:]...)
+	return true
+}
+
+// 获取可修改的mapping或sequence值节点，值为别名时替换为锚点内容的副本，避免修改其他引用
+func editableValue(m *yaml.Node, key string) *yaml.Node {
+	index := MappingKeyIndex(m, key)
+	if index < 0 {
+		return nil
+	}
+
+	value := m.Content[index+1]
+	if value.Kind == yaml.AliasNode {
+		value = copyNode(value)
+		m.Content[index+1] = value
+	}
+
+	return value
+}
+
+// 把json风格的节点转为yaml块风格
+func clearFlowStyle(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		node.Style &^= yaml.DoubleQuotedStyle
+	}
+	node.Style &^= yaml.FlowStyle
+	for _, child := range node.Content {
+		clearFlowStyle(child)
+	}
+}
+
+// 解析yaml或json格式的mapping数据
+func parseMappingData(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	err := yaml.Unmarshal(data, doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("data is not a yaml or json object")
+	}
+
+	node := doc.Content[0]
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		clearFlowStyle(node)
+	}
+
+	return node, nil
+}
+
+// 字符串slice转为sequence节点，已存在的元素复用原节点，保留注释和引号风格
+func updateStringSequence(seq *yaml.Node, values []string) {
+	oldNodes := map[string]*yaml.Node{}
+	var style yaml.Style
+	for i, item := range seq.Content {
+		if item.Kind == yaml.ScalarNode {
+			if _, ok := oldNodes[item.Value]; !ok {
+				oldNodes[item.Value] = item
+			}
+			if i == 0 {
+				style = item.Style
+			}
+		}
+	}
+
+	content := []*yaml.Node{}
+	for _, value := range values {
+		if node, ok := oldNodes[value]; ok {
+			content = append(content, node)
+			delete(oldNodes, value)
+			continue
+		}
+		node := newScalarNode(value)
+		node.Style = style
+		content = append(content, node)
+	}
+
+	seq.Content = content
+}
+
+// map转为mapping节点，已存在的key复用原节点，新的key按字母顺序添加到最后
+func updateStringMapping(m *yaml.Node, kvs map[string]string) {
+	content := []*yaml.Node{}
+	exists := map[string]bool{}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key, value := m.Content[i], m.Content[i+1]
+		newValue, ok := kvs[key.Value]
+		if !ok {
+			continue
+		}
+		exists[key.Value] = true
+		if value.Kind != yaml.ScalarNode || value.Value != newValue {
+			node := newScalarNode(newValue)
+			if value.Kind == yaml.ScalarNode {
+				node.Style = value.Style
+			}
+			node.LineComment = value.LineComment
+			value = node
+		}
+		content = append(content, key, value)
+	}
+
+	for _, key := range SortedKeys(kvs) {
+		if !exists[key] {
+			content = append(content, newScalarNode(key), newScalarNode(kvs[key]))
+		}
+	}
+
+	m.Content = content
+}
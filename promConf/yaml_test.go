@@ -0,0 +1,171 @@
+package promConf
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 真实prometheus配置文件，以及每个文件中包含static_configs的job
+var roundTripCorpus = map[string]string{
+	"prometheus-example.yml": "prometheus",
+	"compact.yml":            "node_exporter",
+	"anchors.yml":            "node_exporter",
+	"four-spaces.yml":        "node",
+	"kubernetes.yml":         "static-nodes",
+}
+
+func readRoundTripFile(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "roundtrip", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEditDocument_NoChange(t *testing.T) {
+	for name := range roundTripCorpus {
+		data := readRoundTripFile(t, name)
+		out, err := editDocument(data, func(root *yaml.Node) error { return nil })
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(data, out) {
+			t.Errorf("%s: content changed without edit", name)
+		}
+	}
+}
+
+// 添加target后再删除，内容应该与原始内容完全相同
+func TestConfigYaml_RoundTrip(t *testing.T) {
+	for name, jobName := range roundTripCorpus {
+		data := readRoundTripFile(t, name)
+		pConfig := NewConfigYaml(data)
+
+		err := pConfig.AddJobTargets(jobName, []string{"192.0.2.1:9100"})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		err = pConfig.DelJobTargets(jobName, []string{"192.0.2.1:9100"})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if !bytes.Equal(data, pConfig.Data) {
+			t.Errorf("%s: round trip changed content:\n%s", name, pConfig.Data)
+		}
+	}
+}
+
+// 添加job后，原始内容的每一行都保留，包括注释、空行、锚点和引号
+func TestConfigYaml_PreserveFormat(t *testing.T) {
+	jobData := []byte(`
+job_name: 'redis_exporter'
+static_configs:
+- targets: ['192.168.1.11:9121']
+  labels:
+    id: "100"
+`)
+
+	for name, jobName := range roundTripCorpus {
+		data := readRoundTripFile(t, name)
+		pConfig := NewConfigYaml(data)
+
+		err := pConfig.AddJob(jobData)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		err = pConfig.AddJobLabels(jobName, map[string]string{"foo": "bar"})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		oldLines := splitLines(string(data))
+		newLines := splitLines(string(pConfig.Data))
+		for _, op := range diffLines(oldLines, newLines) {
+			if op.kind == lineDelete {
+				t.Errorf("%s: line %d '%s' is changed", name, op.a+1, oldLines[op.a])
+			}
+		}
+
+		targets, err := pConfig.GetJobTargets("redis_exporter")
+		if err != nil || len(targets) != 1 {
+			t.Errorf("%s: got targets %v, err=%v", name, targets, err)
+		}
+	}
+}
+
+func TestConfigYaml_EditAlias(t *testing.T) {
+	pConfig := NewConfigYaml(readRoundTripFile(t, "anchors.yml"))
+	err := pConfig.ReplaceJobLabels("mysqld_exporter", map[string]string{"dc": "gz"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	labels, _ := pConfig.GetJobLabels("node_exporter")
+	if labels["dc"] != "sh" {
+		t.Errorf("labels of anchor should not be changed, got %v", labels)
+	}
+	labels, _ = pConfig.GetJobLabels("mysqld_exporter")
+	if labels["dc"] != "gz" || len(labels) != 1 {
+		t.Errorf("got labels %v, want map[dc:gz]", labels)
+	}
+	if !strings.Contains(string(pConfig.Data), "&dc_sh") {
+		t.Error("anchor is lost")
+	}
+
+	fmt.Println(string(pConfig.Data))
+}
+
+func TestDetectIndent(t *testing.T) {
+	if indent := detectIndent(readRoundTripFile(t, "four-spaces.yml")); indent != 4 {
+		t.Errorf("got indent %d, want 4", indent)
+	}
+	if indent := detectIndent(readRoundTripFile(t, "compact.yml")); indent != 2 {
+		t.Errorf("got indent %d, want 2", indent)
+	}
+}
+
+func TestDetectCompactSequence(t *testing.T) {
+	if !detectCompactSequence(readRoundTripFile(t, "compact.yml")) {
+		t.Error("compact.yml uses compact sequences")
+	}
+	if detectCompactSequence(readRoundTripFile(t, "four-spaces.yml")) {
+		t.Error("four-spaces.yml uses indented sequences")
+	}
+}
+
+// 原始内容的sequence不缩进时，新增的嵌套sequence也不缩进
+func TestEditDocument_CompactSequence(t *testing.T) {
+	data := []byte(`scrape_configs:
+- job_name: node
+  static_configs:
+  - targets: null
+`)
+	c := NewConfigYaml(data)
+	err := c.AddJobTargets("node", []string{"127.0.0.1:9100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `scrape_configs:
+- job_name: node
+  static_configs:
+  - targets:
+    - 127.0.0.1:9100
+`
+	if string(c.Data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", c.Data, expected)
+	}
+}
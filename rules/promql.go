@@ -0,0 +1,778 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 离线检查PromQL表达式的语法，不依赖prometheus，支持数字、字符串、向量选择器、范围选择器、
+// 子查询、offset和@修饰符、函数、聚合操作和二元运算，检查函数名称和参数个数、标签匹配器和正则表达式，
+// 不做返回值类型检查，不支持的语法见包文档。
+
+// SyntaxError PromQL语法错误
+type SyntaxError struct {
+	Pos int    // 错误在表达式中的位置，从0开始
+	Msg string // 错误信息
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("parse error at char %d: %s", e.Pos+1, e.Msg)
+}
+
+// CheckPromQL 检查PromQL表达式的语法
+func CheckPromQL(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return &SyntaxError{Pos: 0, Msg: "no expression found in input"}
+	}
+
+	p := &parser{input: expr}
+	p.next()
+	err := p.parseExpr()
+	if err != nil {
+		return err
+	}
+	if p.tok.typ != tokEOF {
+		return p.errorf("unexpected %s", p.tok)
+	}
+
+	return nil
+}
+
+// --------------------------------- 词法分析 ---------------------------------
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokLeftParen
+	tokRightParen
+	tokLeftBrace
+	tokRightBrace
+	tokLeftBracket
+	tokRightBracket
+	tokComma
+	tokColon
+	tokAt
+	tokOperator
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return "string " + t.val
+	case tokNumber:
+		return "number " + t.val
+	case tokDuration:
+		return "duration " + t.val
+	}
+	return fmt.Sprintf("'%s'", t.val)
+}
+
+var (
+	durationRe  = regexp.MustCompile(`^([0-9]+(ms|[smhdwy]))+$`)
+	numberRe    = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?)`)
+	operators   = []string{"==", "!=", "<=", ">=", "=~", "!~", "+", "-", "*", "/", "%", "^", "<", ">", "="}
+	singleChars = map[byte]tokenType{
+		'(': tokLeftParen, ')': tokRightParen, '{': tokLeftBrace, '}': tokRightBrace,
+		'[': tokLeftBracket, ']': tokRightBracket, ',': tokComma, ':': tokColon, '@': tokAt,
+	}
+)
+
+type parser struct {
+	input string
+	pos   int   // 下一个token的开始位置
+	tok   token // 当前token
+	err   error // 词法分析错误
+
+	inBracket bool // 在范围选择器的[]中，':'为子查询分隔符
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// 读取下一个token，词法错误时当前token为EOF并记录错误
+func (p *parser) next() {
+	p.skipSpaceAndComment()
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{typ: tokEOF, pos: start}
+		return
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c == '"' || c == '\'' || c == '`':
+		s, err := p.scanString(c)
+		if err != nil {
+			p.err = err
+			p.tok = token{typ: tokEOF, pos: start}
+			return
+		}
+		p.tok = token{typ: tokString, val: s, pos: start}
+		return
+
+	case isDigit(c) || (c == '.' && p.pos+1 < len(p.input) && isDigit(p.input[p.pos+1])):
+		// 数字或时间，例如 1.5e3、0x1f、5m、1h30m
+		end := p.pos
+		for end < len(p.input) && (isAlphaNum(p.input[end]) || p.input[end] == '.' ||
+			((p.input[end] == '+' || p.input[end] == '-') && (p.input[end-1] == 'e' || p.input[end-1] == 'E') && !strings.ContainsAny(p.input[start:end], "xX"))) {
+			end++
+		}
+		word := p.input[start:end]
+		p.pos = end
+		if durationRe.MatchString(word) {
+			p.tok = token{typ: tokDuration, val: word, pos: start}
+			return
+		}
+		if numberRe.FindString(word) != word {
+			p.err = &SyntaxError{Pos: start, Msg: fmt.Sprintf("bad number or duration syntax: '%s'", word)}
+			p.tok = token{typ: tokEOF, pos: start}
+			return
+		}
+		p.tok = token{typ: tokNumber, val: word, pos: start}
+		return
+
+	case isIdentStart(c) && !(c == ':' && p.inBracket):
+		end := p.pos
+		for end < len(p.input) && (isAlphaNum(p.input[end]) || p.input[end] == ':') {
+			end++
+		}
+		p.pos = end
+		p.tok = token{typ: tokIdent, val: p.input[start:end], pos: start}
+		return
+	}
+
+	if typ, ok := singleChars[c]; ok {
+		p.pos++
+		p.tok = token{typ: typ, val: string(c), pos: start}
+		return
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			p.pos += len(op)
+			p.tok = token{typ: tokOperator, val: op, pos: start}
+			return
+		}
+	}
+
+	r, _ := utf8.DecodeRuneInString(p.input[p.pos:])
+	p.err = &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character: %q", r)}
+	p.tok = token{typ: tokEOF, pos: start}
+}
+
+func (p *parser) skipSpaceAndComment() {
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c == '#' {
+			for p.pos < len(p.input) && p.input[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if !unicode.IsSpace(rune(c)) {
+			return
+		}
+		p.pos++
+	}
+}
+
+// 读取字符串，返回带引号的原始内容
+func (p *parser) scanString(quote byte) (string, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			s := p.input[start:p.pos]
+			if _, err := unquoteString(s); err != nil {
+				return "", &SyntaxError{Pos: start, Msg: fmt.Sprintf("invalid escape sequence in string %s", s)}
+			}
+			return s, nil
+		case c == '\\' && quote != '`':
+			p.pos += 2
+		case c == '\n' && quote != '`':
+			return "", &SyntaxError{Pos: start, Msg: "unterminated quoted string"}
+		default:
+			p.pos++
+		}
+	}
+
+	return "", &SyntaxError{Pos: start, Msg: "unterminated quoted string"}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlphaNum(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// 字符串token去掉引号后的值
+func unquoteString(s string) (string, error) {
+	quote := s[0]
+	s = s[1 : len(s)-1]
+	if quote == '`' {
+		return s, nil
+	}
+
+	buf := &strings.Builder{}
+	for len(s) > 0 {
+		r, multibyte, tail, err := strconv.UnquoteChar(s, quote)
+		if err != nil {
+			return "", err
+		}
+		s = tail
+		if multibyte {
+			buf.WriteRune(r)
+		} else {
+			buf.WriteByte(byte(r))
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// --------------------------------- 语法分析 ---------------------------------
+
+// 二元运算符的优先级，数值越大优先级越高
+var binaryOperators = map[string]int{
+	"or":     1,
+	"and":    2,
+	"unless": 2,
+	"==":     3, "!=": 3, "<=": 3, "<": 3, ">=": 3, ">": 3,
+	"+": 4, "-": 4,
+	"*": 5, "/": 5, "%": 5, "atan2": 5,
+	"^": 6,
+}
+
+var comparisonOperators = map[string]bool{"==": true, "!=": true, "<=": true, "<": true, ">=": true, ">": true}
+
+var setOperators = map[string]bool{"and": true, "or": true, "unless": true}
+
+// 聚合操作，值为是否需要额外的参数，例如topk(3, x)
+var aggregations = map[string]bool{
+	"sum": false, "min": false, "max": false, "avg": false, "group": false, "stddev": false,
+	"stdvar": false, "count": false, "count_values": true, "bottomk": true, "topk": true,
+	"quantile": true, "limitk": true, "limit_ratio": true,
+}
+
+// 函数的最少和最多参数个数，最多为-1表示参数个数不限
+var functions = map[string][2]int{
+	"abs": {1, 1}, "absent": {1, 1}, "absent_over_time": {1, 1}, "acos": {1, 1}, "acosh": {1, 1},
+	"asin": {1, 1}, "asinh": {1, 1}, "atan": {1, 1}, "atanh": {1, 1}, "avg_over_time": {1, 1},
+	"ceil": {1, 1}, "changes": {1, 1}, "clamp": {3, 3}, "clamp_max": {2, 2}, "clamp_min": {2, 2},
+	"cos": {1, 1}, "cosh": {1, 1}, "count_over_time": {1, 1}, "days_in_month": {0, 1},
+	"day_of_month": {0, 1}, "day_of_week": {0, 1}, "day_of_year": {0, 1}, "deg": {1, 1},
+	"delta": {1, 1}, "deriv": {1, 1}, "double_exponential_smoothing": {3, 3}, "exp": {1, 1},
+	"floor": {1, 1}, "histogram_avg": {1, 1}, "histogram_count": {1, 1}, "histogram_fraction": {3, 3},
+	"histogram_quantile": {2, 2}, "histogram_stddev": {1, 1}, "histogram_stdvar": {1, 1},
+	"histogram_sum": {1, 1}, "holt_winters": {3, 3}, "hour": {0, 1}, "idelta": {1, 1},
+	"increase": {1, 1}, "info": {1, 2}, "irate": {1, 1}, "label_join": {3, -1}, "label_replace": {5, 5},
+	"last_over_time": {1, 1}, "ln": {1, 1}, "log10": {1, 1}, "log2": {1, 1}, "mad_over_time": {1, 1},
+	"max_over_time": {1, 1}, "min_over_time": {1, 1}, "minute": {0, 1}, "month": {0, 1}, "pi": {0, 0},
+	"predict_linear": {2, 2}, "present_over_time": {1, 1}, "quantile_over_time": {2, 2}, "rad": {1, 1},
+	"rate": {1, 1}, "resets": {1, 1}, "round": {1, 2}, "scalar": {1, 1}, "sgn": {1, 1}, "sin": {1, 1},
+	"sinh": {1, 1}, "sort": {1, 1}, "sort_by_label": {1, -1}, "sort_by_label_desc": {1, -1},
+	"sort_desc": {1, 1}, "sqrt": {1, 1}, "stddev_over_time": {1, 1}, "stdvar_over_time": {1, 1},
+	"sum_over_time": {1, 1}, "tan": {1, 1}, "tanh": {1, 1}, "time": {0, 0}, "timestamp": {1, 1},
+	"vector": {1, 1}, "year": {0, 1},
+}
+
+var matchOperators = map[string]bool{"=": true, "!=": true, "=~": true, "!~": true}
+
+// 获取当前token，词法分析出错时返回错误
+func (p *parser) current() (token, error) {
+	if p.err != nil {
+		return p.tok, p.err
+	}
+	return p.tok, nil
+}
+
+func (p *parser) expect(typ tokenType, context string) error {
+	tok, err := p.current()
+	if err != nil {
+		return err
+	}
+	if tok.typ != typ {
+		return p.errorf("unexpected %s in %s", tok, context)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseExpr() error {
+	return p.parseBinary(1)
+}
+
+// 按优先级解析二元运算，^为右结合，其他为左结合
+func (p *parser) parseBinary(minPrec int) error {
+	err := p.parseUnary()
+	if err != nil {
+		return err
+	}
+
+	for {
+		tok, err := p.current()
+		if err != nil {
+			return err
+		}
+		if tok.typ != tokOperator && tok.typ != tokIdent {
+			return nil
+		}
+		prec, ok := binaryOperators[strings.ToLower(tok.val)]
+		if !ok || prec < minPrec || (tok.typ == tokIdent) != isLetter(tok.val) {
+			return nil
+		}
+		p.next()
+
+		err = p.parseBinaryModifiers(strings.ToLower(tok.val))
+		if err != nil {
+			return err
+		}
+
+		nextPrec := prec + 1
+		if tok.val == "^" {
+			nextPrec = prec
+		}
+		err = p.parseBinary(nextPrec)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func isLetter(s string) bool {
+	return s != "" && isIdentStart(s[0])
+}
+
+// 解析二元运算的修饰符：bool、on/ignoring、group_left/group_right
+func (p *parser) parseBinaryModifiers(op string) error {
+	if p.isKeyword("bool") {
+		if !comparisonOperators[op] {
+			return p.errorf("bool modifier can only be used on comparison operators")
+		}
+		p.next()
+	}
+
+	if p.isKeyword("on") || p.isKeyword("ignoring") {
+		p.next()
+		err := p.parseLabelList()
+		if err != nil {
+			return err
+		}
+
+		if p.isKeyword("group_left") || p.isKeyword("group_right") {
+			if setOperators[op] {
+				return p.errorf("no grouping allowed for \"%s\" operation", op)
+			}
+			p.next()
+			if p.tok.typ == tokLeftParen {
+				err = p.parseLabelList()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	return p.err == nil && p.tok.typ == tokIdent && strings.EqualFold(p.tok.val, keyword)
+}
+
+func (p *parser) parseUnary() error {
+	tok, err := p.current()
+	if err != nil {
+		return err
+	}
+	if tok.typ == tokOperator && (tok.val == "+" || tok.val == "-") {
+		p.next()
+		return p.parseUnary()
+	}
+
+	err = p.parsePrimary()
+	if err != nil {
+		return err
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePrimary() error {
+	tok, err := p.current()
+	if err != nil {
+		return err
+	}
+
+	switch tok.typ {
+	case tokNumber, tokString, tokDuration:
+		p.next()
+		return nil
+
+	case tokLeftParen:
+		p.next()
+		err = p.parseExpr()
+		if err != nil {
+			return err
+		}
+		return p.expect(tokRightParen, "paren expression")
+
+	case tokLeftBrace:
+		return p.parseSelector("")
+
+	case tokIdent:
+		name := tok.val
+		lower := strings.ToLower(name)
+		if lower == "inf" || lower == "nan" {
+			p.next()
+			return nil
+		}
+		if _, ok := binaryOperators[lower]; ok {
+			return p.errorf("unexpected %s", tok)
+		}
+
+		p.next()
+		if _, ok := aggregations[lower]; ok && (p.tok.typ == tokLeftParen || p.isKeyword("by") || p.isKeyword("without")) {
+			return p.parseAggregation(lower)
+		}
+		if p.tok.typ == tokLeftParen && p.err == nil {
+			return p.parseFunction(name, tok.pos)
+		}
+		return p.parseSelector(name)
+	}
+
+	return p.errorf("unexpected %s", tok)
+}
+
+// 解析聚合操作，例如 sum by (job) (rate(x[5m])) 或 topk(3, x) without (instance)
+func (p *parser) parseAggregation(name string) error {
+	hasGrouping := false
+	if p.isKeyword("by") || p.isKeyword("without") {
+		p.next()
+		err := p.parseLabelList()
+		if err != nil {
+			return err
+		}
+		hasGrouping = true
+	}
+
+	start := p.tok.pos
+	argCount, err := p.parseArgs()
+	if err != nil {
+		return err
+	}
+	want := 1
+	if aggregations[name] {
+		want = 2
+	}
+	if argCount != want {
+		return &SyntaxError{Pos: start, Msg: fmt.Sprintf("wrong number of arguments for aggregate expression provided, expected %d, got %d", want, argCount)}
+	}
+
+	if p.isKeyword("by") || p.isKeyword("without") {
+		if hasGrouping {
+			return p.errorf("aggregation must only contain one grouping clause")
+		}
+		p.next()
+		return p.parseLabelList()
+	}
+
+	return nil
+}
+
+func (p *parser) parseFunction(name string, pos int) error {
+	limit, ok := functions[name]
+	if !ok {
+		return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unknown function with name \"%s\"", name)}
+	}
+
+	argCount, err := p.parseArgs()
+	if err != nil {
+		return err
+	}
+	if argCount < limit[0] || (limit[1] >= 0 && argCount > limit[1]) {
+		expected := strconv.Itoa(limit[0])
+		if limit[1] < 0 {
+			expected = "at least " + expected
+		} else if limit[1] != limit[0] {
+			expected = fmt.Sprintf("%d to %d", limit[0], limit[1])
+		}
+		return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("expected %s argument(s) in call to \"%s\", got %d", expected, name, argCount)}
+	}
+
+	return nil
+}
+
+// 解析括号中逗号分隔的参数，返回参数个数
+func (p *parser) parseArgs() (int, error) {
+	err := p.expect(tokLeftParen, "argument list")
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		if p.tok.typ == tokRightParen && p.err == nil {
+			p.next()
+			return count, nil
+		}
+		if count > 0 {
+			err = p.expect(tokComma, "argument list")
+			if err != nil {
+				return 0, err
+			}
+		}
+		err = p.parseExpr()
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
+
+// 解析括号中的标签名称列表，例如 (job, instance)
+func (p *parser) parseLabelList() error {
+	err := p.expect(tokLeftParen, "grouping opts")
+	if err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		tok, err := p.current()
+		if err != nil {
+			return err
+		}
+		if tok.typ == tokRightParen {
+			p.next()
+			return nil
+		}
+		if i > 0 {
+			err = p.expect(tokComma, "grouping opts")
+			if err != nil {
+				return err
+			}
+			if p.tok.typ == tokRightParen && p.err == nil {
+				p.next()
+				return nil
+			}
+		}
+		tok, err = p.current()
+		if err != nil {
+			return err
+		}
+		if (tok.typ != tokIdent && tok.typ != tokString) || (tok.typ == tokIdent && strings.Contains(tok.val, ":")) {
+			return p.errorf("unexpected %s in grouping opts, expected label", tok)
+		}
+		p.next()
+	}
+}
+
+// 解析向量选择器，例如 up{job="node"} 或 {__name__=~"node_.*"}
+func (p *parser) parseSelector(metricName string) error {
+	if p.tok.typ != tokLeftBrace || p.err != nil {
+		return nil
+	}
+	p.next()
+
+	hasNonEmpty := metricName != ""
+	for i := 0; ; i++ {
+		tok, err := p.current()
+		if err != nil {
+			return err
+		}
+		if tok.typ == tokRightBrace {
+			p.next()
+			break
+		}
+		if i > 0 {
+			err = p.expect(tokComma, "label matching")
+			if err != nil {
+				return err
+			}
+			if p.tok.typ == tokRightBrace && p.err == nil {
+				p.next()
+				break
+			}
+		}
+
+		nonEmpty, err := p.parseMatcher()
+		if err != nil {
+			return err
+		}
+		hasNonEmpty = hasNonEmpty || nonEmpty
+	}
+
+	if !hasNonEmpty {
+		return p.errorf("vector selector must contain at least one non-empty matcher")
+	}
+	return nil
+}
+
+// 解析标签匹配器，返回匹配器是否不匹配空字符串
+func (p *parser) parseMatcher() (bool, error) {
+	tok, err := p.current()
+	if err != nil {
+		return false, err
+	}
+
+	// 只有字符串时为指标名称
+	if tok.typ == tokString {
+		p.next()
+		if p.tok.typ == tokComma || p.tok.typ == tokRightBrace {
+			v, _ := unquoteString(tok.val)
+			return v != "", nil
+		}
+	} else if tok.typ != tokIdent || strings.Contains(tok.val, ":") {
+		return false, p.errorf("unexpected %s in label matching, expected label", tok)
+	} else {
+		p.next()
+	}
+
+	op, err := p.current()
+	if err != nil {
+		return false, err
+	}
+	if op.typ != tokOperator || !matchOperators[op.val] {
+		return false, p.errorf("unexpected %s in label matching, expected one of \"=\", \"!=\", \"=~\" or \"!~\"", op)
+	}
+	p.next()
+
+	value, err := p.current()
+	if err != nil {
+		return false, err
+	}
+	if value.typ != tokString {
+		return false, p.errorf("unexpected %s in label matching, expected string", value)
+	}
+	p.next()
+
+	v, _ := unquoteString(value.val)
+	switch op.val {
+	case "=":
+		return v != "", nil
+	case "!=":
+		return v == "", nil
+	}
+
+	re, err := regexp.Compile("^(?:" + v + ")$")
+	if err != nil {
+		return false, &SyntaxError{Pos: value.pos, Msg: fmt.Sprintf("invalid regular expression %s: %v", value.val, err)}
+	}
+	matchEmpty := re.MatchString("")
+	if op.val == "=~" {
+		return !matchEmpty, nil
+	}
+	return matchEmpty, nil
+}
+
+// 解析范围选择器、子查询、offset和@修饰符
+func (p *parser) parsePostfix() error {
+	for {
+		tok, err := p.current()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case tok.typ == tokLeftBracket:
+			p.inBracket = true
+			p.next()
+			err = p.expectDuration("range")
+			if err != nil {
+				return err
+			}
+			if p.tok.typ == tokColon && p.err == nil {
+				p.next()
+				if p.tok.typ != tokRightBracket {
+					err = p.expectDuration("subquery")
+					if err != nil {
+						return err
+					}
+				}
+			}
+			p.inBracket = false
+			err = p.expect(tokRightBracket, "range")
+			if err != nil {
+				return err
+			}
+
+		case p.isKeyword("offset"):
+			p.next()
+			if p.tok.typ == tokOperator && p.tok.val == "-" && p.err == nil {
+				p.next()
+			}
+			err = p.expectDuration("offset")
+			if err != nil {
+				return err
+			}
+
+		case tok.typ == tokAt:
+			p.next()
+			if p.isKeyword("start") || p.isKeyword("end") {
+				p.next()
+				err = p.expect(tokLeftParen, "@ modifier")
+				if err != nil {
+					return err
+				}
+				err = p.expect(tokRightParen, "@ modifier")
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if p.tok.typ == tokOperator && (p.tok.val == "-" || p.tok.val == "+") && p.err == nil {
+				p.next()
+			}
+			tok, err = p.current()
+			if err != nil {
+				return err
+			}
+			if tok.typ != tokNumber {
+				return p.errorf("unexpected %s in @ modifier, expected timestamp", tok)
+			}
+			p.next()
+
+		default:
+			return nil
+		}
+	}
+}
+
+// 时间可能被词法分析为数字，例如[5]，需要单独判断
+func (p *parser) expectDuration(context string) error {
+	tok, err := p.current()
+	if err != nil {
+		return err
+	}
+	if tok.typ != tokDuration {
+		return p.errorf("unexpected %s in %s, expected duration", tok, context)
+	}
+	p.next()
+	return nil
+}
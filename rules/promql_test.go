@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPromQL(t *testing.T) {
+	valids := []string{
+		`up`,
+		`up == 0`,
+		`1`,
+		`-1.5e3 + 0x1f`,
+		`Inf`,
+		`"foo"`,
+		`node_load1{instance="127.0.0.1:9100", job=~"node.*"}`,
+		`{__name__=~"node_.+"}`,
+		`{"node_load1", job="node"}`,
+		`up{job="node",}`,
+		`rate(http_requests_total[5m])`,
+		`rate(http_requests_total[1h30m] offset 5m)`,
+		`rate(http_requests_total[5m] @ 1609746000)`,
+		`rate(http_requests_total[5m] @ start())`,
+		`max_over_time(rate(http_requests_total[5m])[30m:1m])`,
+		`max_over_time(deriv(rate(x[1m])[5m:])[10m:])`,
+		`sum by (job) (rate(http_requests_total[5m]))`,
+		`sum(rate(http_requests_total[5m])) without (instance, pod)`,
+		`topk(3, sum by (app, proc) (rate(instance_cpu_time_ns[5m])))`,
+		`count_values("version", build_version)`,
+		`histogram_quantile(0.9, sum by (le) (rate(http_request_duration_seconds_bucket[10m])))`,
+		`label_replace(up{job="api-server"}, "foo", "$1", "service", "(.*):.*")`,
+		`label_join(up, "foo", ",", "a", "b", "c")`,
+		`100 * (1 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[5m]))) > 80`,
+		`method_code:http_errors:rate5m{code="500"} / ignoring(code) method:http_requests:rate5m`,
+		`method_code:http_errors:rate5m / ignoring(code) group_left method:http_requests:rate5m`,
+		`a * on (instance) group_right (job) b`,
+		`up == bool 1`,
+		`a and on (job) b or c unless d`,
+		`2 ^ 3 ^ 2`,
+		`absent(up{job="node"} == 1)`,
+		`time() - process_start_time_seconds < 60`,
+		`vector(1) # comment`,
+		`probe_success{job="blackbox"} == 0
+		  and
+		probe_http_status_code != 200`,
+		`up{job='node', path="a\"b"}`,
+		"up{path=`c:\\data`}",
+	}
+	for _, expr := range valids {
+		if err := CheckPromQL(expr); err != nil {
+			t.Errorf("CheckPromQL(%q) error: %v", expr, err)
+		}
+	}
+
+	invalids := map[string]string{
+		``:                            "no expression found",
+		`up{`:                         "unexpected end of input",
+		`up{job="node"`:               "unexpected end of input",
+		`rate(up[5m]`:                 "unexpected end of input",
+		`sum(up))`:                    "unexpected ')'",
+		`up{job=node}`:                "expected string",
+		`up{job~="node"}`:             "unexpected character",
+		`up{job=~"("}`:                "invalid regular expression",
+		`{job=""}`:                    "at least one non-empty matcher",
+		`{job=~".*"}`:                 "at least one non-empty matcher",
+		`rate(up[5x])`:                "bad number or duration",
+		`rate(up[5])`:                 "expected duration",
+		`rates(up[5m])`:               "unknown function",
+		`rate(up[5m], 1)`:             "expected 1 argument(s)",
+		`histogram_quantile(0.9)`:     "expected 2 argument(s)",
+		`topk(up)`:                    "wrong number of arguments",
+		`sum by (job) (up) by (job)`:  "only contain one grouping clause",
+		`sum by (job:x) (up)`:         "expected label",
+		`up + bool 1`:                 "bool modifier",
+		`a and on (job) group_left b`: "no grouping allowed",
+		`up ==`:                       "unexpected end of input",
+		`up{job="node}`:               "unterminated quoted string",
+		`"\q"`:                        "invalid escape sequence",
+		`up offset`:                   "expected duration",
+		`up @ foo`:                    "expected timestamp",
+		`and up`:                      "unexpected 'and'",
+		`up 1`:                        "unexpected number 1",
+	}
+	for expr, want := range invalids {
+		err := CheckPromQL(expr)
+		if err == nil {
+			t.Errorf("CheckPromQL(%q) expected error", expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckPromQL(%q) error = %v, want contains %q", expr, err, want)
+		}
+	}
+}
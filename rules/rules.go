@@ -0,0 +1,589 @@
+// Package rules 管理prometheus告警规则和记录规则文件，支持分组和规则的增删改查，
+// 修改只改变被修改的节点，保留原有的注释和格式，写入前离线检查规则和PromQL语法。
+//
+// PromQL语法检查(promql.go)没有使用github.com/prometheus/prometheus/promql/parser，
+// 因为引入该包需要依赖整个prometheus服务端模块(tsdb、存储、服务发现等)，并且要求的go版本远高于
+// 本模块的go 1.17，与promConf自行解析配置文件而不依赖prometheus/config的原因相同。
+// 检查的目的是在写入前发现常见错误，不能代替prometheus加载规则时的检查(promtool check rules)，
+// 已知的差异：
+//   - 不做类型检查，例如 rate(up)、sum("a")、字符串参与二元运算 会通过检查，prometheus加载时报错
+//   - 需要开启特性开关的实验语法会报错，例如 范围中的时长表达式 [5m*2]、step()、anchored和smoothed修饰符、
+//     带下划线分隔的数字 1_000
+//   - 函数名称和参数个数来自固定的列表，prometheus新增的函数在更新列表前会报错 unknown function
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/zhufuyi/mpc/promConf"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleGroups 规则文件内容
+type RuleGroups struct {
+	Groups []*RuleGroup `json:"groups" yaml:"groups"`
+}
+
+// RuleGroup 规则分组
+type RuleGroup struct {
+	Name     string                 `json:"name" yaml:"name"`
+	Interval string                 `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Limit    int                    `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Rules    []*Rule                `json:"rules" yaml:"rules"`
+	Extra    map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+// Rule 告警规则或记录规则，alert和record只能设置一个
+type Rule struct {
+	Record      string                 `json:"record,omitempty" yaml:"record,omitempty"`
+	Alert       string                 `json:"alert,omitempty" yaml:"alert,omitempty"`
+	Expr        string                 `json:"expr" yaml:"expr"`
+	For         string                 `json:"for,omitempty" yaml:"for,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string      `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Extra       map[string]interface{} `json:"-" yaml:",inline"`
+}
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRe  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// prometheus模板中可以使用的函数，只用于检查模板语法
+	templateFuncs = template.FuncMap{}
+)
+
+func init() {
+	names := []string{
+		"query", "first", "label", "value", "strvalue", "args", "reReplaceAll", "safeHtml", "match",
+		"title", "toUpper", "toLower", "graphLink", "tableLink", "sortByLabel", "humanize", "humanize1024",
+		"humanizeDuration", "humanizePercentage", "humanizeTimestamp", "toTime", "toDuration", "pathPrefix",
+		"externalURL", "parseDuration", "stripPort", "stripDomain", "urlQueryEscape", "tmpl", "now",
+	}
+	for _, name := range names {
+		templateFuncs[name] = func(...interface{}) interface{} { return nil }
+	}
+}
+
+// Name 规则名称，告警规则为alert，记录规则为record
+func (r *Rule) Name() string {
+	if r.Alert != "" {
+		return r.Alert
+	}
+	return r.Record
+}
+
+// CheckValid 检查规则是否有效，包括名称、PromQL语法、for时间、标签和注解，
+// 返回的错误属于promConf.ErrInvalidConfig类型
+func (r *Rule) CheckValid() error {
+	err := r.checkValid()
+	if err != nil {
+		return fmt.Errorf("%w: %v", promConf.ErrInvalidConfig, err)
+	}
+	return nil
+}
+
+func (r *Rule) checkValid() error {
+	switch {
+	case r.Record == "" && r.Alert == "":
+		return errors.New("one of 'record' or 'alert' must be set")
+	case r.Record != "" && r.Alert != "":
+		return errors.New("only one of 'record' and 'alert' must be set")
+	}
+
+	if r.Expr == "" {
+		return errors.New("field 'expr' must be set in rule")
+	}
+	if err := CheckPromQL(r.Expr); err != nil {
+		return fmt.Errorf("could not parse expression: %v", err)
+	}
+
+	if r.Record != "" {
+		if !metricNameRe.MatchString(r.Record) {
+			return fmt.Errorf("invalid recording rule name: %s", r.Record)
+		}
+		if r.For != "" {
+			return errors.New("invalid field 'for' in recording rule")
+		}
+		if len(r.Annotations) > 0 {
+			return errors.New("invalid field 'annotations' in recording rule")
+		}
+	}
+
+	if r.For != "" {
+		if _, err := promConf.ParseDuration(r.For); err != nil {
+			return fmt.Errorf("invalid field 'for': %v", err)
+		}
+	}
+
+	for _, name := range promConf.SortedKeys(r.Labels) {
+		if !labelNameRe.MatchString(name) {
+			return fmt.Errorf("invalid label name: %s", name)
+		}
+		if err := checkTemplate("label", name, r.Labels[name]); err != nil {
+			return err
+		}
+	}
+	for _, name := range promConf.SortedKeys(r.Annotations) {
+		if !labelNameRe.MatchString(name) {
+			return fmt.Errorf("invalid annotation name: %s", name)
+		}
+		if err := checkTemplate("annotation", name, r.Annotations[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 检查标签和注解中的模板语法，与prometheus一样预先定义$labels、$externalLabels和$value变量
+func checkTemplate(kind string, name string, text string) error {
+	if !strings.Contains(text, "{{") {
+		return nil
+	}
+
+	defs := "{{$labels := .Labels}}{{$externalLabels := .ExternalLabels}}{{$externalURL := .ExternalURL}}{{$value := .Value}}"
+	_, err := template.New(name).Funcs(templateFuncs).Parse(defs + text)
+	if err != nil {
+		return fmt.Errorf("invalid %s template '%s': %v", kind, name, err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------
+
+// ValidateError 规则文件校验错误，包含检查出的所有问题
+type ValidateError struct {
+	Problems []string
+}
+
+func (e *ValidateError) Error() string {
+	return "invalid rule file:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Is 校验错误属于promConf.ErrInvalidConfig类型
+func (e *ValidateError) Is(target error) bool {
+	return target == promConf.ErrInvalidConfig
+}
+
+// ParseRuleGroups 解析规则文件内容
+func ParseRuleGroups(data []byte) (*RuleGroups, error) {
+	rgs := &RuleGroups{}
+	err := yaml.Unmarshal(data, rgs)
+	if err != nil {
+		return nil, err
+	}
+	return rgs, nil
+}
+
+// Validate 离线校验规则文件，检查分组名称重复、评估间隔、规则和PromQL语法，返回的错误包含所有问题
+func Validate(data []byte) error {
+	rgs, err := ParseRuleGroups(data)
+	if err != nil {
+		return &ValidateError{Problems: []string{err.Error()}}
+	}
+
+	problems := []string{}
+	names := map[string]bool{}
+	for i, group := range rgs.Groups {
+		if group == nil {
+			continue
+		}
+		path := fmt.Sprintf("groups[%d]", i)
+		if group.Name == "" {
+			problems = append(problems, path+": group name is empty")
+		} else {
+			path = fmt.Sprintf("groups[name=%s]", group.Name)
+			if names[group.Name] {
+				problems = append(problems, path+": group name is repeated in the same file")
+			}
+			names[group.Name] = true
+		}
+
+		if group.Interval != "" {
+			if _, err := promConf.ParseDuration(group.Interval); err != nil {
+				problems = append(problems, fmt.Sprintf("%s.interval: %v", path, err))
+			}
+		}
+
+		for j, rule := range group.Rules {
+			if rule == nil {
+				continue
+			}
+			if err := rule.checkValid(); err != nil {
+				rulePath := fmt.Sprintf("%s.rules[%d]", path, j)
+				if rule.Name() != "" {
+					rulePath = fmt.Sprintf("%s.rules[%d:%s]", path, j, rule.Name())
+				}
+				problems = append(problems, rulePath+": "+err.Error())
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidateError{Problems: problems}
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------------------------
+
+// RuleFile 规则文件
+type RuleFile struct {
+	Data []byte
+}
+
+// NewRuleFile 实例化，data为空时表示新的规则文件
+func NewRuleFile(data []byte) *RuleFile {
+	return &RuleFile{Data: data}
+}
+
+// Validate 校验规则文件
+func (f *RuleFile) Validate() error {
+	return Validate(f.Data)
+}
+
+// Persistent 校验通过后持久化到文件，文件已存在时先备份到同级目录bak下，与prometheus配置文件的备份方式一致，policy为备份保留策略
+func (f *RuleFile) Persistent(file string, policy promConf.BackupPolicy) error {
+	err := f.Validate()
+	if err != nil {
+		return err
+	}
+
+	return promConf.WriteFileWithBackup(file, f.Data, policy)
+}
+
+// GetGroups 获取所有分组
+func (f *RuleFile) GetGroups() ([]*RuleGroup, error) {
+	rgs, err := ParseRuleGroups(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	if rgs.Groups == nil {
+		return []*RuleGroup{}, nil
+	}
+	return rgs.Groups, nil
+}
+
+// GetGroup 获取分组
+func (f *RuleFile) GetGroup(groupName string) (*RuleGroup, error) {
+	groups, err := f.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if group != nil && group.Name == groupName {
+			return group, nil
+		}
+	}
+
+	return nil, errGroupNotFound(groupName)
+}
+
+// GetRules 获取分组中名称为name的规则，name为空时返回分组所有规则
+func (f *RuleFile) GetRules(groupName string, name string) ([]*Rule, error) {
+	group, err := f.GetGroup(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []*Rule{}
+	for _, rule := range group.Rules {
+		if rule != nil && (name == "" || rule.Name() == name) {
+			rules = append(rules, rule)
+		}
+	}
+	if name != "" && len(rules) == 0 {
+		return nil, errRuleNotFound(groupName, name)
+	}
+
+	return rules, nil
+}
+
+// AddRule 添加规则到分组最后，分组不存在时自动创建，分组中已存在名称和标签都相同的规则时返回错误
+func (f *RuleFile) AddRule(groupName string, rule *Rule) error {
+	err := rule.CheckValid()
+	if err != nil {
+		return err
+	}
+
+	node, err := promConf.ToNode(rule)
+	if err != nil {
+		return err
+	}
+
+	return f.edit(func(root *yaml.Node) error {
+		groups := groupsNode(root)
+		group := findGroupNode(groups, groupName)
+		if group == nil {
+			group, err = promConf.ToNode(&RuleGroup{Name: groupName, Rules: []*Rule{}})
+			if err != nil {
+				return err
+			}
+			groups.Content = append(groups.Content, group)
+		}
+
+		seq := promConf.MappingValue(group, "rules")
+		if seq == nil || seq.Kind != yaml.SequenceNode {
+			seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			promConf.SetMappingValue(group, "rules", seq)
+		}
+		if len(seq.Content) == 0 {
+			seq.Style &^= yaml.FlowStyle
+		}
+		for _, item := range seq.Content {
+			old := &Rule{}
+			if item.Decode(old) == nil && old.Name() == rule.Name() && isSameLabels(old.Labels, rule.Labels) {
+				return fmt.Errorf("rule '%s' with the same labels already exists in group '%s'", rule.Name(), groupName)
+			}
+		}
+		seq.Content = append(seq.Content, node)
+
+		return nil
+	})
+}
+
+// ReplaceRule 替换分组中名称为name的规则，保留原规则的注释，新规则没有设置的字段使用原规则的值，
+// 例如没有设置alert和record时使用原规则的名称，分组中有多个同名规则时返回错误
+func (f *RuleFile) ReplaceRule(groupName string, name string, rule *Rule) error {
+	return f.edit(func(root *yaml.Node) error {
+		seq, indexes, err := findRuleNodes(root, groupName, name)
+		if err != nil {
+			return err
+		}
+		if len(indexes) > 1 {
+			return fmt.Errorf("there are %d rules named '%s' in group '%s', delete them and add again", len(indexes), name, groupName)
+		}
+
+		old := seq.Content[indexes[0]]
+		oldRule := &Rule{}
+		err = old.Decode(oldRule)
+		if err != nil {
+			return err
+		}
+		newRule := mergeRule(oldRule, rule)
+		err = newRule.CheckValid()
+		if err != nil {
+			return err
+		}
+
+		node, err := promConf.ToNode(newRule)
+		if err != nil {
+			return err
+		}
+		node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		seq.Content[indexes[0]] = node
+
+		return nil
+	})
+}
+
+// 合并规则，rule中没有设置的字段使用old的值，记录规则没有for和annotations，类型改变时不保留
+func mergeRule(old *Rule, rule *Rule) *Rule {
+	newRule := *rule
+	if newRule.Alert == "" && newRule.Record == "" {
+		newRule.Alert, newRule.Record = old.Alert, old.Record
+	}
+	if newRule.Expr == "" {
+		newRule.Expr = old.Expr
+	}
+	if len(newRule.Labels) == 0 {
+		newRule.Labels = old.Labels
+	}
+	if len(newRule.Extra) == 0 {
+		newRule.Extra = old.Extra
+	}
+	if newRule.Alert != "" && old.Alert != "" {
+		if newRule.For == "" {
+			newRule.For = old.For
+		}
+		if len(newRule.Annotations) == 0 {
+			newRule.Annotations = old.Annotations
+		}
+	}
+	return &newRule
+}
+
+// DelRule 删除分组中名称为name的所有规则，分组中没有规则时删除分组
+func (f *RuleFile) DelRule(groupName string, name string) error {
+	return f.edit(func(root *yaml.Node) error {
+		seq, indexes, err := findRuleNodes(root, groupName, name)
+		if err != nil {
+			return err
+		}
+
+		content := []*yaml.Node{}
+		for i, item := range seq.Content {
+			if !isContainIndex(indexes, i) {
+				content = append(content, item)
+			}
+		}
+		seq.Content = content
+
+		if len(content) == 0 {
+			return delGroupNode(root, groupName)
+		}
+		return nil
+	})
+}
+
+// DelGroup 删除分组
+func (f *RuleFile) DelGroup(groupName string) error {
+	return f.edit(func(root *yaml.Node) error {
+		return delGroupNode(root, groupName)
+	})
+}
+
+// SetGroupInterval 设置分组的评估间隔，interval为空时删除，使用全局的evaluation_interval
+func (f *RuleFile) SetGroupInterval(groupName string, interval string) error {
+	if interval != "" {
+		if _, err := promConf.ParseDuration(interval); err != nil {
+			return fmt.Errorf("%w: invalid interval: %v", promConf.ErrInvalidConfig, err)
+		}
+	}
+
+	return f.edit(func(root *yaml.Node) error {
+		group := findGroupNode(promConf.MappingValue(root, "groups"), groupName)
+		if group == nil {
+			return errGroupNotFound(groupName)
+		}
+
+		if interval == "" {
+			promConf.DeleteMappingKey(group, "interval")
+			return nil
+		}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: interval}
+		if promConf.MappingKeyIndex(group, "interval") >= 0 {
+			promConf.SetMappingValue(group, "interval", value)
+			return nil
+		}
+		// 添加到name后面
+		index := promConf.MappingKeyIndex(group, "name")
+		if index >= 0 {
+			index += 2
+		}
+		promConf.InsertMappingValue(group, index, "interval", value)
+		return nil
+	})
+}
+
+// 修改根节点，只有被修改的节点内容会变化，保留原有的注释和格式
+func (f *RuleFile) edit(fn func(root *yaml.Node) error) error {
+	data, err := promConf.EditYaml(f.Data, fn)
+	if err != nil {
+		return err
+	}
+
+	f.Data = data
+	return nil
+}
+
+// --------------------------------------------------------------------------------------
+
+// 获取groups节点，不存在时创建
+func groupsNode(root *yaml.Node) *yaml.Node {
+	groups := promConf.MappingValue(root, "groups")
+	if groups == nil || groups.Kind != yaml.SequenceNode {
+		groups = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		promConf.SetMappingValue(root, "groups", groups)
+	}
+	if len(groups.Content) == 0 {
+		groups.Style &^= yaml.FlowStyle
+	}
+	return groups
+}
+
+func findGroupNode(groups *yaml.Node, groupName string) *yaml.Node {
+	if groups == nil {
+		return nil
+	}
+	for _, item := range groups.Content {
+		name := promConf.MappingValue(item, "name")
+		if name != nil && name.Value == groupName {
+			return item
+		}
+	}
+	return nil
+}
+
+func delGroupNode(root *yaml.Node, groupName string) error {
+	groups := promConf.MappingValue(root, "groups")
+	if groups != nil {
+		for i, item := range groups.Content {
+			name := promConf.MappingValue(item, "name")
+			if name != nil && name.Value == groupName {
+				groups.Content = append(groups.Content[:i], groups.Content[i+1:]...)
+				return nil
+			}
+		}
+	}
+
+	return errGroupNotFound(groupName)
+}
+
+// 查找分组中名称为name的规则，返回rules节点和规则的索引
+func findRuleNodes(root *yaml.Node, groupName string, name string) (*yaml.Node, []int, error) {
+	group := findGroupNode(promConf.MappingValue(root, "groups"), groupName)
+	if group == nil {
+		return nil, nil, errGroupNotFound(groupName)
+	}
+
+	indexes := []int{}
+	seq := promConf.MappingValue(group, "rules")
+	if seq != nil {
+		for i, item := range seq.Content {
+			for _, key := range []string{"alert", "record"} {
+				value := promConf.MappingValue(item, key)
+				if value != nil && value.Value == name {
+					indexes = append(indexes, i)
+					break
+				}
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, nil, errRuleNotFound(groupName, name)
+	}
+
+	return seq, indexes, nil
+}
+
+// 规则分组不存在，与target不存在的退出码相同
+func errGroupNotFound(groupName string) error {
+	return &promConf.Error{
+		Err:  promConf.ErrTargetsNotFound,
+		Path: fmt.Sprintf("groups[name=%s]", groupName),
+		Msg:  fmt.Sprintf("rule group '%s' not found", groupName),
+	}
+}
+
+// 规则不存在，与target不存在的退出码相同
+func errRuleNotFound(groupName string, name string) error {
+	return &promConf.Error{
+		Err:  promConf.ErrTargetsNotFound,
+		Path: fmt.Sprintf("groups[name=%s].rules[name=%s]", groupName, name),
+		Msg:  fmt.Sprintf("rule '%s' not found in group '%s'", name, groupName),
+	}
+}
+
+func isContainIndex(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+func isSameLabels(l1 map[string]string, l2 map[string]string) bool {
+	if len(l1) == 0 && len(l2) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(l1, l2)
+}
@@ -0,0 +1,272 @@
+package rules
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+var ruleData = []byte(`# node exporter rules
+groups:
+  - name: node
+    interval: 1m
+    rules:
+      # instance is down
+      - alert: NodeDown
+        expr: up{job="node_exporter"} == 0
+        for: 5m
+        labels:
+          severity: critical
+        annotations:
+          summary: "instance {{ $labels.instance }} is down"
+      - record: instance:node_cpu:rate5m
+        expr: sum by (instance) (rate(node_cpu_seconds_total{mode!="idle"}[5m]))
+`)
+
+func TestRuleCheckValid(t *testing.T) {
+	valid := &Rule{Alert: "HighLoad", Expr: "node_load1 > 10", For: "10m", Labels: map[string]string{"severity": "warning"},
+		Annotations: map[string]string{"description": "load is {{ $value | humanize }}"}}
+	if err := valid.CheckValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	invalids := map[string]*Rule{
+		"one of 'record' or 'alert'":  {Expr: "up"},
+		"only one of":                 {Alert: "a", Record: "b", Expr: "up"},
+		"'expr' must be set":          {Alert: "a"},
+		"could not parse expression":  {Alert: "a", Expr: "up{"},
+		"invalid recording rule name": {Record: "a-b", Expr: "up"},
+		"invalid field 'for'":         {Record: "a", Expr: "up", For: "5m"},
+		"invalid field 'annotations'": {Record: "a", Expr: "up", Annotations: map[string]string{"a": "b"}},
+		"duration '5x' is invalid":    {Alert: "a", Expr: "up", For: "5x"},
+		"invalid label name":          {Alert: "a", Expr: "up", Labels: map[string]string{"a-b": "c"}},
+		"invalid annotation template": {Alert: "a", Expr: "up", Annotations: map[string]string{"summary": "{{ $labels.instance }"}},
+	}
+	for want, rule := range invalids {
+		err := rule.CheckValid()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckValid() error = %v, want contains %q", err, want)
+		}
+		if !errors.Is(err, promConf.ErrInvalidConfig) {
+			t.Errorf("CheckValid() error = %v, want ErrInvalidConfig", err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(ruleData); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`groups:
+  - name: a
+    interval: 1x
+    rules:
+      - alert: A
+        expr: up ==
+  - name: a
+    rules: []
+`)
+	err := Validate(data)
+	if !errors.Is(err, promConf.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	for _, want := range []string{"groups[name=a].interval", "groups[name=a].rules[0:A]", "repeated"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v, want contains %q", err, want)
+		}
+	}
+}
+
+func TestRuleFileGet(t *testing.T) {
+	f := NewRuleFile(ruleData)
+
+	groups, err := f.GetGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Name != "node" || groups[0].Interval != "1m" || len(groups[0].Rules) != 2 {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+
+	rules, err := f.GetRules("node", "NodeDown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].For != "5m" || rules[0].Labels["severity"] != "critical" {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	if _, err = f.GetRules("node", "NotExist"); err == nil {
+		t.Error("expected error")
+	}
+	if _, err = f.GetGroup("mysql"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRuleFileEdit(t *testing.T) {
+	f := NewRuleFile(ruleData)
+
+	// 添加到已存在的分组，保留注释
+	err := f.AddRule("node", &Rule{Alert: "NodeDown", Expr: "up == 0", Labels: map[string]string{"severity": "critical"}})
+	if err == nil {
+		t.Error("expected duplicate error")
+	}
+	err = f.AddRule("node", &Rule{Alert: "HighLoad", Expr: "node_load1 > 10", For: "10m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := string(ruleData) + `      - alert: HighLoad
+        expr: node_load1 > 10
+        for: 10m
+`
+	if string(f.Data) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", f.Data, want)
+	}
+
+	// 替换规则，保留名称、注释和没有设置的字段
+	err = f.ReplaceRule("node", "NodeDown", &Rule{Expr: `up{job="node_exporter"} == 0`, For: "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(f.Data), "      # instance is down\n      - alert: NodeDown\n        expr: up{job=\"node_exporter\"} == 0\n        for: 1m\n        labels:\n          severity: critical\n") {
+		t.Fatalf("unexpected data:\n%s", f.Data)
+	}
+
+	// 新分组
+	err = f.AddRule("mysql", &Rule{Alert: "MysqlDown", Expr: "mysql_up == 0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.SetGroupInterval("mysql", "30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(f.Data), "  - name: mysql\n    interval: 30s\n    rules:\n      - alert: MysqlDown\n        expr: mysql_up == 0\n") {
+		t.Fatalf("unexpected data:\n%s", f.Data)
+	}
+
+	// 删除分组中最后的规则时删除分组
+	err = f.DelRule("mysql", "MysqlDown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.GetGroup("mysql"); !errors.Is(err, promConf.ErrTargetsNotFound) {
+		t.Errorf("group mysql should be deleted, got %v", err)
+	}
+	if err = f.DelRule("node", "MysqlDown"); !errors.Is(err, promConf.ErrTargetsNotFound) {
+		t.Errorf("expected rule not found, got %v", err)
+	}
+
+	err = f.AddRule("node", &Rule{Alert: "Bad", Expr: "rate(up)[5m"})
+	if err == nil {
+		t.Error("expected promql error")
+	}
+
+	err = f.DelGroup("node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(f.Data)) != "# node exporter rules\ngroups: []" {
+		t.Fatalf("unexpected data:\n%s", f.Data)
+	}
+}
+
+func TestRuleFileReplaceExpr(t *testing.T) {
+	f := NewRuleFile(ruleData)
+
+	// 只替换expr，其他字段保持不变
+	err := f.ReplaceRule("node", "NodeDown", &Rule{Expr: "up == 1", Labels: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := f.GetRules("node", "NodeDown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := rules[0]
+	if rule.Expr != "up == 1" || rule.For != "5m" || rule.Labels["severity"] != "critical" ||
+		rule.Annotations["summary"] != "instance {{ $labels.instance }} is down" {
+		t.Fatalf("unexpected rule %+v", rule)
+	}
+
+	// 只替换for
+	err = f.ReplaceRule("node", "NodeDown", &Rule{For: "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, _ = f.GetRules("node", "NodeDown")
+	if rules[0].Expr != "up == 1" || rules[0].For != "1m" || len(rules[0].Annotations) != 1 {
+		t.Fatalf("unexpected rule %+v", rules[0])
+	}
+}
+
+func TestRuleFileNew(t *testing.T) {
+	f := NewRuleFile(nil)
+	err := f.AddRule("redis", &Rule{Alert: "RedisDown", Expr: "redis_up == 0", For: "1m",
+		Annotations: map[string]string{"summary": "redis {{ $labels.instance }} is down"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `groups:
+  - name: redis
+    rules:
+      - alert: RedisDown
+        expr: redis_up == 0
+        for: 1m
+        annotations:
+          summary: redis {{ $labels.instance }} is down
+`
+	if string(f.Data) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", f.Data, want)
+	}
+}
+
+func TestRuleFilePersistent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "rules", "node.yml")
+	f := NewRuleFile(ruleData)
+	err = f.Persistent(file, promConf.BackupPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.AddRule("node", &Rule{Alert: "HighLoad", Expr: "node_load1 > 10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Persistent(file, promConf.BackupPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := promConf.ListBackups(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got %d", len(backups))
+	}
+
+	// 校验失败时不写入
+	f.Data = append(f.Data, []byte("      - alert: Bad\n        expr: up ==\n")...)
+	if err = f.Persistent(file, promConf.BackupPolicy{}); err == nil {
+		t.Fatal("expected validate error")
+	}
+	data, _ := ioutil.ReadFile(file)
+	if strings.Contains(string(data), "Bad") {
+		t.Error("invalid rule file should not be written")
+	}
+}
@@ -0,0 +1,367 @@
+// Package templates 常用exporter的job模板，模板为text/template格式的yaml，参数有类型、默认值和
+// 是否必填，内置node、mysqld、redis、blackbox-http、blackbox-icmp模板，支持从目录加载自定义模板。
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/zhufuyi/mpc/promConf"
+	"gopkg.in/yaml.v3"
+)
+
+// 参数类型
+const (
+	ParamString   = "string"   // 字符串
+	ParamList     = "list"     // 逗号分隔的字符串列表，例如 a:9104,b:9104
+	ParamMap      = "map"      // 逗号分隔的kv，例如 env=prod,team=db
+	ParamInt      = "int"      // 整数
+	ParamBool     = "bool"     // true或false
+	ParamDuration = "duration" // prometheus时间格式，例如 15s、1m
+)
+
+//go:embed builtin/*.yaml
+var builtinFS embed.FS
+
+// Param 模板参数
+type Param struct {
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type" yaml:"type"`
+	Default     string `json:"default,omitempty" yaml:"default,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Template job模板，Job为text/template格式的job配置，参数通过.name访问
+type Template struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Params      []*Param `json:"params" yaml:"params"`
+	Job         string   `json:"job" yaml:"job"`
+
+	File string `json:"file,omitempty" yaml:"-"` // 自定义模板的文件，内置模板为空
+}
+
+// Catalog 模板目录，按名称索引
+type Catalog map[string]*Template
+
+// ParseTemplate 解析模板定义，检查参数类型和模板语法
+func ParseTemplate(data []byte) (*Template, error) {
+	t := &Template{}
+	err := yaml.Unmarshal(data, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Name == "" {
+		return nil, fmt.Errorf("field 'name' of template is empty")
+	}
+	if strings.TrimSpace(t.Job) == "" {
+		return nil, fmt.Errorf("field 'job' of template '%s' is empty", t.Name)
+	}
+
+	names := map[string]bool{}
+	for _, p := range t.Params {
+		if p.Name == "" {
+			return nil, fmt.Errorf("param name of template '%s' is empty", t.Name)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("param '%s' of template '%s' is duplicated", p.Name, t.Name)
+		}
+		names[p.Name] = true
+		if p.Type == "" {
+			p.Type = ParamString
+		}
+		if p.Default != "" {
+			if _, err = p.parse(p.Default); err != nil {
+				return nil, fmt.Errorf("default value of param '%s' in template '%s' is invalid, %v", p.Name, t.Name, err)
+			}
+		} else if _, err = p.parse(""); err != nil {
+			return nil, fmt.Errorf("param '%s' of template '%s' is invalid, %v", p.Name, t.Name, err)
+		}
+	}
+
+	_, err = t.parseJob()
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Builtin 内置的模板
+func Builtin() (Catalog, error) {
+	files, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		return nil, err
+	}
+
+	c := Catalog{}
+	for _, file := range files {
+		data, err := builtinFS.ReadFile("builtin/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		t, err := ParseTemplate(data)
+		if err != nil {
+			return nil, fmt.Errorf("builtin template %s: %v", file.Name(), err)
+		}
+		c[t.Name] = t
+	}
+
+	return c, nil
+}
+
+// Load 加载内置模板和dir目录下的*.yaml、*.yml自定义模板，名称相同时自定义模板覆盖内置模板，
+// dir为空或不存在时只加载内置模板
+func Load(dir string) (Catalog, error) {
+	c, err := Builtin()
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return c, nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		t, err := ParseTemplate(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		t.File = path
+		c[t.Name] = t
+	}
+
+	return c, nil
+}
+
+// DefaultDir 默认的自定义模板目录 ~/.mpc/templates
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mpc", "templates")
+}
+
+// Get 按名称获取模板
+func (c Catalog) Get(name string) (*Template, error) {
+	t, ok := c[name]
+	if !ok {
+		return nil, fmt.Errorf("template '%s' not found, supports %s", name, strings.Join(c.Names(), ", "))
+	}
+	return t, nil
+}
+
+// Names 所有模板的名称，按字母排序
+func (c Catalog) Names() []string {
+	names := []string{}
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render 使用参数值渲染模板，values为参数名称和字符串形式的值，没有设置的参数使用默认值，
+// 返回块风格的job配置，已检查job配置是否有效
+func (t *Template) Render(values map[string]string) ([]byte, error) {
+	params := map[string]*Param{}
+	for _, p := range t.Params {
+		params[p.Name] = p
+	}
+	for name := range values {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("unknown param '%s' of template '%s', supports %s", name, t.Name, strings.Join(t.ParamNames(), ", "))
+		}
+	}
+
+	data := map[string]interface{}{}
+	for _, p := range t.Params {
+		value, ok := values[p.Name]
+		if !ok || value == "" {
+			if p.Required && p.Default == "" {
+				return nil, fmt.Errorf("param '%s' of template '%s' is required, eg: --set %s=<%s>", p.Name, t.Name, p.Name, p.Type)
+			}
+			value = p.Default
+		}
+		v, err := p.parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("param '%s' is invalid, %v", p.Name, err)
+		}
+		data[p.Name] = v
+	}
+
+	tmpl, err := t.parseJob()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, fmt.Errorf("render template '%s' failed, %v", t.Name, err)
+	}
+
+	out, err := blockStyle(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("job rendered by template '%s' is not valid yaml, %v", t.Name, err)
+	}
+
+	jc := &promConf.JobConfig{}
+	err = yaml.Unmarshal(out, jc)
+	if err != nil {
+		return nil, err
+	}
+	err = jc.CheckValid()
+	if err != nil {
+		return nil, fmt.Errorf("job rendered by template '%s' is invalid, %v", t.Name, err)
+	}
+
+	return out, nil
+}
+
+// ParamNames 参数名称，按定义的顺序
+func (t *Template) ParamNames() []string {
+	names := []string{}
+	for _, p := range t.Params {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func (t *Template) parseJob() (*template.Template, error) {
+	tmpl, err := template.New(t.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(t.Job)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template '%s': %v", t.Name, err)
+	}
+	return tmpl, nil
+}
+
+// 模板中可以使用的函数
+var templateFuncs = template.FuncMap{
+	// 转为json，在yaml中是flow风格的值，渲染后转为块风格，例如 targets: {{ toJson .targets }}
+	"toJson": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	// 双引号字符串，例如 job_name: {{ quote .name }}
+	"quote": func(s string) string {
+		return strconv.Quote(s)
+	},
+}
+
+// 按参数类型转换值，空字符串转为类型的零值
+func (p *Param) parse(value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+
+	switch p.Type {
+	case ParamString:
+		return value, nil
+
+	case ParamList:
+		list := []string{}
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+		return list, nil
+
+	case ParamMap:
+		m := map[string]string{}
+		for _, kv := range strings.Split(value, ",") {
+			if strings.TrimSpace(kv) == "" {
+				continue
+			}
+			split := strings.SplitN(kv, "=", 2)
+			if len(split) != 2 {
+				return nil, fmt.Errorf("value '%s' format is invalid, eg: env=prod,team=db", value)
+			}
+			m[strings.TrimSpace(split[0])] = strings.TrimSpace(split[1])
+		}
+		return m, nil
+
+	case ParamInt:
+		if value == "" {
+			return 0, nil
+		}
+		return strconv.Atoi(value)
+
+	case ParamBool:
+		if value == "" {
+			return false, nil
+		}
+		return strconv.ParseBool(value)
+
+	case ParamDuration:
+		if value == "" {
+			return "", nil
+		}
+		if _, err := promConf.ParseDuration(value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("unknown param type '%s', supports %s, %s, %s, %s, %s, %s",
+		p.Type, ParamString, ParamList, ParamMap, ParamInt, ParamBool, ParamDuration)
+}
+
+// 渲染后的yaml转为块风格，toJson生成的值不保留flow风格和双引号
+func blockStyle(data []byte) ([]byte, error) {
+	node := &yaml.Node{}
+	err := yaml.Unmarshal(data, node)
+	if err != nil {
+		return nil, err
+	}
+	if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("job is not a yaml object")
+	}
+	clearStyle(node)
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(node)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), encoder.Close()
+}
+
+// 非空的对象和数组使用块风格，字符串只在需要时加引号
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	if (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && len(node.Content) == 0 {
+		node.Style = yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
@@ -0,0 +1,156 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhufuyi/mpc/promConf"
+)
+
+func TestBuiltin(t *testing.T) {
+	c, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "blackbox-http,blackbox-icmp,mysqld,node,redis"
+	if names := strings.Join(c.Names(), ","); names != expected {
+		t.Errorf("got %s, expected %s", names, expected)
+	}
+
+	// 所有内置模板只设置必填参数都可以渲染出有效的job
+	for _, name := range c.Names() {
+		out, err := c[name].Render(map[string]string{"targets": "10.0.0.1:9100"})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if err = promConf.Validate(append([]byte("scrape_configs:\n  - "), indent(out)...)); err != nil {
+			t.Errorf("%s: %v\n%s", name, err, out)
+		}
+	}
+}
+
+func indent(data []byte) []byte {
+	return []byte(strings.ReplaceAll(strings.TrimRight(string(data), "\n"), "\n", "\n    ") + "\n")
+}
+
+func TestTemplate_Render(t *testing.T) {
+	c, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := c.Get("mysqld")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := tmpl.Render(map[string]string{
+		"name":            "mysql_prod",
+		"targets":         "a:9104,b:9104",
+		"labels":          "env=prod,enabled=true",
+		"scrape_interval": "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `job_name: mysql_prod
+scrape_interval: 30s
+static_configs:
+  - targets:
+      - a:9104
+      - b:9104
+    labels:
+      enabled: "true"
+      env: prod
+`
+	if string(out) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", out, expected)
+	}
+
+	invalids := map[string]map[string]string{
+		"is required":       {},
+		"unknown param":     {"targets": "a:9104", "foo": "bar"},
+		"duration":          {"targets": "a:9104", "scrape_interval": "30"},
+		"format is invalid": {"targets": "a:9104", "labels": "env"},
+	}
+	for msg, values := range invalids {
+		_, err = tmpl.Render(values)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected error containing '%s', got %v", msg, err)
+		}
+	}
+
+	if _, err = c.Get("postgres"); err == nil {
+		t.Error("expected error for unknown template")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	custom := []byte(`name: node
+description: node_exporter with a fixed port
+params:
+  - name: hosts
+    type: list
+    required: true
+  - name: port
+    type: int
+    default: "9100"
+job: |
+  job_name: node
+  static_configs:
+    - targets:
+      {{- range .hosts }}
+        - {{ . }}:{{ $.port }}
+      {{- end }}
+`)
+	err = ioutil.WriteFile(filepath.Join(dir, "node.yaml"), custom, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a template"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 5 || c["node"].File == "" {
+		t.Fatalf("custom template does not override builtin template, %v", c.Names())
+	}
+
+	out, err := c["node"].Render(map[string]string{"hosts": "10.0.0.1,10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "- 10.0.0.1:9100\n      - 10.0.0.2:9100") {
+		t.Errorf("unexpected result\n%s", out)
+	}
+
+	// 目录不存在时只加载内置模板
+	c, err = Load(filepath.Join(dir, "missing"))
+	if err != nil || len(c) != 5 {
+		t.Errorf("got %v, %v", c.Names(), err)
+	}
+
+	_, err = ParseTemplate([]byte("name: a\nparams:\n  - name: p\n    type: float\njob: 'job_name: a'\n"))
+	if err == nil || !strings.Contains(err.Error(), "unknown param type") {
+		t.Errorf("expected unknown param type error, got %v", err)
+	}
+	_, err = ParseTemplate([]byte("name: a\njob: 'job_name: {{ .a '\n"))
+	if err == nil {
+		t.Error("expected template syntax error")
+	}
+}